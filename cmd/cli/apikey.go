@@ -0,0 +1,268 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	cmd2 "github.com/axellelanca/urlshortener/cmd"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// variables qui stockeront les valeurs des flags des sous-commandes 'apikey'
+var (
+	apiKeyNameFlag    string
+	apiKeyIDFlag      uint
+	apiKeyCodeFlag    string
+	apiKeyUnownedFlag bool
+)
+
+// APIKeyCmd regroupe les sous-commandes de gestion des clés d'API.
+var APIKeyCmd = &cobra.Command{
+	Use:   "apikey",
+	Short: "Gère les clés d'API donnant accès aux routes /api/v1.",
+	Long: `Cette commande regroupe la création, la liste et la révocation des clés d'API.
+Chaque lien créé via l'API appartient à la clé qui l'a créé : une clé ne peut consulter
+et gérer que ses propres liens.`,
+}
+
+// APIKeyCreateCmd représente la commande 'apikey create'
+var APIKeyCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Crée une nouvelle clé d'API.",
+	Long: `Cette commande génère une nouvelle clé d'API et l'affiche une seule fois :
+seule son empreinte est conservée en base, elle ne pourra pas être affichée à nouveau.
+
+Exemple:
+  url-shortener apikey create --name="equipe-marketing"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if apiKeyNameFlag == "" {
+			fmt.Println("Erreur : le flag --name est obligatoire")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+
+		apiKeyService, closeDB := openAPIKeyService()
+		defer closeDB()
+
+		rawKey, key, err := apiKeyService.CreateAPIKey(apiKeyNameFlag)
+		if err != nil {
+			log.Fatalf("FATAL: échec de la création de la clé d'API : %v", err)
+		}
+
+		fmt.Printf("Clé d'API créée avec succès:\n")
+		fmt.Printf("ID: %d\n", key.ID)
+		fmt.Printf("Nom: %s\n", key.Name)
+		fmt.Printf("Clé: %s\n", rawKey)
+		fmt.Println("Conservez cette clé en lieu sûr : elle ne sera plus affichée.")
+	},
+}
+
+// APIKeyListCmd représente la commande 'apikey list'
+var APIKeyListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les clés d'API existantes.",
+	Run: func(cmd *cobra.Command, args []string) {
+		apiKeyService, closeDB := openAPIKeyService()
+		defer closeDB()
+
+		keys, err := apiKeyService.ListAPIKeys()
+		if err != nil {
+			log.Fatalf("FATAL: échec de la récupération des clés d'API : %v", err)
+		}
+		if len(keys) == 0 {
+			fmt.Println("Aucune clé d'API.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tNOM\tPRÉFIXE\tCRÉÉE LE\tDERNIÈRE UTILISATION\tSTATUT")
+		for _, key := range keys {
+			lastUsed := "-"
+			if key.LastUsedAt != nil {
+				lastUsed = key.LastUsedAt.Format(time.RFC3339)
+			}
+			status := "active"
+			if key.RevokedAt != nil {
+				status = "révoquée le " + key.RevokedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s…\t%s\t%s\t%s\n",
+				key.ID, key.Name, key.Prefix, key.CreatedAt.Format(time.RFC3339), lastUsed, status)
+		}
+		_ = w.Flush()
+	},
+}
+
+// APIKeyRevokeCmd représente la commande 'apikey revoke'
+var APIKeyRevokeCmd = &cobra.Command{
+	Use:   "revoke",
+	Short: "Révoque une clé d'API.",
+	Long: `Cette commande révoque une clé d'API : elle ne permet plus d'accéder à l'API.
+Les liens créés avec cette clé continuent de rediriger.
+
+Exemple:
+  url-shortener apikey revoke --id=3`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if apiKeyIDFlag == 0 {
+			fmt.Println("Erreur : le flag --id est obligatoire")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+
+		apiKeyService, closeDB := openAPIKeyService()
+		defer closeDB()
+
+		key, err := apiKeyService.RevokeAPIKey(apiKeyIDFlag)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Printf("Aucune clé d'API trouvée pour l'ID : %d\n", apiKeyIDFlag)
+				os.Exit(1)
+			}
+			if errors.Is(err, services.ErrAPIKeyAlreadyRevoked) {
+				fmt.Printf("La clé d'API %d (%s) est déjà révoquée.\n", key.ID, key.Name)
+				return
+			}
+			log.Fatalf("FATAL: échec de la révocation de la clé d'API : %v", err)
+		}
+
+		fmt.Printf("Clé d'API %d (%s) révoquée.\n", key.ID, key.Name)
+	},
+}
+
+// APIKeyAssignCmd représente la commande 'apikey assign'
+var APIKeyAssignCmd = &cobra.Command{
+	Use:   "assign",
+	Short: "Rattache des liens existants à une clé d'API.",
+	Long: `Cette commande rattache un lien, ou tous les liens sans propriétaire (créés via la CLI
+sans --owner ou avant l'authentification par clé d'API), à une clé d'API active :
+ils deviennent consultables et gérables via l'API avec cette clé.
+Un lien appartenant déjà à une autre clé lui est retiré.
+
+Exemples:
+  url-shortener apikey assign --id=3 --code=q3-report
+  url-shortener apikey assign --id=3 --unowned`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if apiKeyIDFlag == 0 {
+			fmt.Println("Erreur : le flag --id est obligatoire")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+		if (apiKeyCodeFlag == "") == !apiKeyUnownedFlag {
+			fmt.Println("Erreur : indiquez soit --code, soit --unowned")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+
+		db, closeDB := openAPIKeyDatabase()
+		defer closeDB()
+
+		key := requireActiveAPIKey(services.NewAPIKeyService(repository.NewAPIKeyRepository(db), cmd2.Logger), apiKeyIDFlag)
+		linkService := services.NewLinkService(repository.NewLinkRepository(db), cmd2.Logger)
+
+		if apiKeyUnownedFlag {
+			assigned, err := linkService.AssignUnownedLinks(key.ID)
+			if err != nil {
+				log.Fatalf("FATAL: échec du rattachement des liens : %v", err)
+			}
+			fmt.Printf("%d lien(s) sans propriétaire rattaché(s) à la clé d'API %d (%s).\n", assigned, key.ID, key.Name)
+			return
+		}
+
+		link, err := linkService.GetLinkByShortCode(apiKeyCodeFlag)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				fmt.Printf("Aucun lien trouvé pour le code : %s\n", apiKeyCodeFlag)
+				os.Exit(1)
+			}
+			log.Fatalf("FATAL: échec de la récupération du lien : %v", err)
+		}
+		previous := link.OwnerID
+		if err := linkService.AssignOwner(link, key.ID); err != nil {
+			log.Fatalf("FATAL: échec du rattachement du lien : %v", err)
+		}
+		fmt.Printf("Lien '%s' rattaché à la clé d'API %d (%s).\n", link.ShortCode, key.ID, key.Name)
+		if previous != nil && *previous != key.ID {
+			fmt.Printf("Propriétaire précédent : clé d'API %d.\n", *previous)
+		}
+	},
+}
+
+// requireActiveAPIKey récupère la clé d'API 'id' et termine la commande si elle n'existe pas
+// ou est révoquée.
+func requireActiveAPIKey(apiKeyService *services.APIKeyService, id uint) *models.APIKey {
+	key, err := apiKeyService.GetActiveAPIKey(id)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		fmt.Printf("Aucune clé d'API trouvée pour l'ID : %d\n", id)
+		os.Exit(1)
+	case errors.Is(err, services.ErrInvalidAPIKey):
+		fmt.Printf("La clé d'API %d (%s) est révoquée.\n", key.ID, key.Name)
+		os.Exit(1)
+	case err != nil:
+		log.Fatalf("FATAL: échec de la récupération de la clé d'API : %v", err)
+	}
+	return key
+}
+
+// openAPIKeyService ouvre la base de données configurée et construit le APIKeyService.
+// La fonction retournée ferme la connexion et doit être appelée via defer.
+func openAPIKeyService() (*services.APIKeyService, func()) {
+	db, closeDB := openAPIKeyDatabase()
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	return services.NewAPIKeyService(apiKeyRepo, cmd2.Logger), closeDB
+}
+
+// openAPIKeyDatabase ouvre la base de données configurée.
+// La fonction retournée ferme la connexion et doit être appelée via defer.
+func openAPIKeyDatabase() (*gorm.DB, func()) {
+	// Charger la configuration chargée globalement via cmd.cfg
+	cfg := cmd2.Cfg
+	if cfg == nil {
+		log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+	}
+
+	db, err := cmd2.OpenDatabase()
+	if err != nil {
+		log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+
+	return db, func() { _ = sqlDB.Close() }
+}
+
+// init() s'exécute automatiquement lors de l'importation du package.
+// Il définit les flags des sous-commandes et les rattache à RootCmd.
+func init() {
+	APIKeyCreateCmd.Flags().StringVar(&apiKeyNameFlag, "name", "", "Nom descriptif de la clé d'API")
+	if err := APIKeyCreateCmd.MarkFlagRequired("name"); err != nil {
+		log.Printf("WARN: impossible de marquer --name comme requis: %v", err)
+	}
+
+	APIKeyRevokeCmd.Flags().UintVar(&apiKeyIDFlag, "id", 0, "ID de la clé d'API à révoquer")
+	if err := APIKeyRevokeCmd.MarkFlagRequired("id"); err != nil {
+		log.Printf("WARN: impossible de marquer --id comme requis: %v", err)
+	}
+
+	APIKeyAssignCmd.Flags().UintVar(&apiKeyIDFlag, "id", 0, "ID de la clé d'API à laquelle rattacher les liens")
+	APIKeyAssignCmd.Flags().StringVar(&apiKeyCodeFlag, "code", "", "Code court du lien à rattacher")
+	APIKeyAssignCmd.Flags().BoolVar(&apiKeyUnownedFlag, "unowned", false, "Rattacher tous les liens sans propriétaire")
+	if err := APIKeyAssignCmd.MarkFlagRequired("id"); err != nil {
+		log.Printf("WARN: impossible de marquer --id comme requis: %v", err)
+	}
+
+	APIKeyCmd.AddCommand(APIKeyCreateCmd, APIKeyListCmd, APIKeyRevokeCmd, APIKeyAssignCmd)
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(APIKeyCmd)
+}
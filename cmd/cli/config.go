@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	cmd2 "github.com/axellelanca/urlshortener/cmd"
+	"github.com/axellelanca/urlshortener/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// secretKeyFragments identifie les clés dont la valeur est masquée par 'config show'.
+var secretKeyFragments = []string{"secret", "password", "dsn"}
+
+// ConfigCmd regroupe les sous-commandes d'inspection de la configuration.
+var ConfigCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspecte la configuration de l'application.",
+}
+
+// ConfigShowCmd représente la commande 'config show'
+var ConfigShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Affiche la configuration effective et l'origine de chaque valeur.",
+	Long: `Cette commande affiche la configuration effective, après fusion des valeurs par défaut,
+du fichier de configuration, des variables d'environnement URLSHORTENER_* et des options
+--db et --port, ainsi que la source de chaque valeur (default, file, env ou flag).
+
+Exemples:
+  url-shortener config show
+  URLSHORTENER_SERVER_PORT=9090 url-shortener config show --config /etc/urlshortener/config.yaml`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+		}
+
+		if file := config.ConfigFileUsed(); file != "" {
+			fmt.Printf("Fichier de configuration: %s\n\n", file)
+		} else {
+			fmt.Printf("Fichier de configuration: aucun (valeurs par défaut)\n\n")
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CLÉ\tVALEUR\tSOURCE")
+		for _, setting := range config.Settings() {
+			source := setting.Source
+			if setting.Origin != "" && setting.Source != config.SourceFile {
+				source = fmt.Sprintf("%s (%s)", setting.Source, setting.Origin)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\n", setting.Key, displayValue(setting), source)
+		}
+		_ = w.Flush()
+	},
+}
+
+// displayValue formate la valeur d'une clé pour l'affichage, en masquant les valeurs sensibles.
+func displayValue(setting config.Setting) string {
+	value := fmt.Sprint(maskNested(setting.Value))
+	if isSecretKey(setting.Key) && value != "" {
+		return "********"
+	}
+	if value == "" {
+		return `""`
+	}
+	return value
+}
+
+// isSecretKey indique si la valeur de la clé 'key' doit être masquée.
+func isSecretKey(key string) bool {
+	for _, fragment := range secretKeyFragments {
+		if strings.Contains(strings.ToLower(key), fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskNested masque les valeurs sensibles des listes d'objets lues dans le fichier de configuration
+// (par exemple le secret de chaque élément de webhooks.endpoints), que viper retourne sans les découper en clés.
+func maskNested(value any) any {
+	switch v := value.(type) {
+	case []any:
+		masked := make([]any, len(v))
+		for i, item := range v {
+			masked[i] = maskNested(item)
+		}
+		return masked
+	case map[string]any:
+		masked := make(map[string]any, len(v))
+		for key, item := range v {
+			if isSecretKey(key) && fmt.Sprint(item) != "" {
+				masked[key] = "********"
+			} else {
+				masked[key] = maskNested(item)
+			}
+		}
+		return masked
+	default:
+		return value
+	}
+}
+
+// init() s'exécute automatiquement lors de l'importation du package.
+func init() {
+	ConfigCmd.AddCommand(ConfigShowCmd)
+	cmd2.RootCmd.AddCommand(ConfigCmd)
+}
@@ -1,22 +1,34 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/url" // Pour valider le format de l'URL
 	"os"
+	"time"
 
 	cmd2 "github.com/axellelanca/urlshortener/cmd"
 	"github.com/axellelanca/urlshortener/internal/repository"
 	"github.com/axellelanca/urlshortener/internal/services"
 	"github.com/spf13/cobra"
-	"github.com/glebarez/sqlite"
-	"gorm.io/gorm"
 )
 
 // variable longURLFlag qui stockera la valeur du flag --url
 var longURLFlag string
 
+// variable aliasFlag qui stockera la valeur du flag --alias (optionnel)
+var aliasFlag string
+
+// variables expiresAtFlag et maxClicksFlag qui stockeront la durée de vie optionnelle du lien
+var (
+	expiresAtFlag string
+	maxClicksFlag int
+)
+
+// variable ownerFlag qui stockera l'ID de la clé d'API propriétaire du lien (0 = sans propriétaire)
+var ownerFlag uint
+
 // CreateCmd représente la commande 'create'
 var CreateCmd = &cobra.Command{
 	Use:   "create",
@@ -24,7 +36,13 @@ var CreateCmd = &cobra.Command{
 	Long: `Cette commande raccourcit une URL longue fournie et affiche le code court généré.
 
 Exemple:
-  url-shortener create --url="https://www.google.com/search?q=go+lang"`,
+  url-shortener create --url="https://www.google.com/search?q=go+lang"
+  url-shortener create --url="https://example.com/rapport" --alias="q3-report"
+  url-shortener create --url="https://example.com/promo" --expires-at="2025-12-31T23:59:59Z" --max-clicks=100
+  url-shortener create --url="https://example.com/doc" --owner=3
+
+Sans --owner, le lien n'appartient à aucune clé d'API et n'est pas visible via l'API
+(voir 'apikey assign').`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// 1: Valider que le flag --url a été fourni.
 		if longURLFlag == "" {
@@ -41,14 +59,33 @@ Exemple:
 			os.Exit(1)
 		}
 
+		// Valider l'alias au plus tôt pour éviter une connexion inutile à la base.
+		if aliasFlag != "" {
+			if err := services.ValidateAlias(aliasFlag); err != nil {
+				fmt.Printf("Erreur : %v\n", err)
+				os.Exit(1)
+			}
+		}
+
+		// Parser la date d'expiration optionnelle au format RFC 3339.
+		var expiresAt *time.Time
+		if expiresAtFlag != "" {
+			t, err := time.Parse(time.RFC3339, expiresAtFlag)
+			if err != nil {
+				fmt.Printf("Erreur : date d'expiration invalide '%s' (format attendu : RFC 3339, ex. 2025-12-31T23:59:59Z)\n", expiresAtFlag)
+				os.Exit(1)
+			}
+			expiresAt = &t
+		}
+
 		// Charger la configuration chargée globalement via cmd.cfg
 		cfg := cmd2.Cfg
 		if cfg == nil {
 			log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
 		}
 
-		// Initialiser la connexion à la base de données SQLite.
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		// Initialiser la connexion à la base de données configurée.
+		db, err := cmd2.OpenDatabase()
 		if err != nil {
 			log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
 		}
@@ -61,14 +98,47 @@ Exemple:
 		// S'assurer que la connexion est fermée à la fin de l'exécution de la commande
 		defer sqlDB.Close()
 
+		// Vérifier que la clé d'API propriétaire éventuelle existe et n'est pas révoquée.
+		var ownerID *uint
+		if ownerFlag != 0 {
+			key := requireActiveAPIKey(services.NewAPIKeyService(repository.NewAPIKeyRepository(db), cmd2.Logger), ownerFlag)
+			ownerID = &key.ID
+		}
+
 		// Initialiser les repositories et services nécessaires NewLinkRepository & NewLinkService
 		linkRepo := repository.NewLinkRepository(db)
-		linkService := services.NewLinkService(linkRepo)
+		linkService := services.NewLinkService(linkRepo, cmd2.Logger)
+		if cfg.NetworkGuard.CheckOnCreate {
+			guard, err := cmd2.NetworkGuard()
+			if err != nil {
+				log.Fatalf("FATAL: configuration network_guard invalide: %v", err)
+			}
+			linkService.SetNetworkGuard(guard)
+		}
+		// L'événement link.created est enregistré en base ; il sera livré par le serveur.
+		dispatcher, err := cmd2.Webhooks(db)
+		if err != nil {
+			log.Fatalf("FATAL: configuration des webhooks invalide: %v", err)
+		}
+		linkService.SetWebhooks(dispatcher)
 
 		// Appeler le LinkService et la fonction CreateLink pour créer le lien court.
 		// os.Exit(1) si erreur
-		link, err := linkService.CreateLink(longURLFlag)
+		link, err := linkService.CreateLink(longURLFlag, services.CreateLinkOptions{
+			CustomAlias: aliasFlag,
+			ExpiresAt:   expiresAt,
+			MaxClicks:   maxClicksFlag,
+			OwnerID:     ownerID,
+		})
 		if err != nil {
+			if errors.Is(err, services.ErrAliasTaken) {
+				fmt.Printf("Erreur : l'alias '%s' est déjà utilisé\n", aliasFlag)
+				os.Exit(1)
+			}
+			if errors.Is(err, services.ErrInvalidExpiration) || errors.Is(err, services.ErrForbiddenDestination) {
+				fmt.Printf("Erreur : %v\n", err)
+				os.Exit(1)
+			}
 			log.Fatalf("FATAL: échec de la création du lien court : %v", err)
 		}
 
@@ -76,6 +146,15 @@ Exemple:
 		fmt.Printf("URL courte créée avec succès:\n")
 		fmt.Printf("Code: %s\n", link.ShortCode)
 		fmt.Printf("URL complète: %s\n", fullShortURL)
+		if link.ExpiresAt != nil {
+			fmt.Printf("Expire le: %s\n", link.ExpiresAt.Format(time.RFC3339))
+		}
+		if link.MaxClicks > 0 {
+			fmt.Printf("Budget de clics: %d\n", link.MaxClicks)
+		}
+		if link.OwnerID != nil {
+			fmt.Printf("Clé d'API propriétaire: %d\n", *link.OwnerID)
+		}
 	},
 }
 
@@ -84,6 +163,10 @@ Exemple:
 func init() {
 	// Définir le flag --url pour la commande create.
 	CreateCmd.Flags().StringVar(&longURLFlag, "url", "", "URL longue à raccourcir")
+	CreateCmd.Flags().StringVar(&aliasFlag, "alias", "", "Alias personnalisé à utiliser comme code court (optionnel)")
+	CreateCmd.Flags().StringVar(&expiresAtFlag, "expires-at", "", "Date d'expiration du lien au format RFC 3339 (optionnel)")
+	CreateCmd.Flags().IntVar(&maxClicksFlag, "max-clicks", 0, "Nombre maximal de clics acceptés par le lien, 0 = illimité (optionnel)")
+	CreateCmd.Flags().UintVar(&ownerFlag, "owner", 0, "ID de la clé d'API propriétaire du lien (optionnel)")
 
 	// Marquer le flag comme requis
 	if err := CreateCmd.MarkFlagRequired("url"); err != nil {
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	cmd2 "github.com/axellelanca/urlshortener/cmd"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/services"
+	"github.com/spf13/cobra"
+)
+
+// HealthCmd représente la commande 'health'
+var HealthCmd = &cobra.Command{
+	Use:   "health",
+	Short: "Liste les liens actuellement inaccessibles.",
+	Long: `Cette commande liste les liens dont la dernière vérification par le moniteur d'URLs
+(lancé avec run-server) a échoué, du plus anciennement inaccessible au plus récent,
+avec la cause du dernier échec.
+
+Exemple:
+  url-shortener health`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if cmd2.Cfg == nil {
+			log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+		}
+
+		db, err := cmd2.OpenDatabase()
+		if err != nil {
+			log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+		}
+		defer sqlDB.Close()
+
+		healthService := services.NewHealthService(repository.NewLinkCheckRepository(db))
+		broken, err := healthService.ListBrokenLinks()
+		if err != nil {
+			sqlDB.Close()
+			log.Fatalf("FATAL: échec de la récupération des liens inaccessibles : %v", err)
+		}
+		if len(broken) == 0 {
+			fmt.Println("Aucun lien inaccessible.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "CODE\tURL\tINACCESSIBLE DEPUIS\tDERNIÈRE VÉRIFICATION\tCAUSE")
+		for _, entry := range broken {
+			since, lastChecked := "-", "-"
+			if entry.Link.HealthChangedAt != nil {
+				since = entry.Link.HealthChangedAt.Format(time.RFC3339)
+			}
+			if entry.Link.LastCheckedAt != nil {
+				lastChecked = entry.Link.LastCheckedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+				entry.Link.ShortCode, entry.Link.LongURL, since, lastChecked, failureCause(entry.LastCheck))
+		}
+		_ = w.Flush()
+
+		fmt.Printf("\n%d lien(s) inaccessible(s).\n", len(broken))
+	},
+}
+
+// failureCause décrit la cause d'échec d'une vérification : le code HTTP reçu, ou la classe d'erreur.
+func failureCause(check *models.LinkCheck) string {
+	switch {
+	case check == nil:
+		return "-"
+	case check.StatusCode != 0:
+		return "HTTP " + strconv.Itoa(check.StatusCode)
+	default:
+		return check.ErrorClass
+	}
+}
+
+func init() {
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(HealthCmd)
+}
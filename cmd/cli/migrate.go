@@ -3,51 +3,180 @@ package cli
 import (
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
 
 	cmd2 "github.com/axellelanca/urlshortener/cmd"
-	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/migrations"
 	"github.com/spf13/cobra"
-	"github.com/glebarez/sqlite" // Driver SQLite pour GORM
-	"gorm.io/gorm"
 )
 
+// migrationsDirFlag stocke la valeur du flag --dir de 'migrate create'.
+var migrationsDirFlag string
+
 // MigrateCmd représente la commande 'migrate'
 var MigrateCmd = &cobra.Command{
 	Use:   "migrate",
-	Short: "Exécute les migrations de la base de données.",
-	Long:  `Cette commande initialise la connexion à la base de données et exécute les migrations GORM pour créer ou mettre à jour les tables nécessaires.`,
+	Short: "Gère les migrations versionnées de la base de données.",
+	Long: `Cette commande gère les migrations numérotées et réversibles du schéma de la base de données.
+Les migrations appliquées sont enregistrées dans la table schema_migrations.
+
+Sans sous-commande, 'migrate' applique toutes les migrations en attente (comme 'migrate up').
+
+Exemples:
+  url-shortener migrate up
+  url-shortener migrate down 1
+  url-shortener migrate status
+  url-shortener migrate create add_link_tags`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		// Récupérer la configuration chargée globalement via cmd.Cfg
-		cfg := cmd2.Cfg
-		if cfg == nil {
-			log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+		MigrateUpCmd.Run(cmd, args)
+	},
+}
+
+// MigrateUpCmd représente la commande 'migrate up'
+var MigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Applique toutes les migrations en attente.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("Migration appliquée : %s\n", m)
+		}
+		if err != nil {
+			closeDB()
+			log.Fatalf("FATAL: échec lors de l'exécution des migrations: %v", err)
 		}
 
-		// Initialiser la connexion à la base de données SQLite.
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		// Pas touche au log
+		if len(applied) == 0 {
+			fmt.Println("Le schéma de la base de données est déjà à jour.")
+			return
+		}
+		fmt.Println("Migrations de la base de données exécutées avec succès.")
+	},
+}
+
+// MigrateDownCmd représente la commande 'migrate down N'
+var MigrateDownCmd = &cobra.Command{
+	Use:   "down [N]",
+	Short: "Annule les N dernières migrations appliquées (1 par défaut).",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				fmt.Printf("Erreur : N doit être un entier positif (reçu '%s')\n", args[0])
+				os.Exit(1)
+			}
+			steps = n
+		}
+
+		migrator, closeDB := openMigrator()
+		defer closeDB()
+
+		rolledBack, err := migrator.Down(steps)
+		for _, m := range rolledBack {
+			fmt.Printf("Migration annulée : %s\n", m)
+		}
 		if err != nil {
-			log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
+			closeDB()
+			log.Fatalf("FATAL: échec de l'annulation des migrations: %v", err)
+		}
+		if len(rolledBack) == 0 {
+			fmt.Println("Aucune migration appliquée à annuler.")
 		}
+	},
+}
+
+// MigrateStatusCmd représente la commande 'migrate status'
+var MigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Affiche l'état de chaque migration.",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator, closeDB := openMigrator()
+		defer closeDB()
 
-		sqlDB, err := db.DB()
+		statuses, err := migrator.Status()
 		if err != nil {
-			log.Fatalf("FATAL: échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+			closeDB()
+			log.Fatalf("FATAL: impossible de lire l'état des migrations: %v", err)
 		}
-		// Assurez-vous que la connexion est fermée après la migration grâce à defer.
-		defer sqlDB.Close()
 
-		// Exécuter les migrations automatiques de GORM.
-		// On passe les pointeurs vers tous les modèles.
-		if err := db.AutoMigrate(&models.Link{}, &models.Click{}); err != nil {
-			log.Fatalf("FATAL: échec lors de l'exécution des migrations: %v", err)
+		pending := 0
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNOM\tÉTAT\tAPPLIQUÉE LE")
+		for _, status := range statuses {
+			state, appliedAt := "en attente", "-"
+			switch {
+			case status.Unknown:
+				state = "inconnue de ce binaire"
+			case status.AppliedAt != nil:
+				state = "appliquée"
+			default:
+				pending++
+			}
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.UTC().Format("2006-01-02 15:04:05 UTC")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
 		}
+		_ = w.Flush()
 
-		// Pas touche au log
-		fmt.Println("Migrations de la base de données exécutées avec succès.")
+		fmt.Printf("\n%d migration(s) en attente.\n", pending)
 	},
 }
 
+// MigrateCreateCmd représente la commande 'migrate create <nom>'
+var MigrateCreateCmd = &cobra.Command{
+	Use:   "create <nom>",
+	Short: "Crée le squelette d'une nouvelle migration.",
+	Long: `Cette commande crée un fichier Go 'NNNN_<nom>.go' dans le dossier des migrations,
+numéroté à la suite des migrations existantes. Complétez ses fonctions Up et Down,
+puis recompilez l'application pour que la migration soit prise en compte.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := migrations.Create(migrationsDirFlag, args[0])
+		if err != nil {
+			log.Fatalf("FATAL: impossible de créer la migration: %v", err)
+		}
+		fmt.Printf("Migration créée : %s\n", path)
+	},
+}
+
+// openMigrator ouvre la base de données configurée et construit le Migrator.
+// La fonction retournée ferme la connexion et doit être appelée via defer.
+func openMigrator() (*migrations.Migrator, func()) {
+	// Récupérer la configuration chargée globalement via cmd.Cfg
+	if cmd2.Cfg == nil {
+		log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+	}
+
+	// Initialiser la connexion à la base de données configurée, sans exiger un schéma à jour.
+	db, err := cmd2.OpenMigrationDatabase()
+	if err != nil {
+		log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+
+	return migrations.NewMigrator(db, cmd2.Logger), func() { _ = sqlDB.Close() }
+}
+
 func init() {
+	MigrateCreateCmd.Flags().StringVar(&migrationsDirFlag, "dir", "internal/migrations", "Dossier des fichiers de migration")
+
+	MigrateCmd.AddCommand(MigrateUpCmd, MigrateDownCmd, MigrateStatusCmd, MigrateCreateCmd)
 	// Ajouter la commande à RootCmd
 	cmd2.RootCmd.AddCommand(MigrateCmd)
 }
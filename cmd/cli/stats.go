@@ -5,19 +5,33 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"text/tabwriter"
+	"time"
 
 	cmd2 "github.com/axellelanca/urlshortener/cmd"
 	"github.com/axellelanca/urlshortener/internal/repository"
 	"github.com/axellelanca/urlshortener/internal/services"
 	"github.com/spf13/cobra"
 
-	"github.com/glebarez/sqlite" // Driver SQLite pour GORM
 	"gorm.io/gorm"
 )
 
 // variable shortCodeFlag qui stockera la valeur du flag --code
 var shortCodeFlag string
 
+// variables qui stockeront les flags optionnels de la série temporelle (--by, --from, --to)
+var (
+	byFlag   string
+	fromFlag string
+	toFlag   string
+)
+
+// variable referrersFlag qui stockera la valeur du flag --referrers
+var referrersFlag bool
+
+// variable includeBotsFlag qui stockera la valeur du flag --include-bots
+var includeBotsFlag bool
+
 // StatsCmd représente la commande 'stats'
 var StatsCmd = &cobra.Command{
 	Use:   "stats",
@@ -25,8 +39,17 @@ var StatsCmd = &cobra.Command{
 	Long: `Cette commande permet de récupérer et d'afficher le nombre total de clics
 pour une URL courte spécifique en utilisant son code.
 
+Les clics de robots (aperçus de liens, supervision...) sont exclus des totaux et affichés à part ;
+--include-bots les réintègre dans le classement des référents et la série temporelle.
+Avec --referrers, la commande affiche les hôtes référents ayant généré le plus de clics.
+Avec --by, la commande affiche également le nombre de clics par heure, jour ou semaine
+(intervalles alignés sur UTC, les semaines commençant le lundi), sur la période bornée
+par --from et --to ; ces deux flags ne s'appliquent qu'à la série temporelle.
+
 Exemple:
-  url-shortener stats --code="xyz123"`,
+  url-shortener stats --code="xyz123"
+  url-shortener stats --code="xyz123" --referrers
+  url-shortener stats --code="xyz123" --by day --from 2025-01-01 --to 2025-02-01`,
 	Run: func(cmd *cobra.Command, args []string) {
 		// Valider que le flag --code a été fourni.
 		// os.Exit(1) si erreur
@@ -36,6 +59,37 @@ Exemple:
 			os.Exit(1)
 		}
 
+		// Valider les flags de la série temporelle avant de se connecter à la base.
+		// --from et --to ne bornent que la série temporelle : sans --by, ils seraient ignorés.
+		if byFlag == "" && (fromFlag != "" || toFlag != "") {
+			fmt.Println("Erreur : les flags --from et --to nécessitent --by")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+		var granularity services.Granularity
+		var from, to time.Time
+		if byFlag != "" {
+			var err error
+			if granularity, err = services.ParseGranularity(byFlag); err != nil {
+				fmt.Printf("Erreur : %v\n", err)
+				os.Exit(1)
+			}
+			to = time.Now()
+			if toFlag != "" {
+				if to, err = parseTimeFlag(toFlag); err != nil {
+					fmt.Printf("Erreur : date --to invalide '%s' (formats acceptés : RFC 3339 ou AAAA-MM-JJ)\n", toFlag)
+					os.Exit(1)
+				}
+			}
+			from = granularity.DefaultRange(to)
+			if fromFlag != "" {
+				if from, err = parseTimeFlag(fromFlag); err != nil {
+					fmt.Printf("Erreur : date --from invalide '%s' (formats acceptés : RFC 3339 ou AAAA-MM-JJ)\n", fromFlag)
+					os.Exit(1)
+				}
+			}
+		}
+
 		// Charger la configuration chargée globalement via cmd.cfg
 		cfg := cmd2.Cfg
 		if cfg == nil {
@@ -44,7 +98,7 @@ Exemple:
 
 		// 3: Initialiser la connexion à la BDD.
 		// log.Fatalf si erreur
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		db, err := cmd2.OpenDatabase()
 		if err != nil {
 			log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
 		}
@@ -59,7 +113,7 @@ Exemple:
 
 		// Initialiser les repositories et services nécessaires NewLinkRepository & NewLinkService
 		linkRepo := repository.NewLinkRepository(db)
-		linkService := services.NewLinkService(linkRepo)
+		linkService := services.NewLinkService(linkRepo, cmd2.Logger)
 
 		// 5: Appeler GetLinkStats pour récupérer le lien et ses statistiques.
 		// Attention, la fonction retourne 3 valeurs
@@ -77,14 +131,96 @@ Exemple:
 		fmt.Printf("Statistiques pour le code court: %s\n", link.ShortCode)
 		fmt.Printf("URL longue: %s\n", link.LongURL)
 		fmt.Printf("Total de clics: %d\n", totalClicks)
+
+		clickService := services.NewClickService(repository.NewClickRepository(db))
+		botClicks, err := clickService.GetBotClicksCount(link.ID)
+		if err != nil {
+			log.Fatalf("FATAL: échec du comptage des clics de robots : %v", err)
+		}
+		fmt.Printf("Clics de robots (exclus du total): %d\n", botClicks)
+
+		uniqueVisitors, err := clickService.GetUniqueVisitorsCount(link.ID)
+		if err != nil {
+			log.Fatalf("FATAL: échec du comptage des visiteurs uniques : %v", err)
+		}
+		fmt.Printf("Visiteurs uniques: %d\n", uniqueVisitors)
+
+		// Afficher la durée de vie restante si le lien est limité dans le temps ou en nombre de clics.
+		lifetime := services.ComputeLifetime(link, time.Now())
+		if lifetime.ExpiresAt != nil {
+			fmt.Printf("Expire le: %s (dans %ds)\n", lifetime.ExpiresAt.Format(time.RFC3339), *lifetime.ExpiresInSeconds)
+		}
+		if lifetime.RemainingClicks != nil {
+			fmt.Printf("Clics restants: %d / %d\n", *lifetime.RemainingClicks, lifetime.MaxClicks)
+		}
+		if lifetime.Expired {
+			fmt.Println("Statut: expiré")
+		}
+
+		if referrersFlag {
+			referrers, err := clickService.GetTopReferrers(link.ID, services.DefaultTopLimit, includeBotsFlag)
+			if err != nil {
+				log.Fatalf("FATAL: échec de la récupération des référents : %v", err)
+			}
+			fmt.Println("\nPrincipaux référents:")
+			if len(referrers) == 0 {
+				fmt.Println("Aucun clic enregistré.")
+			}
+			for _, r := range referrers {
+				fmt.Printf("%-40s  %d\n", r.Host, r.Clicks)
+			}
+		}
+
+		if byFlag == "" {
+			return
+		}
+
+		// Afficher la série temporelle des clics, intervalles vides compris.
+		points, err := clickService.GetClickTimeSeries(link.ID, from, to, granularity, includeBotsFlag)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTimeRange) {
+				fmt.Printf("Erreur : %v\n", err)
+				os.Exit(1)
+			}
+			log.Fatalf("FATAL: échec de la récupération de la série temporelle : %v", err)
+		}
+
+		fmt.Printf("\nClics par %s (UTC):\n", granularity)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DÉBUT\tCLICS\tVISITEURS UNIQUES")
+		for _, p := range points {
+			fmt.Fprintf(w, "%s\t%d\t%d\n", formatBucketStart(p.Start, granularity), p.Clicks, p.UniqueVisitors)
+		}
+		_ = w.Flush()
 	},
 }
 
+// parseTimeFlag interprète une date passée en flag, au format RFC 3339 ou AAAA-MM-JJ.
+func parseTimeFlag(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// formatBucketStart formate le début d'un intervalle selon sa granularité.
+func formatBucketStart(start time.Time, granularity services.Granularity) string {
+	if granularity == services.GranularityHour {
+		return start.Format("2006-01-02 15:00")
+	}
+	return start.Format(time.DateOnly)
+}
+
 // init() s'exécute automatiquement lors de l'importation du package.
 // Il est utilisé pour définir les flags que cette commande accepte.
 func init() {
 	// Définir le flag --code pour la commande stats.
 	StatsCmd.Flags().StringVar(&shortCodeFlag, "code", "", "Code court du lien à inspecter")
+	StatsCmd.Flags().BoolVar(&includeBotsFlag, "include-bots", false, "Inclut les clics de robots dans les référents et la série temporelle")
+	StatsCmd.Flags().BoolVar(&referrersFlag, "referrers", false, "Affiche les principaux hôtes référents")
+	StatsCmd.Flags().StringVar(&byFlag, "by", "", "Affiche les clics par intervalle : hour, day ou week (optionnel)")
+	StatsCmd.Flags().StringVar(&fromFlag, "from", "", "Début de la série temporelle, RFC 3339 ou AAAA-MM-JJ (avec --by)")
+	StatsCmd.Flags().StringVar(&toFlag, "to", "", "Fin (exclue) de la série temporelle, RFC 3339 ou AAAA-MM-JJ (avec --by)")
 
 	// Marquer le flag comme requis
 	if err := StatsCmd.MarkFlagRequired("code"); err != nil {
@@ -0,0 +1,265 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	cmd2 "github.com/axellelanca/urlshortener/cmd"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/webhooks"
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+)
+
+// variables qui stockeront les valeurs des flags des sous-commandes 'webhooks'
+var (
+	webhookStatusFlag   string
+	webhookEndpointFlag string
+	webhookEventFlag    string
+	webhookLimitFlag    int
+	webhookIDFlag       uint
+)
+
+// WebhooksCmd regroupe les sous-commandes de gestion des webhooks.
+var WebhooksCmd = &cobra.Command{
+	Use:   "webhooks",
+	Short: "Consulte et teste les livraisons de webhooks.",
+	Long: `Cette commande regroupe la consultation du journal des livraisons de webhooks,
+l'envoi d'un événement de test et le renvoi d'une livraison.
+Les endpoints sont déclarés dans la section 'webhooks' de la configuration.`,
+}
+
+// WebhooksListCmd représente la commande 'webhooks list'
+var WebhooksListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Liste les dernières livraisons de webhooks.",
+	Long: `Cette commande liste les livraisons de webhooks, de la plus récente à la plus ancienne,
+avec leur statut, le nombre de tentatives et le dernier code HTTP reçu.
+
+Exemples:
+  url-shortener webhooks list
+  url-shortener webhooks list --status=failed --endpoint=alertes --limit=50`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		statuses := []string{models.DeliveryPending, models.DeliverySucceeded, models.DeliveryFailed}
+		if webhookStatusFlag != "" && !slices.Contains(statuses, webhookStatusFlag) {
+			fmt.Printf("Erreur : statut inconnu '%s' (attendu : %s, %s ou %s)\n", webhookStatusFlag, statuses[0], statuses[1], statuses[2])
+			os.Exit(1)
+		}
+
+		db, closeDB := openWebhooksDatabase()
+		defer closeDB()
+
+		deliveries, err := repository.NewWebhookDeliveryRepository(db).ListDeliveries(repository.WebhookDeliveryFilter{
+			Status:   webhookStatusFlag,
+			Endpoint: webhookEndpointFlag,
+			Event:    webhookEventFlag,
+			Limit:    webhookLimitFlag,
+		})
+		if err != nil {
+			closeDB()
+			log.Fatalf("FATAL: échec de la récupération des livraisons de webhooks : %v", err)
+		}
+		if len(deliveries) == 0 {
+			fmt.Println("Aucune livraison de webhook.")
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tENDPOINT\tÉVÉNEMENT\tSTATUT\tTENTATIVES\tCODE\tCRÉÉE LE\tDÉTAIL")
+		for _, delivery := range deliveries {
+			code := "-"
+			if delivery.ResponseCode != 0 {
+				code = strconv.Itoa(delivery.ResponseCode)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+				delivery.ID, delivery.Endpoint, delivery.Event, delivery.Status, delivery.Attempts, code,
+				delivery.CreatedAt.Format(time.RFC3339), deliveryDetail(delivery))
+		}
+		_ = w.Flush()
+	},
+}
+
+// WebhooksTestCmd représente la commande 'webhooks test'
+var WebhooksTestCmd = &cobra.Command{
+	Use:   "test",
+	Short: "Envoie un événement de test à un endpoint.",
+	Long: `Cette commande envoie immédiatement un événement 'webhook.test' signé à l'endpoint indiqué,
+quels que soient ses abonnements, et affiche le résultat. L'envoi n'est pas réessayé.
+
+Exemple:
+  url-shortener webhooks test --endpoint=alertes`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if webhookEndpointFlag == "" {
+			fmt.Println("Erreur : le flag --endpoint est obligatoire")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+
+		dispatcher, closeDB := openWebhookDispatcher()
+		defer closeDB()
+
+		delivery, err := dispatcher.Test(webhookEndpointFlag)
+		if errors.Is(err, webhooks.ErrUnknownEndpoint) {
+			closeDB()
+			fmt.Printf("Aucun endpoint de webhook nommé '%s' dans la configuration.\n", webhookEndpointFlag)
+			os.Exit(1)
+		}
+		if err != nil {
+			closeDB()
+			log.Fatalf("FATAL: échec de l'envoi de test : %v", err)
+		}
+		if !printAttempt(delivery) {
+			closeDB()
+			os.Exit(1)
+		}
+	},
+}
+
+// WebhooksReplayCmd représente la commande 'webhooks replay'
+var WebhooksReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Renvoie une livraison de webhook.",
+	Long: `Cette commande renvoie l'événement d'une livraison terminée (réussie ou abandonnée)
+à son endpoint, avec le même corps et le même identifiant d'événement. La nouvelle livraison
+est tentée immédiatement ; en cas d'échec, elle est réessayée par le serveur.
+
+Exemple:
+  url-shortener webhooks replay --id=42`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if webhookIDFlag == 0 {
+			fmt.Println("Erreur : le flag --id est obligatoire")
+			_ = cmd.Usage()
+			os.Exit(1)
+		}
+
+		dispatcher, closeDB := openWebhookDispatcher()
+		defer closeDB()
+
+		delivery, err := dispatcher.Replay(webhookIDFlag)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			closeDB()
+			fmt.Printf("Aucune livraison de webhook trouvée pour l'ID : %d\n", webhookIDFlag)
+			os.Exit(1)
+		case errors.Is(err, webhooks.ErrDeliveryPending):
+			closeDB()
+			fmt.Printf("La livraison %d est encore en attente : elle sera tentée par le serveur.\n", webhookIDFlag)
+			os.Exit(1)
+		case errors.Is(err, webhooks.ErrUnknownEndpoint):
+			closeDB()
+			fmt.Printf("L'endpoint de la livraison %d n'est plus configuré.\n", webhookIDFlag)
+			os.Exit(1)
+		case err != nil:
+			closeDB()
+			log.Fatalf("FATAL: échec du renvoi de la livraison : %v", err)
+		}
+
+		fmt.Printf("Livraison %d renvoyée (nouvelle livraison : %d).\n", webhookIDFlag, delivery.ID)
+		if !printAttempt(delivery) {
+			closeDB()
+			os.Exit(1)
+		}
+	},
+}
+
+// printAttempt affiche le résultat de la première tentative d'une livraison.
+// Retourne true si elle a réussi.
+func printAttempt(delivery *models.WebhookDelivery) bool {
+	if delivery.Status == models.DeliverySucceeded {
+		fmt.Printf("Livraison %d vers '%s' réussie (HTTP %d).\n", delivery.ID, delivery.Endpoint, delivery.ResponseCode)
+		return true
+	}
+	fmt.Printf("Échec de la livraison %d vers '%s' : %s\n", delivery.ID, delivery.Endpoint, delivery.LastError)
+	if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil {
+		fmt.Printf("Nouvelle tentative par le serveur à partir de %s.\n", delivery.NextAttemptAt.Format(time.RFC3339))
+	}
+	return false
+}
+
+// deliveryDetail décrit l'état d'une livraison : la prochaine tentative si elle est en attente,
+// sinon la cause du dernier échec.
+func deliveryDetail(delivery models.WebhookDelivery) string {
+	detail := delivery.LastError
+	if delivery.Status == models.DeliveryPending && delivery.NextAttemptAt != nil {
+		detail = "prochaine tentative " + delivery.NextAttemptAt.Format(time.RFC3339)
+		if delivery.LastError != "" {
+			detail += " (" + delivery.LastError + ")"
+		}
+	}
+	if delivery.ReplayOf != nil {
+		detail = strings.TrimSpace(fmt.Sprintf("renvoi de %d %s", *delivery.ReplayOf, detail))
+	}
+	if detail == "" {
+		return "-"
+	}
+	return detail
+}
+
+// openWebhooksDatabase ouvre la base de données configurée.
+// La fonction retournée ferme la connexion et doit être appelée via defer.
+func openWebhooksDatabase() (*gorm.DB, func()) {
+	if cmd2.Cfg == nil {
+		log.Fatalf("FATAL: la configuration globale n'a pas été chargée")
+	}
+
+	db, err := cmd2.OpenDatabase()
+	if err != nil {
+		log.Fatalf("FATAL: impossible de se connecter à la base de données: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatalf("FATAL: Échec de l'obtention de la base de données SQL sous-jacente: %v", err)
+	}
+	return db, func() { _ = sqlDB.Close() }
+}
+
+// openWebhookDispatcher ouvre la base de données et construit le Dispatcher des webhooks.
+// Termine la commande si aucun endpoint n'est configuré.
+func openWebhookDispatcher() (*webhooks.Dispatcher, func()) {
+	if cmd2.Cfg != nil && len(cmd2.Cfg.Webhooks.Endpoints) == 0 {
+		fmt.Println("Aucun endpoint de webhook configuré (section 'webhooks.endpoints').")
+		os.Exit(1)
+	}
+	db, closeDB := openWebhooksDatabase()
+	dispatcher, err := cmd2.Webhooks(db)
+	if err != nil {
+		closeDB()
+		log.Fatalf("FATAL: configuration des webhooks invalide: %v", err)
+	}
+	return dispatcher, closeDB
+}
+
+// init() s'exécute automatiquement lors de l'importation du package.
+// Il définit les flags des sous-commandes et les rattache à RootCmd.
+func init() {
+	WebhooksListCmd.Flags().StringVar(&webhookStatusFlag, "status", "", "Filtre sur le statut (pending, succeeded ou failed)")
+	WebhooksListCmd.Flags().StringVar(&webhookEndpointFlag, "endpoint", "", "Filtre sur le nom de l'endpoint")
+	WebhooksListCmd.Flags().StringVar(&webhookEventFlag, "event", "", "Filtre sur le type d'événement (link.down, link.created...)")
+	WebhooksListCmd.Flags().IntVar(&webhookLimitFlag, "limit", 20, "Nombre maximal de livraisons affichées (0 = toutes)")
+
+	WebhooksTestCmd.Flags().StringVar(&webhookEndpointFlag, "endpoint", "", "Nom de l'endpoint à tester")
+	if err := WebhooksTestCmd.MarkFlagRequired("endpoint"); err != nil {
+		log.Printf("WARN: impossible de marquer --endpoint comme requis: %v", err)
+	}
+
+	WebhooksReplayCmd.Flags().UintVar(&webhookIDFlag, "id", 0, "ID de la livraison à renvoyer")
+	if err := WebhooksReplayCmd.MarkFlagRequired("id"); err != nil {
+		log.Printf("WARN: impossible de marquer --id comme requis: %v", err)
+	}
+
+	WebhooksCmd.AddCommand(WebhooksListCmd, WebhooksTestCmd, WebhooksReplayCmd)
+
+	// Ajouter la commande à RootCmd
+	cmd2.RootCmd.AddCommand(WebhooksCmd)
+}
@@ -1,16 +1,37 @@
 package cmd
 
 import (
+	"fmt"
 	"log"
+	"log/slog"
+	"os"
+	"time"
 
 	"github.com/axellelanca/urlshortener/internal/config"
+	"github.com/axellelanca/urlshortener/internal/database"
+	"github.com/axellelanca/urlshortener/internal/logging"
+	"github.com/axellelanca/urlshortener/internal/migrations"
+	"github.com/axellelanca/urlshortener/internal/netguard"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/webhooks"
 	"github.com/spf13/cobra"
+	"gorm.io/gorm"
 )
 
 // cfg est la variable globale qui contiendra la configuration chargée.
 // Elle sera accessible à toutes les commandes Cobra.
 var Cfg *config.Config
 
+// Logger est le logger structuré de l'application, configuré par la section 'log' de la configuration.
+// Il est aussi installé comme logger par défaut (slog et log).
+var Logger = slog.Default()
+
+// LogLevel est le niveau minimal de Logger ; il peut être modifié à chaud.
+var LogLevel = new(slog.LevelVar)
+
+// cfgFile stocke la valeur de l'option globale --config.
+var cfgFile string
+
 // RootCmd représente la commande de base lorsque l'on appelle l'application sans sous-commande.
 // C'est le point d'entrée principal pour Cobra.
 var RootCmd = &cobra.Command{
@@ -39,6 +60,18 @@ func init() {
 	// Initialiser la configuration globale avec OnInitialize
 	cobra.OnInitialize(initConfig)
 
+	// Options globales, disponibles pour toutes les sous-commandes.
+	// --db et --port surchargent le fichier de configuration et les variables d'environnement.
+	flags := RootCmd.PersistentFlags()
+	flags.StringVar(&cfgFile, "config", "", "Chemin du fichier de configuration (par défaut : configs/config.yaml, ou $"+config.ConfigFileEnv+")")
+	flags.String("db", "", "Fichier de base SQLite à utiliser (surcharge database.name, refusé avec PostgreSQL et MySQL)")
+	flags.Int("port", 0, "Port d'écoute du serveur HTTP (surcharge server.port)")
+	for key, name := range map[string]string{"database.name": "db", "server.port": "port"} {
+		if err := config.BindFlag(key, flags.Lookup(name)); err != nil {
+			log.Fatalf("FATAL: %v", err)
+		}
+	}
+
 	// IMPORTANT : Ici, nous n'appelons PAS RootCmd.AddCommand() directement
 	// pour les commandes 'server', 'create', 'stats', 'migrate'.
 	// Ces commandes s'enregistreront elles-mêmes via leur propre fonction init().
@@ -55,13 +88,113 @@ func init() {
 // grâce à la méthode OnInitialize utilisée dans init().
 func initConfig() {
 	var err error
-	Cfg, err = config.LoadConfig()
+	Cfg, err = config.LoadConfig(cfgFile)
 	if err != nil {
-		// Loggue l'erreur mais ne fait pas un os.Exit(1) ici si LoadConfig()
-		// gère déjà l'absence de fichier avec des valeurs par défaut.
-		// Si LoadConfig() termine le programme en cas d'erreur fatale,
-		// cette vérification est surtout pour les avertissements.
-		log.Printf("Attention: Problème lors du chargement de la configuration: %v. Utilisation des valeurs par défaut.", err)
+		// Un fichier illisible ou une configuration invalide arrête immédiatement la commande :
+		// mieux vaut échouer au démarrage que tourner avec une configuration inattendue.
+		// L'absence du fichier par défaut n'est pas une erreur (valeurs par défaut).
+		fmt.Fprintf(os.Stderr, "Erreur de configuration : %v\n", err)
+		os.Exit(1)
+	}
+	// --db surcharge database.name, qui n'est utilisé que par SQLite : avec PostgreSQL ou MySQL,
+	// la base est désignée par database.dsn et l'option serait ignorée sans avertissement.
+	if RootCmd.PersistentFlags().Changed("db") && Cfg.Database.Driver != database.DriverSQLite {
+		fmt.Fprintf(os.Stderr, "Erreur de configuration : --db only applies to the %s driver (database.driver is '%s'), set database.dsn instead\n",
+			database.DriverSQLite, Cfg.Database.Driver)
+		os.Exit(1)
 	}
 	// La configuration est maintenant disponible via la variable globale 'cmd.cfg'.
+
+	setupLogger()
+	Logger.Debug("configuration loaded",
+		"port", Cfg.Server.Port,
+		"database_driver", Cfg.Database.Driver,
+		"database", Cfg.Database.Name,
+		"analytics_buffer", Cfg.Analytics.BufferSize,
+		"monitor_interval_minutes", Cfg.Monitor.IntervalMinutes)
+}
+
+// OpenDatabase ouvre la base de données configurée et vérifie que son schéma est à jour.
+// Si des migrations sont en attente, la connexion est fermée et l'erreur retournée enveloppe
+// migrations.ErrSchemaBehind : les requêtes échoueraient sur des tables ou des colonnes absentes.
+// Toutes les commandes passent par cette fonction, sauf 'migrate' (voir OpenMigrationDatabase).
+func OpenDatabase() (*gorm.DB, error) {
+	db, err := OpenMigrationDatabase()
+	if err != nil {
+		return nil, err
+	}
+	if err := migrations.NewMigrator(db, Logger).CheckUpToDate(); err != nil {
+		if sqlDB, dbErr := db.DB(); dbErr == nil {
+			_ = sqlDB.Close()
+		}
+		return nil, fmt.Errorf("%w (run 'url-shortener migrate up')", err)
+	}
+	return db, nil
+}
+
+// OpenMigrationDatabase ouvre la base de données configurée (pilote, connexion et pool de connexions),
+// avec les logs SQL dirigés vers Logger, sans vérifier son schéma. Réservée aux commandes 'migrate'.
+func OpenMigrationDatabase() (*gorm.DB, error) {
+	db := Cfg.Database
+	return database.Open(database.Options{
+		Driver:          db.Driver,
+		Name:            db.Name,
+		DSN:             db.DSN,
+		MaxOpenConns:    db.MaxOpenConns,
+		MaxIdleConns:    db.MaxIdleConns,
+		ConnMaxLifetime: time.Duration(db.ConnMaxLifetimeSeconds) * time.Second,
+		ConnMaxIdleTime: time.Duration(db.ConnMaxIdleTimeSeconds) * time.Second,
+	}, Logger)
+}
+
+// NetworkGuard construit le Guard des requêtes sortantes à partir de la configuration.
+// Retourne nil si network_guard.enabled est faux : aucune destination n'est alors refusée.
+func NetworkGuard() (*netguard.Guard, error) {
+	if !Cfg.NetworkGuard.Enabled {
+		return nil, nil
+	}
+	return netguard.New(Cfg.NetworkGuard.AllowedNetworks)
+}
+
+// Webhooks construit le Dispatcher des webhooks à partir de la configuration. Comme le moniteur,
+// il ne contacte pas les adresses internes si network_guard est activé.
+// Retourne nil si aucun endpoint n'est configuré : aucun événement n'est alors publié.
+func Webhooks(db *gorm.DB) (*webhooks.Dispatcher, error) {
+	cfg := Cfg.Webhooks
+	if len(cfg.Endpoints) == 0 {
+		return nil, nil
+	}
+	guard, err := NetworkGuard()
+	if err != nil {
+		return nil, err
+	}
+	endpoints := make([]webhooks.Endpoint, 0, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		endpoints = append(endpoints, webhooks.Endpoint{
+			Name:   endpoint.Name,
+			URL:    endpoint.URL,
+			Secret: string(endpoint.Secret),
+			Events: endpoint.Events,
+		})
+	}
+	return webhooks.NewDispatcher(repository.NewWebhookDeliveryRepository(db), webhooks.Config{
+		Endpoints:      endpoints,
+		MaxAttempts:    cfg.MaxAttempts,
+		InitialBackoff: time.Duration(cfg.InitialBackoffSeconds) * time.Second,
+		MaxBackoff:     time.Duration(cfg.MaxBackoffSeconds) * time.Second,
+		Timeout:        time.Duration(cfg.TimeoutSeconds) * time.Second,
+		Retention:      time.Duration(cfg.DeliveryRetentionDays) * 24 * time.Hour,
+		BaseURL:        Cfg.Server.BaseURL,
+		Guard:          guard,
+	}, Logger), nil
+}
+
+// setupLogger construit Logger à partir de la configuration et l'installe comme logger par défaut.
+// Le niveau et le format ont déjà été vérifiés par config.Validate.
+func setupLogger() {
+	level, _ := logging.ParseLevel(Cfg.Log.Level)
+	LogLevel.Set(level)
+
+	Logger, _ = logging.New(os.Stderr, Cfg.Log.Format, LogLevel)
+	slog.SetDefault(Logger)
 }
@@ -0,0 +1,132 @@
+package server
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	cmd2 "github.com/axellelanca/urlshortener/cmd"
+	"github.com/axellelanca/urlshortener/internal/api"
+	"github.com/axellelanca/urlshortener/internal/config"
+	"github.com/axellelanca/urlshortener/internal/logging"
+	"github.com/axellelanca/urlshortener/internal/monitor"
+	"github.com/axellelanca/urlshortener/internal/workers"
+)
+
+// configReloader applique à chaud les modifications du fichier de configuration.
+// Seules les clés rechargeables (config.ReloadableKeys) sont appliquées ; les autres
+// modifications sont signalées et ignorées jusqu'au prochain redémarrage.
+type configReloader struct {
+	mu       sync.Mutex
+	current  *config.Config // Configuration effective : les clés non rechargeables gardent leur valeur de démarrage
+	stopped  bool           // Vrai une fois l'arrêt du serveur commencé
+	monitor  *monitor.UrlMonitor
+	workers  *workers.ClickWorkerPool
+	limiters *api.RateLimiters // nil si la limitation de débit est désactivée
+	logger   *slog.Logger
+	requests uint64 // Nombre de rechargements reçus, pour corréler les logs
+}
+
+// newConfigReloader crée un configReloader partant de la configuration de démarrage 'cfg'.
+// 'limiters' vaut nil si la limitation de débit est désactivée : ses politiques ne sont alors pas rechargées.
+func newConfigReloader(cfg *config.Config, urlMonitor *monitor.UrlMonitor, clickWorkers *workers.ClickWorkerPool, limiters *api.RateLimiters, logger *slog.Logger) *configReloader {
+	current := *cfg
+	return &configReloader{
+		current:  &current,
+		monitor:  urlMonitor,
+		workers:  clickWorkers,
+		limiters: limiters,
+		logger:   logger.With("component", "config_reload"),
+	}
+}
+
+// watch lance la surveillance du fichier de configuration.
+func (r *configReloader) watch() {
+	if err := config.Watch(r.reload); err != nil {
+		r.logger.Info("configuration hot reload disabled", "reason", err)
+		return
+	}
+	r.logger.Info("watching configuration file for changes",
+		"file", config.ConfigFileUsed(), "reloadable_keys", config.ReloadableKeys())
+}
+
+// stop désactive les rechargements : appelée au début de l'arrêt du serveur, avant la fermeture
+// du channel des clics, pour qu'aucun worker ne soit relancé ensuite.
+func (r *configReloader) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+}
+
+// reload compare la nouvelle configuration à la configuration effective et applique
+// les modifications des clés rechargeables. Une configuration invalide est ignorée en entier.
+func (r *configReloader) reload(next *config.Config, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopped {
+		return
+	}
+	r.requests++
+	logger := r.logger.With("reload", r.requests)
+
+	if err != nil {
+		logger.Error("configuration change rejected, keeping current configuration", "error", err)
+		return
+	}
+
+	changes := r.current.Diff(next)
+	if len(changes) == 0 {
+		logger.Debug("configuration file changed, no effective change")
+		return
+	}
+
+	applied := 0
+	for _, change := range changes {
+		if !change.Reloadable() {
+			logger.Warn("configuration change requires a restart, ignored",
+				"key", change.Key, "current", change.Old, "requested", change.New)
+			continue
+		}
+		r.apply(next, change.Key)
+		logger.Info("configuration change applied", "key", change.Key, "previous", change.Old, "value", change.New)
+		applied++
+	}
+	logger.Info("configuration reloaded", "applied", applied, "ignored", len(changes)-applied)
+}
+
+// apply applique la nouvelle valeur d'une clé rechargeable et la reporte dans la configuration effective.
+func (r *configReloader) apply(next *config.Config, key string) {
+	switch key {
+	case "monitor.interval_minutes":
+		r.current.Monitor.IntervalMinutes = next.Monitor.IntervalMinutes
+		r.monitor.SetInterval(time.Duration(next.Monitor.IntervalMinutes) * time.Minute)
+	case "analytics.worker_count":
+		r.current.Analytics.WorkerCount = next.Analytics.WorkerCount
+		r.workers.Resize(next.Analytics.WorkerCount)
+	case "log.level":
+		// Le niveau a déjà été vérifié par config.Validate.
+		level, _ := logging.ParseLevel(next.Log.Level)
+		r.current.Log.Level = next.Log.Level
+		cmd2.LogLevel.Set(level)
+	case "rate_limit.api.requests_per_minute", "rate_limit.api.burst":
+		r.current.RateLimit.API = next.RateLimit.API
+		if r.limiters != nil {
+			r.limiters.API.SetPolicy(rateLimitPolicy(next.RateLimit.API))
+		}
+	case "rate_limit.create.requests_per_minute", "rate_limit.create.burst":
+		r.current.RateLimit.Create = next.RateLimit.Create
+		if r.limiters != nil {
+			r.limiters.Create.SetPolicy(rateLimitPolicy(next.RateLimit.Create))
+		}
+	case "rate_limit.stats.requests_per_minute", "rate_limit.stats.burst":
+		r.current.RateLimit.Stats = next.RateLimit.Stats
+		if r.limiters != nil {
+			r.limiters.Stats.SetPolicy(rateLimitPolicy(next.RateLimit.Stats))
+		}
+	case "rate_limit.redirect.requests_per_minute", "rate_limit.redirect.burst":
+		r.current.RateLimit.Redirect = next.RateLimit.Redirect
+		if r.limiters != nil {
+			r.limiters.Redirect.SetPolicy(rateLimitPolicy(next.RateLimit.Redirect))
+		}
+	}
+}
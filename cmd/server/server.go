@@ -1,8 +1,11 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,17 +14,27 @@ import (
 
 	cmd2 "github.com/axellelanca/urlshortener/cmd"
 	"github.com/axellelanca/urlshortener/internal/api"
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/config"
+	"github.com/axellelanca/urlshortener/internal/journal"
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/migrations"
 	"github.com/axellelanca/urlshortener/internal/models"
 	"github.com/axellelanca/urlshortener/internal/monitor"
+	"github.com/axellelanca/urlshortener/internal/ratelimit"
 	"github.com/axellelanca/urlshortener/internal/repository"
 	"github.com/axellelanca/urlshortener/internal/services"
 	"github.com/axellelanca/urlshortener/internal/workers"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
-	"github.com/glebarez/sqlite" // Driver SQLite pour GORM
-	"gorm.io/gorm"
 )
 
+// monitorCheckTimeout borne la durée d'une vérification du moniteur, redirections comprises.
+const monitorCheckTimeout = 5 * time.Second
+
+// expirySweepInterval est l'intervalle de recherche des liens expirés non encore signalés.
+const expirySweepInterval = time.Minute
+
 // RunServerCmd représente la commande 'run-server' de Cobra.
 // C'est le point d'entrée pour lancer le serveur de l'application.
 var RunServerCmd = &cobra.Command{
@@ -36,51 +49,172 @@ puis lance le serveur HTTP.`,
 		if cfg == nil {
 			log.Fatalf("FATAL: Configuration non chargée")
 		}
+		logger := cmd2.Logger
 
-		// Initialiser la connexion à la BDD
-		db, err := gorm.Open(sqlite.Open(cfg.Database.Name), &gorm.Config{})
+		// Initialiser la connexion à la BDD. OpenDatabase refuse un schéma en retard :
+		// les migrations s'appliquent avec 'url-shortener migrate up'.
+		db, err := cmd2.OpenDatabase()
+		if errors.Is(err, migrations.ErrSchemaBehind) {
+			fatal(logger, "database schema is not up to date, run 'url-shortener migrate up'", "error", err)
+		}
 		if err != nil {
-			log.Fatalf("FATAL: Échec de la connexion à la base de données: %v", err)
+			fatal(logger, "failed to connect to database", "error", err)
 		}
 
 		// Initialiser les repositories.
-		linkRepo := repository.NewLinkRepository(db)
+		var linkRepo repository.LinkRepository = repository.NewLinkRepository(db)
+		if cfg.Cache.Enabled {
+			// Cache en mémoire devant la recherche par code court : les redirections des liens
+			// fréquemment visités n'interrogent plus la base.
+			linkRepo = repository.NewCachedLinkRepository(linkRepo, repository.LinkCacheConfig{
+				Size:        cfg.Cache.Size,
+				TTL:         time.Duration(cfg.Cache.TTLSeconds) * time.Second,
+				NegativeTTL: time.Duration(cfg.Cache.NegativeTTLSeconds) * time.Second,
+			})
+			logger.Info("link cache enabled", "size", cfg.Cache.Size,
+				"ttl_seconds", cfg.Cache.TTLSeconds, "negative_ttl_seconds", cfg.Cache.NegativeTTLSeconds)
+		}
 		clickRepo := repository.NewClickRepository(db)
+		linkCheckRepo := repository.NewLinkCheckRepository(db)
+		apiKeyRepo := repository.NewAPIKeyRepository(db)
+		visitorSaltRepo := repository.NewVisitorSaltRepository(db)
 
-		// Laissez le log
-		log.Println("Repositories initialisés.")
+		logger.Debug("repositories initialized")
 
 		// Initialiser les services métiers.
-		linkService := services.NewLinkService(linkRepo)
-		_ = services.NewClickService(clickRepo) // Service disponible si nécessaire
+		linkService := services.NewLinkService(linkRepo, logger)
 
-		// Laissez le log
-		log.Println("Services métiers initialisés.")
+		// Protection du réseau interne : le moniteur ne contacte pas les adresses privées, de boucle locale
+		// ou de métadonnées, et la création de liens vers ces adresses peut être refusée.
+		guard, err := cmd2.NetworkGuard()
+		if err != nil {
+			fatal(logger, "invalid network guard configuration", "error", err)
+		}
+		if guard != nil {
+			logger.Info("network guard enabled", "allowed_networks", cfg.NetworkGuard.AllowedNetworks,
+				"check_on_create", cfg.NetworkGuard.CheckOnCreate)
+			if cfg.NetworkGuard.CheckOnCreate {
+				linkService.SetNetworkGuard(guard)
+			}
+		} else {
+			logger.Warn("network guard disabled, the url monitor and webhooks can reach internal addresses")
+		}
+
+		// Webhooks : les événements des liens sont enregistrés en base puis livrés en arrière-plan,
+		// avec de nouvelles tentatives en cas d'échec.
+		dispatcher, err := cmd2.Webhooks(db)
+		if err != nil {
+			fatal(logger, "invalid webhooks configuration", "error", err)
+		}
+		if dispatcher != nil {
+			names := make([]string, 0, len(cfg.Webhooks.Endpoints))
+			for _, endpoint := range cfg.Webhooks.Endpoints {
+				names = append(names, endpoint.Name)
+			}
+			logger.Info("webhooks enabled", "endpoints", names, "max_attempts", cfg.Webhooks.MaxAttempts)
+			linkService.SetWebhooks(dispatcher)
+			go dispatcher.Start()
+		}
+		clickService := services.NewClickService(clickRepo)
+		healthService := services.NewHealthService(linkCheckRepo)
+		apiKeyService := services.NewAPIKeyService(apiKeyRepo, logger)
+
+		logger.Debug("services initialized")
+
+		// Charger les signatures de robots supplémentaires éventuelles, en plus de la liste intégrée.
+		var extraSignatures []string
+		if cfg.Analytics.BotSignaturesFile != "" {
+			extraSignatures, err = botfilter.LoadSignatures(cfg.Analytics.BotSignaturesFile)
+			if err != nil {
+				fatal(logger, "failed to load bot signatures", "file", cfg.Analytics.BotSignaturesFile, "error", err)
+			}
+			logger.Info("extra bot signatures loaded", "file", cfg.Analytics.BotSignaturesFile, "signatures", len(extraSignatures))
+		}
+		classifier := botfilter.NewClassifier(extraSignatures)
 
 		// Initialiser le channel ClickEventsChannel (api/handlers) des événements de clic et lancer les workers (StartClickWorkers).
 		api.ClickEventsChannel = make(chan models.ClickEvent, cfg.Analytics.BufferSize)
-		workers.StartClickWorkers(cfg.Analytics.WorkerCount, api.ClickEventsChannel, clickRepo)
+		metrics.RegisterClickChannel(func() int { return len(api.ClickEventsChannel) }, cfg.Analytics.BufferSize)
+		visitorHasher := workers.NewVisitorHasher(visitorSaltRepo, logger)
+		clickWorkers := workers.StartClickWorkers(workers.ClickWorkerConfig{
+			WorkerCount:   cfg.Analytics.WorkerCount,
+			BatchSize:     cfg.Analytics.BatchSize,
+			FlushInterval: time.Duration(cfg.Analytics.FlushIntervalMs) * time.Millisecond,
+		}, api.ClickEventsChannel, clickRepo, classifier, visitorHasher, logger)
+
+		// Ouvrir le journal de débordement et lancer sa relecture : les clics laissés par une exécution
+		// précédente sont enregistrés immédiatement, puis le journal est relu périodiquement.
+		var replayer *workers.JournalReplayer
+		if cfg.Analytics.Spill.Enabled {
+			clickJournal, err := journal.Open(cfg.Analytics.Spill.Dir, cfg.Analytics.Spill.MaxSegmentBytes)
+			if err != nil {
+				fatal(logger, "failed to open click spill journal", "dir", cfg.Analytics.Spill.Dir, "error", err)
+			}
+			api.ClickJournal = clickJournal
+			replayer = workers.NewJournalReplayer(clickJournal, clickWorkers, time.Duration(cfg.Analytics.Spill.ReplayIntervalSeconds)*time.Second)
+			go replayer.Start()
+			logger.Info("click spill journal enabled", "dir", cfg.Analytics.Spill.Dir)
+		}
 
-		log.Printf("Channel d'événements de clic initialisé avec un buffer de %d. %d worker(s) de clics démarré(s).",
-			cfg.Analytics.BufferSize, cfg.Analytics.WorkerCount)
+		logger.Info("click pipeline started", "buffer_size", cfg.Analytics.BufferSize, "workers", cfg.Analytics.WorkerCount)
 
 		// Initialiser et lancer le moniteur d'URLs.
 		// Utilisez l'intervalle configuré
 		monitorInterval := time.Duration(cfg.Monitor.IntervalMinutes) * time.Minute
-		urlMonitor := monitor.NewUrlMonitor(linkRepo, monitorInterval) // Le moniteur a besoin du linkRepo et de l'interval
+		urlMonitor := monitor.NewUrlMonitor(linkRepo, linkCheckRepo, monitor.Config{
+			Interval:           monitorInterval,
+			Concurrency:        cfg.Monitor.Concurrency,
+			PerHostConcurrency: cfg.Monitor.PerHostConcurrency,
+			HostDelay:          time.Duration(cfg.Monitor.HostDelayMs) * time.Millisecond,
+			PassTimeout:        time.Duration(cfg.Monitor.PassTimeoutSeconds) * time.Second,
+			CheckRetention:     time.Duration(cfg.Monitor.CheckRetentionDays) * 24 * time.Hour,
+		}, guard.NewHTTPClient(monitorCheckTimeout), logger)
+		urlMonitor.SetWebhooks(dispatcher)
 
 		// Lancer le moniteur dans sa propre goroutine.
 		go urlMonitor.Start()
 
-		log.Printf("Moniteur d'URLs démarré avec un intervalle de %v.", monitorInterval)
+		// Rechercher périodiquement les liens expirés qui ne sont plus visités, pour signaler leur expiration.
+		expirySweeper := workers.NewExpirySweeper(linkService, expirySweepInterval, logger)
+		go expirySweeper.Start()
 
 		// Configurer le routeur Gin et les handlers API.
-		// Passez les services nécessaires aux fonctions de configuration des routes.
-		router := gin.Default()
-		api.SetupRoutes(router, linkService, cfg.Server.BaseURL)
+		// gin.New remplace gin.Default : le journal d'accès et la récupération des panics
+		// passent par le logger structuré. Le mode debug de Gin n'est gardé qu'au niveau debug.
+		if !logger.Enabled(context.Background(), slog.LevelDebug) {
+			gin.SetMode(gin.ReleaseMode)
+		}
+		router := gin.New()
+		// Seuls les proxies de confiance peuvent fixer l'IP du client via X-Forwarded-For :
+		// c'est elle qui identifie le client pour la limitation de débit et les statistiques.
+		if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+			fatal(logger, "invalid trusted proxies", "trusted_proxies", cfg.Server.TrustedProxies, "error", err)
+		}
+		router.Use(api.AccessLogMiddleware(logger), api.RecoveryMiddleware())
+
+		// Limitation de débit par client, une politique par famille de routes.
+		var limiters *api.RateLimiters
+		if cfg.RateLimit.Enabled {
+			limiters = &api.RateLimiters{
+				API:      ratelimit.NewLimiter(rateLimitPolicy(cfg.RateLimit.API)),
+				Create:   ratelimit.NewLimiter(rateLimitPolicy(cfg.RateLimit.Create)),
+				Stats:    ratelimit.NewLimiter(rateLimitPolicy(cfg.RateLimit.Stats)),
+				Redirect: ratelimit.NewLimiter(rateLimitPolicy(cfg.RateLimit.Redirect)),
+			}
+			logger.Info("rate limiting enabled",
+				"api_per_minute", cfg.RateLimit.API.RequestsPerMinute,
+				"create_per_minute", cfg.RateLimit.Create.RequestsPerMinute,
+				"stats_per_minute", cfg.RateLimit.Stats.RequestsPerMinute,
+				"redirect_per_minute", cfg.RateLimit.Redirect.RequestsPerMinute)
+		}
+		api.SetupRoutes(router, linkService, clickService, healthService, apiKeyService, classifier, limiters, cfg.Server.BaseURL)
 
-		// Pas toucher au log
-		log.Println("Routes API configurées.")
+		// Surveiller le fichier de configuration pour appliquer à chaud les clés rechargeables
+		// (intervalle du moniteur, nombre de workers, niveau de log, politiques de limitation de débit).
+		reloader := newConfigReloader(cfg, urlMonitor, clickWorkers, limiters, logger)
+		reloader.watch()
+
+		logger.Debug("api routes configured")
 
 		// Créer le serveur HTTP Gin
 		serverAddr := fmt.Sprintf(":%d", cfg.Server.Port)
@@ -91,9 +225,9 @@ puis lance le serveur HTTP.`,
 
 		// Démarrer le serveur Gin dans une goroutine anonyme pour ne pas bloquer.
 		go func() {
-			log.Printf("Serveur HTTP démarré sur %s", serverAddr)
+			logger.Info("http server listening", "addr", serverAddr)
 			if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-				log.Fatalf("FATAL: Erreur lors du démarrage du serveur: %v", err)
+				fatal(logger, "http server failed", "addr", serverAddr, "error", err)
 			}
 		}()
 
@@ -103,17 +237,99 @@ puis lance le serveur HTTP.`,
 		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM) // Attendre Ctrl+C ou signal d'arrêt
 
 		// Bloquer jusqu'à ce qu'un signal d'arrêt soit reçu.
-		<-quit
-		log.Println("Signal d'arrêt reçu. Arrêt du serveur...")
+		sig := <-quit
+		logger.Info("shutdown signal received, stopping server", "signal", sig.String())
+
+		// Ignorer les modifications de configuration à partir d'ici : le pool de workers va s'arrêter.
+		reloader.stop()
 
-		// Arrêt propre du serveur HTTP avec un timeout.
-		log.Println("Arrêt en cours... Donnez un peu de temps aux workers pour finir.")
-		time.Sleep(5 * time.Second)
+		// Toutes les étapes de l'arrêt partagent une même échéance : l'arrêt complet ne dépasse pas
+		// shutdownTimeout. Une étape qui l'atteint est abandonnée, les suivantes ne font plus que
+		// ce qui n'attend pas (écriture du journal de débordement, fermeture de la base).
+		shutdownTimeout := time.Duration(cfg.Server.ShutdownTimeoutSeconds) * time.Second
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelShutdown()
+
+		// 1. Arrêt propre du serveur HTTP : plus de nouvelles connexions, les requêtes en cours
+		// disposent du délai restant pour se terminer.
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("http server shutdown incomplete", "error", err)
+		} else {
+			logger.Info("http server stopped")
+		}
+
+		// 2. Fermer le channel des clics : les workers traitent les événements restants puis s'arrêtent.
+		persistedBefore := clickWorkers.Persisted()
+		pending := api.CloseClickEvents()
+		logger.Info("click event channel closed", "pending_events", pending)
+
+		// 3. Arrêter le moniteur d'URLs (ticker et vérification en cours).
+		if err := urlMonitor.Stop(shutdownCtx); err != nil {
+			logger.Warn("url monitor did not stop in time", "error", err)
+		}
+
+		// Arrêter la recherche des liens expirés, puis la livraison des webhooks : les tentatives en cours
+		// sont interrompues, les livraisons non effectuées restent en base et seront tentées au prochain démarrage.
+		if err := expirySweeper.Stop(shutdownCtx); err != nil {
+			logger.Warn("expiry sweeper did not stop in time", "error", err)
+		}
+		if dispatcher != nil {
+			if err := dispatcher.Stop(shutdownCtx); err != nil {
+				logger.Warn("webhook dispatcher did not stop in time", "error", err)
+			}
+		}
+
+		// 4. Attendre que les workers aient vidé le channel.
+		if err := clickWorkers.Wait(shutdownCtx); err != nil {
+			logger.Warn("click workers did not stop in time", "error", err)
+		}
 
-		log.Println("Serveur arrêté proprement.")
+		// Arrêter la relecture du journal, y reporter les événements non traités, puis le fermer :
+		// ils seront enregistrés au prochain démarrage.
+		if replayer != nil {
+			if err := replayer.Stop(shutdownCtx); err != nil {
+				logger.Warn("journal replayer did not stop in time", "error", err)
+			}
+			if n := api.SpillPendingClickEvents(); n > 0 {
+				logger.Info("unprocessed click events written to the spill journal", "events", n)
+			}
+			if err := api.ClickJournal.Close(); err != nil {
+				logger.Warn("failed to close click spill journal", "error", err)
+			}
+		}
+
+		// Les événements encore présents dans le channel à ce stade sont perdus.
+		lost := uint64(len(api.ClickEventsChannel))
+		logger.Info("click events summary",
+			"flushed_on_shutdown", clickWorkers.Persisted()-persistedBefore,
+			"failed", clickWorkers.Failed(),
+			"spilled", api.SpilledClickEvents(),
+			"dropped", api.DroppedClickEvents()+lost,
+			"dropped_full_or_closed", api.DroppedClickEvents(),
+			"dropped_unprocessed", lost)
+
+		// 5. Fermer la connexion à la base de données.
+		if sqlDB, err := db.DB(); err != nil {
+			logger.Warn("failed to get database connection", "error", err)
+		} else if err := sqlDB.Close(); err != nil {
+			logger.Warn("failed to close database", "error", err)
+		}
+
+		logger.Info("server stopped gracefully")
 	},
 }
 
+// rateLimitPolicy convertit une politique de limitation de la configuration.
+func rateLimitPolicy(policy config.RateLimitPolicy) ratelimit.Policy {
+	return ratelimit.Policy{RequestsPerMinute: policy.RequestsPerMinute, Burst: policy.Burst}
+}
+
+// fatal journalise une erreur fatale puis termine le processus.
+func fatal(logger *slog.Logger, msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
+
 func init() {
 	// Ajouter la commande
 	cmd2.RootCmd.AddCommand(RunServerCmd)
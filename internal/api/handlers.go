@@ -3,34 +3,184 @@ package api
 import (
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/journal"
+	"github.com/axellelanca/urlshortener/internal/metrics"
 	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
 	"github.com/axellelanca/urlshortener/internal/services"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm" // Pour gérer gorm.ErrRecordNotFound
 )
 
+// dailyUniqueVisitorsDays est le nombre de jours détaillés dans la réponse des statistiques d'un lien.
+const dailyUniqueVisitorsDays = 7
+
 // ClickEventsChannel est le channel global (ou injecté) utilisé pour envoyer les événements de clic
 // aux workers asynchrones. Il est bufferisé pour ne pas bloquer les requêtes de redirection.
+// Il doit être fermé via CloseClickEvents, jamais directement.
 var ClickEventsChannel chan models.ClickEvent
 
+// ClickJournal est le journal de débordement optionnel (nil si désactivé) : lorsque
+// ClickEventsChannel est plein ou fermé, les événements y sont écrits au lieu d'être abandonnés.
+var ClickJournal *journal.Journal
+
+var (
+	// clickEventsMu protège la fermeture de ClickEventsChannel : les envois se font sous verrou
+	// en lecture, la fermeture sous verrou en écriture, ce qui évite d'écrire dans un channel fermé.
+	clickEventsMu     sync.RWMutex
+	clickEventsClosed bool
+	// droppedClickEvents compte les événements abandonnés (channel plein ou déjà fermé, sans journal).
+	droppedClickEvents atomic.Uint64
+	// spilledClickEvents compte les événements écrits dans le journal de débordement.
+	spilledClickEvents atomic.Uint64
+)
+
+// errNoSpillJournal indique qu'un événement de clic a été abandonné faute de journal de débordement.
+var errNoSpillJournal = errors.New("click event channel is full or closed and no spill journal is configured")
+
+// enqueueClickEvent envoie un événement de clic aux workers sans jamais bloquer.
+// Si le channel est plein ou fermé, l'événement est écrit dans ClickJournal lorsqu'il est configuré.
+// Retourne une erreur si l'événement a été abandonné.
+func enqueueClickEvent(event models.ClickEvent) error {
+	if sendClickEvent(event) {
+		return nil
+	}
+	// L'écriture dans le journal se fait hors de clickEventsMu : une écriture disque lente
+	// ne doit pas retarder CloseClickEvents, qui attend la libération des verrous en lecture.
+	return spillClickEvent(event)
+}
+
+// sendClickEvent envoie l'événement dans ClickEventsChannel s'il est ouvert et n'est pas plein.
+// Retourne false si l'événement n'a pas pu être envoyé.
+func sendClickEvent(event models.ClickEvent) bool {
+	clickEventsMu.RLock()
+	defer clickEventsMu.RUnlock()
+
+	if clickEventsClosed {
+		return false
+	}
+	// Utilise un `select` avec un `default` pour éviter de bloquer si le channel est plein.
+	select {
+	case ClickEventsChannel <- event:
+		metrics.ClickEventsEnqueuedTotal.Inc()
+		return true
+	default:
+		return false
+	}
+}
+
+// spillClickEvent écrit un événement dans le journal de débordement, ou le compte comme abandonné.
+func spillClickEvent(event models.ClickEvent) error {
+	if ClickJournal == nil {
+		droppedClickEvents.Add(1)
+		metrics.ClickEventsDroppedTotal.Inc()
+		return errNoSpillJournal
+	}
+	if err := ClickJournal.Append(event); err != nil {
+		droppedClickEvents.Add(1)
+		metrics.ClickEventsDroppedTotal.Inc()
+		return fmt.Errorf("failed to write click event to the spill journal: %w", err)
+	}
+	spilledClickEvents.Add(1)
+	metrics.ClickEventsSpilledTotal.Inc()
+	return nil
+}
+
+// CloseClickEvents ferme ClickEventsChannel pour signaler aux workers qu'aucun nouvel événement
+// n'arrivera. Les événements des redirections ultérieures sont écrits dans le journal s'il est
+// configuré, abandonnés sinon.
+// Retourne le nombre d'événements encore en attente dans le channel au moment de la fermeture.
+func CloseClickEvents() int {
+	clickEventsMu.Lock()
+	defer clickEventsMu.Unlock()
+
+	if clickEventsClosed {
+		return len(ClickEventsChannel)
+	}
+	clickEventsClosed = true
+	close(ClickEventsChannel)
+	return len(ClickEventsChannel)
+}
+
+// SpillPendingClickEvents vide dans le journal de débordement les événements restés dans
+// ClickEventsChannel après sa fermeture (workers trop lents lors de l'arrêt).
+// Retourne le nombre d'événements écrits dans le journal ; sans journal, ne fait rien.
+func SpillPendingClickEvents() int {
+	if ClickJournal == nil {
+		return 0
+	}
+	spilled := 0
+	for {
+		select {
+		case event, ok := <-ClickEventsChannel:
+			if !ok {
+				return spilled
+			}
+			if spillClickEvent(event) == nil {
+				spilled++
+			}
+		default:
+			return spilled
+		}
+	}
+}
+
+// DroppedClickEvents retourne le nombre d'événements de clic abandonnés depuis le démarrage.
+func DroppedClickEvents() uint64 {
+	return droppedClickEvents.Load()
+}
+
+// SpilledClickEvents retourne le nombre d'événements de clic écrits dans le journal de débordement.
+func SpilledClickEvents() uint64 {
+	return spilledClickEvents.Load()
+}
+
 // SetupRoutes configure toutes les routes de l'API Gin et injecte les dépendances nécessaires
 // Le channel ClickEventsChannel doit être initialisé avant l'appel à SetupRoutes (dans server.go)
-func SetupRoutes(router *gin.Engine, linkService *services.LinkService, baseURL string) {
+// 'limiters' peut être nil : le débit des clients n'est alors pas limité.
+func SetupRoutes(router *gin.Engine, linkService *services.LinkService, clickService *services.ClickService, healthService *services.HealthService, apiKeyService *services.APIKeyService, classifier *botfilter.Classifier, limiters *RateLimiters, baseURL string) {
 	// Route de Health Check , /health
 	router.GET("/health", HealthCheckHandler)
 
+	// Métriques Prometheus, /metrics
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+
 	// Routes de l'API
-	// Doivent être au format /api/v1/
-	apiV1 := router.Group("/api/v1")
-	apiV1.POST("/links", CreateShortLinkHandler(linkService, baseURL))
-	apiV1.GET("/links/:shortCode/stats", GetLinkStatsHandler(linkService))
+	// Doivent être au format /api/v1/ et sont toutes authentifiées par clé d'API.
+	// Le débit est d'abord limité par adresse IP, avant l'authentification : les tentatives avec
+	// une clé invalide sont aussi limitées. Il l'est ensuite par clé d'API, après l'authentification.
+	apiV1 := router.Group("/api/v1", limiters.middleware(PolicyAPI), APIKeyAuthMiddleware(apiKeyService))
+	apiV1.POST("/links", limiters.middleware(PolicyCreate), CreateShortLinkHandler(linkService, baseURL))
+
+	statsRoutes := apiV1.Group("", limiters.middleware(PolicyStats))
+	statsRoutes.GET("/links", ListLinksHandler(linkService, baseURL))
+
+	// Routes portant sur un lien précis : le lien doit appartenir à la clé d'API authentifiée.
+	linkRoutes := statsRoutes.Group("/links/:shortCode", LinkOwnershipMiddleware(linkService))
+	linkRoutes.GET("", GetLinkHandler(baseURL))
+	linkRoutes.PATCH("", UpdateLinkHandler(linkService, baseURL))
+	linkRoutes.DELETE("", DeleteLinkHandler(linkService))
+	linkRoutes.GET("/stats", GetLinkStatsHandler(linkService, clickService))
+	linkRoutes.GET("/clicks/timeseries", GetClickTimeSeriesHandler(clickService))
+	linkRoutes.GET("/referrers", GetTopReferrersHandler(clickService))
+	linkRoutes.GET("/browsers", GetClickBreakdownHandler(clickService, repository.DimensionBrowser, "browsers"))
+	linkRoutes.GET("/os", GetClickBreakdownHandler(clickService, repository.DimensionOS, "os"))
+	linkRoutes.GET("/devices", GetClickBreakdownHandler(clickService, repository.DimensionDeviceType, "devices"))
+	linkRoutes.GET("/health", GetLinkHealthHandler(healthService))
 
-	// Route de Redirection (au niveau racine pour les short codes)
-	router.GET("/:shortCode", RedirectHandler(linkService))
+	// Route de Redirection (au niveau racine pour les short codes), publique.
+	// HEAD est accepté car les vérificateurs de liens l'utilisent : ces clics sont comptés comme robots.
+	// Le débit est limité par adresse IP, avant la recherche du lien et l'envoi de l'événement de clic.
+	redirectLimit := limiters.middleware(PolicyRedirect)
+	router.GET("/:shortCode", redirectLimit, RedirectHandler(linkService, classifier))
+	router.HEAD("/:shortCode", redirectLimit, RedirectHandler(linkService, classifier))
 }
 
 // HealthCheckHandler gère la route /health pour vérifier l'état du service.
@@ -40,7 +190,10 @@ func HealthCheckHandler(c *gin.Context) {
 
 // CreateLinkRequest représente le corps de la requête JSON pour la création d'un lien.
 type CreateLinkRequest struct {
-	LongURL string `json:"long_url" binding:"required,url"` // 'binding:required' pour validation, 'url' pour format URL
+	LongURL     string     `json:"long_url" binding:"required,url"` // 'binding:required' pour validation, 'url' pour format URL
+	CustomAlias string     `json:"custom_alias"`                    // Alias optionnel, validé par le LinkService
+	ExpiresAt   *time.Time `json:"expires_at"`                      // Date d'expiration optionnelle (RFC 3339)
+	MaxClicks   int        `json:"max_clicks" binding:"min=0"`      // Budget de clics optionnel (0 = illimité)
 }
 
 // CreateShortLinkHandler gère la création d'une URL courte.
@@ -55,91 +208,479 @@ func CreateShortLinkHandler(linkService *services.LinkService, baseURL string) g
 		}
 
 		// Appeler le LinkService (CreateLink) pour créer le nouveau lien.
-		link, err := linkService.CreateLink(req.LongURL)
+		link, err := linkService.CreateLink(req.LongURL, services.CreateLinkOptions{
+			CustomAlias: req.CustomAlias,
+			ExpiresAt:   req.ExpiresAt,
+			MaxClicks:   req.MaxClicks,
+			OwnerID:     &currentAPIKey(c).ID,
+		})
 		if err != nil {
-			log.Printf("Error creating link: %v", err)
+			// Un alias ou une expiration mal formés sont des erreurs du client, un alias déjà pris est un conflit.
+			if errors.Is(err, services.ErrInvalidAlias) || errors.Is(err, services.ErrInvalidExpiration) ||
+				errors.Is(err, services.ErrForbiddenDestination) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, services.ErrAliasTaken) {
+				c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+				return
+			}
+			requestLogger(c).Error("failed to create link", "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create link"})
 			return
 		}
 
 		// Retourne le code court et l'URL longue dans la réponse JSON.
-		fullShortURL := fmt.Sprintf("%s/%s", baseURL, link.ShortCode)
-		c.JSON(http.StatusCreated, gin.H{
-			"short_code":     link.ShortCode,
-			"long_url":       link.LongURL,
-			"full_short_url": fullShortURL,
+		metrics.LinksCreatedTotal.Inc()
+		c.JSON(http.StatusCreated, linkResponse(link, baseURL))
+	}
+}
+
+// linkResponse construit la représentation JSON d'un lien commune à tous les endpoints.
+func linkResponse(link *models.Link, baseURL string) gin.H {
+	return gin.H{
+		"short_code":     link.ShortCode,
+		"long_url":       link.LongURL,
+		"full_short_url": fmt.Sprintf("%s/%s", baseURL, link.ShortCode),
+		"created_at":     link.CreatedAt,
+		"expires_at":     link.ExpiresAt,
+		"max_clicks":     link.MaxClicks,
+	}
+}
+
+// parseTimeParam interprète un paramètre de requête temporel, au format RFC 3339
+// ou sous forme de date simple (AAAA-MM-JJ, interprétée à minuit UTC).
+func parseTimeParam(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// ListLinksHandler gère la liste paginée des liens, filtrable par date de création
+// (created_after, created_before) et par sous-chaîne de l'URL longue (url_contains).
+func ListLinksHandler(linkService *services.LinkService, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Une clé d'API ne liste que ses propres liens.
+		filter := repository.LinkFilter{OwnerID: &currentAPIKey(c).ID}
+		for param, target := range map[string]**time.Time{
+			"created_after":  &filter.CreatedAfter,
+			"created_before": &filter.CreatedBefore,
+		} {
+			if value := c.Query(param); value != "" {
+				t, err := parseTimeParam(value)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid %s: expected RFC 3339 or YYYY-MM-DD", param)})
+					return
+				}
+				*target = &t
+			}
+		}
+		filter.URLContains = c.Query("url_contains")
+
+		page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page: expected a positive integer"})
+			return
+		}
+		pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(services.DefaultPageSize)))
+		if err != nil || pageSize < 1 || pageSize > services.MaxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid page_size: expected an integer between 1 and %d", services.MaxPageSize)})
+			return
+		}
+
+		result, err := linkService.ListLinks(filter, page, pageSize)
+		if err != nil {
+			requestLogger(c).Error("failed to list links", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		links := make([]gin.H, 0, len(result.Links))
+		for i := range result.Links {
+			links = append(links, linkResponse(&result.Links[i], baseURL))
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"links":     links,
+			"page":      result.Page,
+			"page_size": result.PageSize,
+			"total":     result.Total,
 		})
 	}
 }
 
+// GetLinkHandler gère la récupération d'un lien par son code court.
+func GetLinkHandler(baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, linkResponse(currentLink(c), baseURL))
+	}
+}
+
+// UpdateLinkRequest représente le corps de la requête JSON pour la modification d'un lien.
+type UpdateLinkRequest struct {
+	LongURL string `json:"long_url" binding:"required,url"` // Nouvelle URL de destination
+}
+
+// UpdateLinkHandler gère la modification de l'URL de destination d'un lien existant.
+func UpdateLinkHandler(linkService *services.LinkService, baseURL string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		var req UpdateLinkRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		if err := linkService.UpdateLinkTarget(link, req.LongURL); err != nil {
+			if errors.Is(err, services.ErrForbiddenDestination) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			requestLogger(c).Error("failed to update link", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, linkResponse(link, baseURL))
+	}
+}
+
+// DeleteLinkHandler gère la suppression d'un lien et de ses clics.
+func DeleteLinkHandler(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		if err := linkService.DeleteLink(link); err != nil {
+			requestLogger(c).Error("failed to delete link", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
 // RedirectHandler gère la redirection d'une URL courte vers l'URL longue et l'enregistrement asynchrone des clics.
-func RedirectHandler(linkService *services.LinkService) gin.HandlerFunc {
+// La requête est classée par 'classifier' avant la recherche du lien : seules les redirections
+// humaines consomment le budget de clics.
+func RedirectHandler(linkService *services.LinkService, classifier *botfilter.Classifier) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Mesurer la latence et le résultat de chaque redirection.
+		start := time.Now()
+		result := metrics.RedirectError
+		defer func() {
+			metrics.RedirectsTotal.WithLabelValues(result).Inc()
+			metrics.RedirectDuration.WithLabelValues(result).Observe(time.Since(start).Seconds())
+		}()
+
 		// Récupère le shortCode de l'URL avec c.Param
 		shortCode := c.Param("shortCode")
 
-		// Récupérer l'URL longue associée au shortCode depuis le linkService (GetLinkByShortCode)
-		link, err := linkService.GetLinkByShortCode(shortCode)
+		// Créer un ClickEvent avec les informations pertinentes ; LinkID est renseigné une fois le lien trouvé.
+		clickEvent := models.ClickEvent{
+			Timestamp: time.Now(),
+			UserAgent: c.Request.UserAgent(),
+			IP:        c.ClientIP(),
+			Referrer:  c.Request.Referer(),
+			Method:    c.Request.Method,
+			Purpose:   botfilter.PurposeHeader(c.Request.Header),
+		}
+
+		// Récupérer l'URL longue associée au shortCode depuis le linkService (ResolveLink),
+		// qui vérifie également que le lien n'a pas expiré et décompte le clic de son budget.
+		link, err := linkService.ResolveLink(shortCode, !classifier.IsBot(clickEvent))
 
 		if err != nil {
+			// Un lien expiré (date dépassée ou budget de clics consommé) n'est plus redirigé.
+			if errors.Is(err, services.ErrLinkExpired) {
+				result = metrics.RedirectExpired
+				c.JSON(http.StatusGone, gin.H{"error": "Link has expired"})
+				return
+			}
 			// Si le lien n'est pas trouvé, retourner HTTP 404 Not Found.
 			// Utiliser errors.Is et l'erreur Gorm
 			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result = metrics.RedirectNotFound
 				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
 				return
 			}
 			// Gérer d'autres erreurs potentielles de la base de données ou du service
-			log.Printf("Error retrieving link for %s: %v", shortCode, err)
+			requestLogger(c).Error("failed to resolve link", "short_code", shortCode, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			return
 		}
+		clickEvent.LinkID = link.ID
 
-		// Créer un ClickEvent avec les informations pertinentes.
-		clickEvent := models.ClickEvent{
-			LinkID:    link.ID,
-			Timestamp: time.Now(),
-			UserAgent: c.Request.UserAgent(),
-			IP:        c.ClientIP(),
-		}
-
-		// Envoyer le ClickEvent dans le ClickEventsChannel avec le Multiplexage.
-		// Utilise un `select` avec un `default` pour éviter de bloquer si le channel est plein.
-		select {
-		case ClickEventsChannel <- clickEvent:
-		default:
-			log.Printf("Warning: ClickEventsChannel is full, dropping click event for %s.", shortCode)
+		// Envoyer le ClickEvent dans le ClickEventsChannel sans bloquer la redirection.
+		if err := enqueueClickEvent(clickEvent); err != nil {
+			requestLogger(c).Warn("dropping click event", "short_code", shortCode, "error", err)
 		}
 
 		// Effectuer la redirection HTTP 302 (StatusFound) vers l'URL longue.
+		result = metrics.RedirectFound
 		c.Redirect(http.StatusFound, link.LongURL)
 	}
 }
 
 // GetLinkStatsHandler gère la récupération des statistiques pour un lien spécifique.
-func GetLinkStatsHandler(linkService *services.LinkService) gin.HandlerFunc {
+// Les clics humains sont comptés dans total_clicks, les clics de robots à part dans bot_clicks.
+// unique_visitors compte les visiteurs humains distincts, détaillés jour par jour dans daily_unique_visitors.
+func GetLinkStatsHandler(linkService *services.LinkService, clickService *services.ClickService) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Récupère le shortCode de l'URL avec c.Param
-		shortCode := c.Param("shortCode")
+		// Le lien a déjà été chargé et son propriétaire vérifié par LinkOwnershipMiddleware.
+		link := currentLink(c)
 
-		// Appeler le LinkService pour obtenir le lien et le nombre total de clics.
-		link, totalClicks, err := linkService.GetLinkStats(shortCode)
+		// Appeler le LinkService pour obtenir le nombre total de clics humains.
+		totalClicks, err := linkService.CountClicks(link)
 		if err != nil {
-			// Gérer le cas où le lien n'est pas trouvé.
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+			requestLogger(c).Error("failed to retrieve link stats", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		botClicks, err := clickService.GetBotClicksCount(link.ID)
+		if err != nil {
+			requestLogger(c).Error("failed to retrieve link stats", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		uniqueVisitors, err := clickService.GetUniqueVisitorsCount(link.ID)
+		if err != nil {
+			requestLogger(c).Error("failed to retrieve link stats", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		dailyUniques, err := clickService.GetDailyUniqueVisitors(link.ID, dailyUniqueVisitorsDays, time.Now())
+		if err != nil {
+			requestLogger(c).Error("failed to retrieve link stats", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		// Retourne les statistiques et la durée de vie restante dans la réponse JSON.
+		lifetime := services.ComputeLifetime(link, time.Now())
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":            link.ShortCode,
+			"long_url":              link.LongURL,
+			"total_clicks":          totalClicks,
+			"bot_clicks":            botClicks,
+			"unique_visitors":       uniqueVisitors,
+			"daily_unique_visitors": dailyUniques,
+			"expires_at":            lifetime.ExpiresAt,
+			"expires_in_seconds":    lifetime.ExpiresInSeconds,
+			"max_clicks":            lifetime.MaxClicks,
+			"remaining_clicks":      lifetime.RemainingClicks,
+			"expired":               lifetime.Expired,
+		})
+	}
+}
+
+// GetClickTimeSeriesHandler gère la récupération du nombre de clics d'un lien par intervalle de temps.
+// Paramètres : granularity (hour, day ou week, day par défaut), from et to (RFC 3339 ou AAAA-MM-JJ).
+// Par défaut, 'to' vaut maintenant et 'from' dépend de la granularité (cf. Granularity.DefaultRange).
+// Comme pour tous les endpoints d'analytics, include_bots=true inclut les clics de robots.
+func GetClickTimeSeriesHandler(clickService *services.ClickService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		includeBots, ok := parseIncludeBots(c)
+		if !ok {
+			return
+		}
+
+		granularity, err := services.ParseGranularity(c.DefaultQuery("granularity", string(services.GranularityDay)))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		to := time.Now()
+		if value := c.Query("to"); value != "" {
+			if to, err = parseTimeParam(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: expected RFC 3339 or YYYY-MM-DD"})
+				return
+			}
+		}
+		from := granularity.DefaultRange(to)
+		if value := c.Query("from"); value != "" {
+			if from, err = parseTimeParam(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: expected RFC 3339 or YYYY-MM-DD"})
+				return
+			}
+		}
+
+		points, err := clickService.GetClickTimeSeries(link.ID, from, to, granularity, includeBots)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTimeRange) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 				return
 			}
-			// Gérer d'autres erreurs
-			log.Printf("Error retrieving stats for %s: %v", shortCode, err)
+			requestLogger(c).Error("failed to retrieve click time series", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		total := 0
+		for _, p := range points {
+			total += p.Clicks
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":   link.ShortCode,
+			"granularity":  granularity,
+			"from":         from.UTC(),
+			"to":           to.UTC(),
+			"total_clicks": total,
+			"buckets":      points,
+		})
+	}
+}
+
+// GetTopReferrersHandler gère la récupération des hôtes référents ayant généré le plus de clics.
+// Paramètre : limit (nombre d'hôtes retournés, 10 par défaut, 100 au maximum).
+func GetTopReferrersHandler(clickService *services.ClickService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		limit, ok := parseTopLimit(c)
+		if !ok {
+			return
+		}
+		includeBots, ok := parseIncludeBots(c)
+		if !ok {
+			return
+		}
+
+		referrers, err := clickService.GetTopReferrers(link.ID, limit, includeBots)
+		if err != nil {
+			requestLogger(c).Error("failed to retrieve referrers", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"short_code": link.ShortCode,
+			"referrers":  referrers,
+		})
+	}
+}
+
+// GetClickBreakdownHandler construit un handler retournant la répartition des clics d'un lien
+// selon une dimension (navigateur, système ou type d'appareil). 'key' est le nom du champ
+// contenant la répartition dans la réponse JSON. Paramètre : limit (cf. parseTopLimit).
+func GetClickBreakdownHandler(clickService *services.ClickService, dimension repository.ClickDimension, key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		limit, ok := parseTopLimit(c)
+		if !ok {
+			return
+		}
+		includeBots, ok := parseIncludeBots(c)
+		if !ok {
+			return
+		}
+
+		entries, total, err := clickService.GetClickBreakdown(link.ID, dimension, limit, includeBots)
+		if err != nil {
+			requestLogger(c).Error("failed to retrieve click breakdown", "dimension", key, "short_code", link.ShortCode, "error", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
 			return
 		}
 
-		// Retourne les statistiques dans la réponse JSON.
 		c.JSON(http.StatusOK, gin.H{
 			"short_code":   link.ShortCode,
-			"long_url":     link.LongURL,
-			"total_clicks": totalClicks,
+			"total_clicks": total,
+			key:            entries,
 		})
 	}
 }
+
+// GetLinkHealthHandler retourne l'état de santé d'un lien tel que vu par le moniteur, son taux de
+// disponibilité sur [from, to) (24 dernières heures par défaut) et ses 'limit' dernières vérifications.
+func GetLinkHealthHandler(healthService *services.HealthService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		link := currentLink(c)
+
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultHealthChecks)))
+		if err != nil || limit < 1 || limit > services.MaxHealthChecks {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit: expected an integer between 1 and %d", services.MaxHealthChecks)})
+			return
+		}
+
+		to := time.Now()
+		if value := c.Query("to"); value != "" {
+			if to, err = parseTimeParam(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to: expected RFC 3339 or YYYY-MM-DD"})
+				return
+			}
+		}
+		from := to.Add(-services.DefaultHealthWindow)
+		if value := c.Query("from"); value != "" {
+			if from, err = parseTimeParam(value); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from: expected RFC 3339 or YYYY-MM-DD"})
+				return
+			}
+		}
+
+		health, err := healthService.GetLinkHealth(link, from, to, limit)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidTimeRange) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			requestLogger(c).Error("failed to retrieve link health", "short_code", link.ShortCode, "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		checks := make([]gin.H, 0, len(health.RecentChecks))
+		for _, check := range health.RecentChecks {
+			checks = append(checks, gin.H{
+				"checked_at":  check.CheckedAt,
+				"healthy":     check.Healthy,
+				"status_code": check.StatusCode,
+				"latency_ms":  check.LatencyMs,
+				"error_class": check.ErrorClass,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"short_code":      link.ShortCode,
+			"long_url":        link.LongURL,
+			"status":          health.Status,
+			"status_since":    health.StatusSince,
+			"last_checked_at": health.LastCheckedAt,
+			"from":            health.From,
+			"to":              health.To,
+			"checks_count":    health.Checks,
+			"healthy_checks":  health.HealthyChecks,
+			"uptime_percent":  health.UptimePercent,
+			"checks":          checks,
+		})
+	}
+}
+
+// parseTopLimit lit le paramètre 'limit' des classements (10 par défaut, 100 au maximum).
+// En cas de valeur invalide, une réponse 400 est écrite et 'ok' vaut false.
+func parseTopLimit(c *gin.Context) (limit int, ok bool) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(services.DefaultTopLimit)))
+	if err != nil || limit < 1 || limit > services.MaxTopLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid limit: expected an integer between 1 and %d", services.MaxTopLimit)})
+		return 0, false
+	}
+	return limit, true
+}
+
+// parseIncludeBots lit le paramètre 'include_bots' des endpoints d'analytics (false par défaut).
+// En cas de valeur invalide, une réponse 400 est écrite et 'ok' vaut false.
+func parseIncludeBots(c *gin.Context) (includeBots bool, ok bool) {
+	includeBots, err := strconv.ParseBool(c.DefaultQuery("include_bots", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid include_bots: expected true or false"})
+		return false, false
+	}
+	return includeBots, true
+}
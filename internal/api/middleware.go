@@ -0,0 +1,180 @@
+package api
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Clés utilisées pour stocker les valeurs partagées entre middlewares et handlers dans le gin.Context.
+const (
+	contextKeyAPIKey = "apiKey"
+	contextKeyLink   = "link"
+	contextKeyLogger = "logger"
+)
+
+// RequestIDHeader est l'en-tête portant l'identifiant de requête, repris de la requête
+// entrante s'il est valide, généré sinon, et renvoyé dans la réponse.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength limite la taille d'un identifiant de requête fourni par le client.
+const maxRequestIDLength = 64
+
+// AccessLogMiddleware attribue un identifiant à chaque requête, stocke dans le contexte un logger
+// enrichi de cet identifiant pour les handlers, puis journalise la requête une fois traitée :
+// méthode, chemin, code court éventuel, statut, latence et IP du client.
+// Doit être le premier middleware du routeur.
+func AccessLogMiddleware(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Header(RequestIDHeader, requestID)
+		c.Set(contextKeyLogger, logger.With("request_id", requestID))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"request_id", requestID,
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency", time.Since(start),
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if shortCode := c.Param("shortCode"); shortCode != "" {
+			attrs = append(attrs, "short_code", shortCode)
+		}
+		if key, ok := c.Get(contextKeyAPIKey); ok {
+			attrs = append(attrs, "api_key_id", key.(*models.APIKey).ID)
+		}
+
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logger.Log(c.Request.Context(), level, "http request", attrs...)
+	}
+}
+
+// RecoveryMiddleware intercepte les panics des handlers, les journalise avec la pile d'appels
+// via le logger de la requête et répond par une erreur 500.
+func RecoveryMiddleware() gin.HandlerFunc {
+	return gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, recovered any) {
+		requestLogger(c).Error("panic recovered",
+			"error", fmt.Sprint(recovered), "stack", string(debug.Stack()))
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+	})
+}
+
+// requestLogger retourne le logger de la requête installé par AccessLogMiddleware,
+// ou le logger par défaut si le middleware n'est pas en place.
+func requestLogger(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get(contextKeyLogger); ok {
+		return logger.(*slog.Logger)
+	}
+	return slog.Default()
+}
+
+// validRequestID indique si un identifiant de requête fourni par le client peut être repris tel quel.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// newRequestID génère un identifiant de requête aléatoire de 16 caractères hexadécimaux.
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// APIKeyAuthMiddleware authentifie les requêtes à l'aide d'une clé d'API transmise
+// dans l'en-tête "Authorization: Bearer <clé>" ou "X-API-Key: <clé>".
+// La clé authentifiée est stockée dans le contexte pour les handlers suivants.
+func APIKeyAuthMiddleware(apiKeyService *services.APIKeyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rawKey := c.GetHeader("X-API-Key")
+		if auth := c.GetHeader("Authorization"); rawKey == "" && auth != "" {
+			scheme, token, found := strings.Cut(auth, " ")
+			if found && strings.EqualFold(scheme, "Bearer") {
+				rawKey = strings.TrimSpace(token)
+			}
+		}
+		if rawKey == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing API key"})
+			return
+		}
+
+		key, err := apiKeyService.Authenticate(rawKey)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+				return
+			}
+			requestLogger(c).Error("failed to authenticate api key", "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.Set(contextKeyAPIKey, key)
+		c.Next()
+	}
+}
+
+// LinkOwnershipMiddleware charge le lien désigné par le paramètre :shortCode et vérifie
+// qu'il appartient à la clé d'API authentifiée. Le lien est stocké dans le contexte.
+// Doit être placé après APIKeyAuthMiddleware.
+func LinkOwnershipMiddleware(linkService *services.LinkService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shortCode := c.Param("shortCode")
+
+		link, err := linkService.GetOwnedLink(shortCode, currentAPIKey(c).ID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+				return
+			}
+			requestLogger(c).Error("failed to retrieve link", "short_code", shortCode, "error", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+
+		c.Set(contextKeyLink, link)
+		c.Next()
+	}
+}
+
+// currentAPIKey retourne la clé d'API authentifiée par APIKeyAuthMiddleware.
+func currentAPIKey(c *gin.Context) *models.APIKey {
+	return c.MustGet(contextKeyAPIKey).(*models.APIKey)
+}
+
+// currentLink retourne le lien chargé par LinkOwnershipMiddleware.
+func currentLink(c *gin.Context) *models.Link {
+	return c.MustGet(contextKeyLink).(*models.Link)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/ratelimit"
+	"github.com/gin-gonic/gin"
+)
+
+// Noms des politiques de limitation de débit (label "policy" de metrics.RateLimitedTotal).
+const (
+	PolicyAPI      = "api"
+	PolicyCreate   = "create"
+	PolicyStats    = "stats"
+	PolicyRedirect = "redirect"
+)
+
+// RateLimiters regroupe les limiteurs de débit des différentes familles de routes.
+// Un RateLimiters nil désactive la limitation.
+type RateLimiters struct {
+	API      *ratelimit.Limiter // Toutes les routes /api/v1, par adresse IP, avant l'authentification
+	Create   *ratelimit.Limiter // POST /api/v1/links
+	Stats    *ratelimit.Limiter // Autres routes /api/v1 (consultation, statistiques, modification)
+	Redirect *ratelimit.Limiter // GET et HEAD /:shortCode
+}
+
+// middleware retourne le middleware de limitation de la politique 'policy', ou un middleware
+// sans effet si la limitation est désactivée.
+func (l *RateLimiters) middleware(policy string) gin.HandlerFunc {
+	if l == nil {
+		return func(c *gin.Context) { c.Next() }
+	}
+	switch policy {
+	case PolicyAPI:
+		return RateLimitMiddleware(l.API, policy)
+	case PolicyCreate:
+		return RateLimitMiddleware(l.Create, policy)
+	case PolicyStats:
+		return RateLimitMiddleware(l.Stats, policy)
+	default:
+		return RateLimitMiddleware(l.Redirect, policy)
+	}
+}
+
+// RateLimitMiddleware limite le débit de chaque client selon 'limiter'. Le client est identifié
+// par sa clé d'API lorsque la requête est authentifiée, sinon par son adresse IP (c.ClientIP,
+// qui ne tient compte des en-têtes X-Forwarded-For que pour les proxies de confiance).
+// Les en-têtes RateLimit-Limit, RateLimit-Remaining et RateLimit-Reset sont ajoutés à chaque réponse ;
+// une requête refusée reçoit un statut 429 et un en-tête Retry-After.
+func RateLimitMiddleware(limiter *ratelimit.Limiter, policy string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if value, ok := c.Get(contextKeyAPIKey); ok {
+			key = "key:" + strconv.FormatUint(uint64(value.(*models.APIKey).ID), 10)
+		}
+
+		decision := limiter.Allow(key)
+		c.Header("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+
+		if !decision.Allowed {
+			metrics.RateLimitedTotal.WithLabelValues(policy).Inc()
+			requestLogger(c).Debug("request rate limited", "policy", policy, "client", key)
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// ceilSeconds arrondit une durée à la seconde supérieure, comme attendu par Retry-After.
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
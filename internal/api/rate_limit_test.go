@@ -0,0 +1,116 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/database"
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/ratelimit"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/services"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// serve exécute une requête GET 'path' sur 'router' depuis l'adresse 'remoteAddr'.
+func serve(router http.Handler, path, remoteAddr string, header http.Header) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.RemoteAddr = remoteAddr
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRateLimitMiddlewareClientKey(t *testing.T) {
+	router := gin.New()
+	if err := router.SetTrustedProxies([]string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("SetTrustedProxies: %v", err)
+	}
+	limiter := ratelimit.NewLimiter(ratelimit.Policy{RequestsPerMinute: 1, Burst: 1})
+	router.GET("/ip", RateLimitMiddleware(limiter, PolicyRedirect), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET("/key/:id", func(c *gin.Context) {
+		id := uint(len(c.Param("id")))
+		c.Set(contextKeyAPIKey, &models.APIKey{ID: id})
+	}, RateLimitMiddleware(limiter, PolicyStats), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	forwardedFor := func(ip string) http.Header {
+		return http.Header{"X-Forwarded-For": {ip}}
+	}
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		header     http.Header
+		want       int
+	}{
+		{"direct client", "/ip", "203.0.113.5:1000", nil, http.StatusOK},
+		{"direct client again", "/ip", "203.0.113.5:1001", nil, http.StatusTooManyRequests},
+		// X-Forwarded-For d'un client qui n'est pas un proxy de confiance est ignoré.
+		{"spoofed forwarded for", "/ip", "203.0.113.5:1002", forwardedFor("198.51.100.9"), http.StatusTooManyRequests},
+		{"other direct client", "/ip", "203.0.113.6:1000", nil, http.StatusOK},
+		// Derrière un proxy de confiance, chaque client transmis a son propre seau.
+		{"proxied client", "/ip", "10.0.0.1:1000", forwardedFor("198.51.100.1"), http.StatusOK},
+		{"other proxied client", "/ip", "10.0.0.1:1001", forwardedFor("198.51.100.2"), http.StatusOK},
+		{"proxied client again", "/ip", "10.0.0.2:1000", forwardedFor("198.51.100.1"), http.StatusTooManyRequests},
+		// Une requête authentifiée est limitée par clé d'API, quelle que soit son adresse IP.
+		{"api key", "/key/a", "203.0.113.5:1003", nil, http.StatusOK},
+		{"api key again", "/key/b", "203.0.113.7:1000", nil, http.StatusTooManyRequests},
+		{"other api key", "/key/ab", "203.0.113.5:1004", nil, http.StatusOK},
+	}
+	for _, tt := range tests {
+		rec := serve(router, tt.path, tt.remoteAddr, tt.header)
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, rec.Code, tt.want)
+		}
+		if rec.Header().Get("RateLimit-Limit") != "1" {
+			t.Errorf("%s: RateLimit-Limit = %q, want 1", tt.name, rec.Header().Get("RateLimit-Limit"))
+		}
+		if tt.want == http.StatusTooManyRequests && rec.Header().Get("Retry-After") != "60" {
+			t.Errorf("%s: Retry-After = %q, want 60", tt.name, rec.Header().Get("Retry-After"))
+		}
+	}
+}
+
+func TestAPIRateLimitBeforeAuth(t *testing.T) {
+	db := dbtest.Open(t, database.DriverSQLite)
+	logger := slog.New(slog.DiscardHandler)
+	policy := ratelimit.Policy{RequestsPerMinute: 1, Burst: 2}
+	limiters := &RateLimiters{
+		API:      ratelimit.NewLimiter(policy),
+		Create:   ratelimit.NewLimiter(policy),
+		Stats:    ratelimit.NewLimiter(policy),
+		Redirect: ratelimit.NewLimiter(policy),
+	}
+	router := gin.New()
+	SetupRoutes(router,
+		services.NewLinkService(repository.NewLinkRepository(db), logger),
+		services.NewClickService(repository.NewClickRepository(db)),
+		services.NewHealthService(repository.NewLinkCheckRepository(db)),
+		services.NewAPIKeyService(repository.NewAPIKeyRepository(db), logger),
+		botfilter.NewClassifier(nil), limiters, "http://localhost:8080")
+
+	invalidKey := http.Header{"X-Api-Key": {"not-a-key"}}
+	for i, want := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+		if rec := serve(router, "/api/v1/links", "203.0.113.5:1000", invalidKey); rec.Code != want {
+			t.Errorf("request %d with an invalid key: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if rec := serve(router, "/api/v1/links", "203.0.113.6:1000", nil); rec.Code != http.StatusUnauthorized {
+		t.Errorf("request from another IP: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,117 @@
+package botfilter
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+// DefaultSignatures liste les fragments de User-Agent (en minuscules) des robots connus :
+// moteurs de recherche, aperçus de liens des messageries et réseaux sociaux,
+// outils de supervision et clients HTTP programmatiques.
+var DefaultSignatures = []string{
+	// Marqueurs génériques
+	"bot", "crawl", "spider", "slurp", "scrapy", "preview", "fetcher", "headlesschrome", "phantomjs",
+	// Aperçus de liens (unfurlers)
+	"facebookexternalhit", "facebookcatalog", "slack-imgproxy", "slackbot", "twitterbot", "linkedinbot",
+	"discordbot", "telegrambot", "whatsapp", "skypeuripreview", "embedly", "redditbot",
+	"vkshare", "iframely", "outbrain", "quora link preview", "bitlybot", "mastodon",
+	// Moteurs de recherche
+	"googlebot", "bingbot", "yandex", "baiduspider", "duckduckbot", "applebot", "petalbot", "semrush", "ahrefs",
+	// Supervision et tests de performance
+	"pingdom", "uptimerobot", "statuscake", "site24x7", "newrelicpinger", "datadog", "lighthouse",
+	"gtmetrix", "checkly", "better uptime",
+	// Clients HTTP programmatiques
+	"curl/", "wget/", "python-requests", "python-urllib", "aiohttp", "go-http-client", "java/", "okhttp",
+	"axios/", "node-fetch", "undici", "httpclient", "libwww-perl", "postmanruntime", "insomnia",
+}
+
+// prefetchHeaders sont les en-têtes par lesquels les navigateurs et aperçus signalent
+// une requête spéculative (préchargement, aperçu) qui ne correspond pas à un clic réel.
+var prefetchHeaders = []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"}
+
+// Classifier détermine si un événement de clic provient d'un robot, à partir d'une liste
+// de signatures de User-Agent et de comportements connus (User-Agent absent, requête HEAD,
+// préchargement). Un Classifier est immuable et peut être partagé entre goroutines.
+type Classifier struct {
+	signatures []string
+}
+
+// NewClassifier crée un Classifier utilisant les signatures par défaut complétées par 'extra'.
+func NewClassifier(extra []string) *Classifier {
+	signatures := make([]string, 0, len(DefaultSignatures)+len(extra))
+	signatures = append(signatures, DefaultSignatures...)
+	for _, s := range extra {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			signatures = append(signatures, s)
+		}
+	}
+	return &Classifier{signatures: signatures}
+}
+
+// LoadSignatures lit un fichier de signatures supplémentaires : un fragment de User-Agent
+// par ligne, les lignes vides et celles commençant par '#' étant ignorées.
+func LoadSignatures(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open bot signatures file: %w", err)
+	}
+	defer f.Close()
+
+	var signatures []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		signatures = append(signatures, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read bot signatures file: %w", err)
+	}
+	return signatures, nil
+}
+
+// IsBot indique si l'événement de clic provient vraisemblablement d'un robot.
+func (c *Classifier) IsBot(event models.ClickEvent) bool {
+	// Comportements : les navigateurs envoient toujours un User-Agent et suivent un lien avec GET.
+	if strings.TrimSpace(event.UserAgent) == "" {
+		return true
+	}
+	if event.Method == http.MethodHead {
+		return true
+	}
+	if isPrefetch(event.Purpose) {
+		return true
+	}
+
+	ua := strings.ToLower(event.UserAgent)
+	for _, signature := range c.signatures {
+		if strings.Contains(ua, signature) {
+			return true
+		}
+	}
+	return false
+}
+
+// PurposeHeader extrait d'une requête HTTP la valeur des en-têtes de préchargement,
+// à conserver dans models.ClickEvent.Purpose.
+func PurposeHeader(header http.Header) string {
+	for _, name := range prefetchHeaders {
+		if value := header.Get(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// isPrefetch indique si la valeur d'un en-tête de préchargement désigne une requête spéculative.
+func isPrefetch(purpose string) bool {
+	purpose = strings.ToLower(purpose)
+	return strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "preview") || strings.Contains(purpose, "prerender")
+}
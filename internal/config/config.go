@@ -2,47 +2,197 @@ package config
 
 import (
 	"fmt"
-	"log" // Pour logger les informations ou erreurs de chargement de config
+	"log/slog" // Pour logger les informations ou erreurs de chargement de config
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper" // La bibliothèque pour la gestion de configuration
 )
 
+// EnvPrefix est le préfixe des variables d'environnement de configuration :
+// la clé 'server.port' est surchargée par URLSHORTENER_SERVER_PORT.
+const EnvPrefix = "URLSHORTENER"
+
+// ConfigFileEnv est la variable d'environnement désignant le fichier de configuration,
+// lorsque l'option --config n'est pas fournie.
+const ConfigFileEnv = EnvPrefix + "_CONFIG"
+
+// Sources possibles de la valeur effective d'une clé, par ordre de priorité décroissante.
+const (
+	SourceFlag    = "flag"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceDefault = "default"
+)
+
+// boundFlags associe les clés de configuration aux options de ligne de commande qui les surchargent.
+var boundFlags = map[string]*pflag.Flag{}
+
+// Setting décrit la valeur effective d'une clé de configuration et son origine.
+type Setting struct {
+	Key    string
+	Value  any
+	Source string // SourceFlag, SourceEnv, SourceFile ou SourceDefault
+	Origin string // Nom de l'option ou de la variable d'environnement, chemin du fichier
+}
+
 // Config est la structure principale qui mappe l'intégralité de la configuration de l'application.
 // Les tags `mapstructure` sont utilisés par Viper pour mapper les clés du fichier de config
 // (ou des variables d'environnement) aux champs de la structure Go.
 type Config struct {
 	Server struct {
-		Port    int    `mapstructure:"port"`
-		BaseURL string `mapstructure:"base_url"`
+		Port                   int      `mapstructure:"port"`
+		BaseURL                string   `mapstructure:"base_url"`
+		ShutdownTimeoutSeconds int      `mapstructure:"shutdown_timeout_seconds"`
+		TrustedProxies         []string `mapstructure:"trusted_proxies"` // IPs ou plages CIDR dont X-Forwarded-For est pris en compte
 	} `mapstructure:"server"`
 
 	Database struct {
-		Name string `mapstructure:"name"`
+		Driver                 string `mapstructure:"driver"` // sqlite, postgres ou mysql
+		Name                   string `mapstructure:"name"`   // Fichier SQLite
+		DSN                    string `mapstructure:"dsn"`    // Chaîne de connexion PostgreSQL ou MySQL
+		MaxOpenConns           int    `mapstructure:"max_open_conns"`
+		MaxIdleConns           int    `mapstructure:"max_idle_conns"`
+		ConnMaxLifetimeSeconds int    `mapstructure:"conn_max_lifetime_seconds"`
+		ConnMaxIdleTimeSeconds int    `mapstructure:"conn_max_idle_time_seconds"`
 	} `mapstructure:"database"`
 
+	Cache struct {
+		Enabled            bool `mapstructure:"enabled"`
+		Size               int  `mapstructure:"size"`
+		TTLSeconds         int  `mapstructure:"ttl_seconds"`
+		NegativeTTLSeconds int  `mapstructure:"negative_ttl_seconds"`
+	} `mapstructure:"cache"`
+
+	RateLimit struct {
+		Enabled  bool            `mapstructure:"enabled"`
+		API      RateLimitPolicy `mapstructure:"api"`
+		Create   RateLimitPolicy `mapstructure:"create"`
+		Stats    RateLimitPolicy `mapstructure:"stats"`
+		Redirect RateLimitPolicy `mapstructure:"redirect"`
+	} `mapstructure:"rate_limit"`
+
+	NetworkGuard struct {
+		Enabled         bool     `mapstructure:"enabled"`          // Refuse les requêtes sortantes vers les adresses internes
+		AllowedNetworks []string `mapstructure:"allowed_networks"` // IPs ou plages CIDR autorisées malgré tout
+		CheckOnCreate   bool     `mapstructure:"check_on_create"`  // Refuse aussi les liens vers ces adresses à la création
+	} `mapstructure:"network_guard"`
+
 	Analytics struct {
-		BufferSize  int `mapstructure:"buffer_size"`
-		WorkerCount int `mapstructure:"worker_count"`
+		BufferSize        int    `mapstructure:"buffer_size"`
+		WorkerCount       int    `mapstructure:"worker_count"`
+		BatchSize         int    `mapstructure:"batch_size"`
+		FlushIntervalMs   int    `mapstructure:"flush_interval_ms"`
+		BotSignaturesFile string `mapstructure:"bot_signatures_file"`
+
+		Spill struct {
+			Enabled               bool   `mapstructure:"enabled"`
+			Dir                   string `mapstructure:"dir"`
+			MaxSegmentBytes       int64  `mapstructure:"max_segment_bytes"`
+			ReplayIntervalSeconds int    `mapstructure:"replay_interval_seconds"`
+		} `mapstructure:"spill"`
 	} `mapstructure:"analytics"`
 
 	Monitor struct {
-		IntervalMinutes int `mapstructure:"interval_minutes"`
+		IntervalMinutes    int `mapstructure:"interval_minutes"`
+		Concurrency        int `mapstructure:"concurrency"`          // Vérifications simultanées maximum
+		PerHostConcurrency int `mapstructure:"per_host_concurrency"` // Vérifications simultanées maximum par hôte
+		HostDelayMs        int `mapstructure:"host_delay_ms"`        // Délai entre deux vérifications d'un même hôte
+		PassTimeoutSeconds int `mapstructure:"pass_timeout_seconds"` // Durée maximale d'un passage (0 = intervalle)
+		CheckRetentionDays int `mapstructure:"check_retention_days"` // Conservation de l'historique des vérifications (0 = illimitée)
 	} `mapstructure:"monitor"`
+
+	Webhooks struct {
+		Endpoints             []WebhookEndpoint `mapstructure:"endpoints"`               // Aucun endpoint = webhooks désactivés
+		MaxAttempts           int               `mapstructure:"max_attempts"`            // Tentatives maximum par livraison
+		InitialBackoffSeconds int               `mapstructure:"initial_backoff_seconds"` // Délai avant la deuxième tentative, doublé ensuite
+		MaxBackoffSeconds     int               `mapstructure:"max_backoff_seconds"`     // Délai maximal entre deux tentatives
+		TimeoutSeconds        int               `mapstructure:"timeout_seconds"`         // Durée maximale d'une tentative
+		DeliveryRetentionDays int               `mapstructure:"delivery_retention_days"` // Conservation des livraisons terminées (0 = illimitée)
+	} `mapstructure:"webhooks"`
+
+	Log struct {
+		Level  string `mapstructure:"level"`
+		Format string `mapstructure:"format"`
+	} `mapstructure:"log"`
+}
+
+// RateLimitPolicy décrit une politique de limitation de débit par seau à jetons.
+type RateLimitPolicy struct {
+	RequestsPerMinute int `mapstructure:"requests_per_minute"` // Rythme de recharge des jetons
+	Burst             int `mapstructure:"burst"`               // Nombre maximal de requêtes en rafale
+}
+
+// WebhookEndpoint décrit un destinataire des webhooks.
+type WebhookEndpoint struct {
+	Name   string   `mapstructure:"name"`   // Nom unique, repris dans le journal des livraisons
+	URL    string   `mapstructure:"url"`    // URL http(s) recevant les événements en POST
+	Secret Secret   `mapstructure:"secret"` // Clé de la signature HMAC-SHA256 des envois
+	Events []string `mapstructure:"events"` // Événements souscrits (vide = tous)
+}
+
+// Secret est une valeur de configuration sensible, masquée lorsqu'elle est affichée ou journalisée.
+type Secret string
+
+// String retourne la valeur masquée.
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return "********"
+}
+
+// MarshalText masque la valeur dans les logs au format JSON.
+func (s Secret) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// BindFlag fait surcharger la clé de configuration 'key' par une option de ligne de commande,
+// lorsque celle-ci est fournie. Les options ont priorité sur l'environnement et le fichier.
+func BindFlag(key string, flag *pflag.Flag) error {
+	if err := viper.BindPFlag(key, flag); err != nil {
+		return fmt.Errorf("failed to bind flag --%s to %s: %w", flag.Name, key, err)
+	}
+	boundFlags[key] = flag
+	return nil
+}
+
+// EnvVarName retourne le nom de la variable d'environnement qui surcharge une clé de configuration.
+func EnvVarName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
 }
 
 // LoadConfig charge la configuration de l'application en utilisant Viper.
-// Elle recherche un fichier 'config.yaml' dans le dossier 'configs/'.
+// Le fichier lu est 'configFile' s'il est fourni (il doit alors exister), sinon celui désigné
+// par URLSHORTENER_CONFIG, sinon 'config.yaml' recherché dans le dossier 'configs/'.
+// Chaque clé peut être surchargée par une variable d'environnement URLSHORTENER_* puis par
+// une option de ligne de commande liée via BindFlag.
 // Elle définit également des valeurs par défaut si le fichier de config est absent ou incomplet.
-func LoadConfig() (*Config, error) {
-	// Spécifie le chemin où Viper doit chercher les fichiers de config.
-	// on cherche dans le dossier 'configs' relatif au répertoire d'exécution.
-	viper.AddConfigPath("configs")
+func LoadConfig(configFile string) (*Config, error) {
+	if configFile == "" {
+		configFile = os.Getenv(ConfigFileEnv)
+	}
+	if configFile != "" {
+		// Fichier explicite : son absence est une erreur, pas un retour silencieux aux valeurs par défaut.
+		viper.SetConfigFile(configFile)
+	} else {
+		// Spécifie le chemin où Viper doit chercher les fichiers de config.
+		// on cherche dans le dossier 'configs' relatif au répertoire d'exécution.
+		viper.AddConfigPath("configs")
 
-	// Spécifie le nom du fichier de config (sans l'extension).
-	viper.SetConfigName("config")
+		// Spécifie le nom du fichier de config (sans l'extension).
+		viper.SetConfigName("config")
 
-	// Spécifie le type de fichier de config.
-	viper.SetConfigType("yaml")
+		// Spécifie le type de fichier de config.
+		viper.SetConfigType("yaml")
+	}
+
+	// Variables d'environnement : URLSHORTENER_ suivi de la clé en majuscules, '.' remplacé par '_'.
+	viper.SetEnvPrefix(EnvPrefix)
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
 
 	// Définir les valeurs par défaut pour toutes les options de configuration.
 	// Ces valeurs seront utilisées si les clés correspondantes ne sont pas trouvées dans le fichier de config
@@ -50,34 +200,109 @@ func LoadConfig() (*Config, error) {
 	// server.port, server.base_url etc.
 	viper.SetDefault("server.port", 8080)
 	viper.SetDefault("server.base_url", "http://localhost:8080")
+	viper.SetDefault("server.shutdown_timeout_seconds", 15)
+	viper.SetDefault("server.trusted_proxies", []string{})
 
+	viper.SetDefault("database.driver", "sqlite")
 	viper.SetDefault("database.name", "url_shortener.db")
+	viper.SetDefault("database.dsn", "")
+	viper.SetDefault("database.max_open_conns", 0)
+	viper.SetDefault("database.max_idle_conns", 2)
+	viper.SetDefault("database.conn_max_lifetime_seconds", 0)
+	viper.SetDefault("database.conn_max_idle_time_seconds", 0)
+
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.size", 10000)
+	viper.SetDefault("cache.ttl_seconds", 60)
+	viper.SetDefault("cache.negative_ttl_seconds", 5)
+
+	viper.SetDefault("rate_limit.enabled", true)
+	viper.SetDefault("rate_limit.api.requests_per_minute", 600)
+	viper.SetDefault("rate_limit.api.burst", 120)
+	viper.SetDefault("rate_limit.create.requests_per_minute", 60)
+	viper.SetDefault("rate_limit.create.burst", 20)
+	viper.SetDefault("rate_limit.stats.requests_per_minute", 300)
+	viper.SetDefault("rate_limit.stats.burst", 60)
+	viper.SetDefault("rate_limit.redirect.requests_per_minute", 600)
+	viper.SetDefault("rate_limit.redirect.burst", 120)
+
+	viper.SetDefault("network_guard.enabled", true)
+	viper.SetDefault("network_guard.allowed_networks", []string{})
+	viper.SetDefault("network_guard.check_on_create", false)
 
 	viper.SetDefault("analytics.buffer_size", 1000)
 	viper.SetDefault("analytics.worker_count", 5)
+	viper.SetDefault("analytics.batch_size", 100)
+	viper.SetDefault("analytics.flush_interval_ms", 500)
+	viper.SetDefault("analytics.bot_signatures_file", "")
+	viper.SetDefault("analytics.spill.enabled", false)
+	viper.SetDefault("analytics.spill.dir", "data/click-journal")
+	viper.SetDefault("analytics.spill.max_segment_bytes", 4<<20)
+	viper.SetDefault("analytics.spill.replay_interval_seconds", 10)
 
 	viper.SetDefault("monitor.interval_minutes", 5)
+	viper.SetDefault("monitor.concurrency", 10)
+	viper.SetDefault("monitor.per_host_concurrency", 2)
+	viper.SetDefault("monitor.host_delay_ms", 500)
+	viper.SetDefault("monitor.pass_timeout_seconds", 0)
+	viper.SetDefault("monitor.check_retention_days", 30)
+
+	viper.SetDefault("webhooks.endpoints", []WebhookEndpoint{})
+	viper.SetDefault("webhooks.max_attempts", 8)
+	viper.SetDefault("webhooks.initial_backoff_seconds", 30)
+	viper.SetDefault("webhooks.max_backoff_seconds", 3600)
+	viper.SetDefault("webhooks.timeout_seconds", 10)
+	viper.SetDefault("webhooks.delivery_retention_days", 30)
+
+	viper.SetDefault("log.level", "info")
+	viper.SetDefault("log.format", "text")
 
 	// Lire le fichier de configuration.
+	// Toutes les clés de Config ont une valeur par défaut : c'est ce qui permet à Viper
+	// de les retrouver dans l'environnement lors du démappage.
 	if err := viper.ReadInConfig(); err != nil {
 		// Si le fichier de config n'existe pas, on reste sur les valeurs par défaut.
 		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
-			log.Printf("Aucun fichier de configuration trouvé, utilisation des valeurs par défaut : %v", err)
+			slog.Warn("no configuration file found, using defaults", "error", err)
 		} else {
 			// Erreur réelle de lecture/parsing
 			return nil, fmt.Errorf("erreur lors de la lecture du fichier de configuration: %w", err)
 		}
 	}
 
-	// 4: Démapper (unmarshal) la configuration lue (ou les valeurs par défaut) dans la structure Config.
-	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("erreur lors du mapping de la configuration: %w", err)
+	// 4: Démapper (unmarshal) la configuration lue (ou les valeurs par défaut) dans la structure Config,
+	// puis la valider.
+	cfg, err := decode()
+	if err != nil {
+		return nil, err
 	}
 
-	// Log pour vérifier la config chargée
-	log.Printf("Configuration loaded: Server Port=%d, DB Name=%s, Analytics Buffer=%d, Monitor Interval=%dmin",
-		cfg.Server.Port, cfg.Database.Name, cfg.Analytics.BufferSize, cfg.Monitor.IntervalMinutes)
+	return cfg, nil // Retourne la configuration chargée
+}
+
+// ConfigFileUsed retourne le chemin du fichier de configuration lu, ou une chaîne vide.
+func ConfigFileUsed() string {
+	return viper.ConfigFileUsed()
+}
+
+// Settings retourne la valeur effective de chaque clé de configuration, triée par clé,
+// avec sa source. Doit être appelée après LoadConfig.
+func Settings() []Setting {
+	keys := viper.AllKeys()
+	sort.Strings(keys)
 
-	return &cfg, nil // Retourne la configuration chargée
+	settings := make([]Setting, 0, len(keys))
+	for _, key := range keys {
+		setting := Setting{Key: key, Value: viper.Get(key), Source: SourceDefault}
+		envName := EnvVarName(key)
+		if flag, ok := boundFlags[key]; ok && flag.Changed {
+			setting.Source, setting.Origin = SourceFlag, "--"+flag.Name
+		} else if _, ok := os.LookupEnv(envName); ok {
+			setting.Source, setting.Origin = SourceEnv, envName
+		} else if viper.InConfig(key) {
+			setting.Source, setting.Origin = SourceFile, viper.ConfigFileUsed()
+		}
+		settings = append(settings, setting)
+	}
+	return settings
 }
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+// reloadableKeys liste les clés que le serveur sait appliquer à chaud lorsqu'elles changent
+// dans le fichier de configuration. Toute autre modification nécessite un redémarrage.
+var reloadableKeys = map[string]bool{
+	"monitor.interval_minutes": true,
+	"analytics.worker_count":   true,
+	"log.level":                true,
+
+	"rate_limit.api.requests_per_minute":      true,
+	"rate_limit.api.burst":                    true,
+	"rate_limit.create.requests_per_minute":   true,
+	"rate_limit.create.burst":                 true,
+	"rate_limit.stats.requests_per_minute":    true,
+	"rate_limit.stats.burst":                  true,
+	"rate_limit.redirect.requests_per_minute": true,
+	"rate_limit.redirect.burst":               true,
+}
+
+// Change décrit une clé de configuration dont la valeur a changé lors d'un rechargement.
+type Change struct {
+	Key string
+	Old any
+	New any
+}
+
+// Reloadable indique si la modification peut être appliquée sans redémarrage.
+func (c Change) Reloadable() bool {
+	return reloadableKeys[c.Key]
+}
+
+// ReloadableKeys retourne les clés rechargeables à chaud, triées.
+func ReloadableKeys() []string {
+	keys := make([]string, 0, len(reloadableKeys))
+	for key := range reloadableKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Diff retourne les clés dont la valeur diffère entre c et other, triées par clé.
+func (c *Config) Diff(other *Config) []Change {
+	before, after := flatten(c), flatten(other)
+
+	var changes []Change
+	for key, old := range before {
+		if value := after[key]; !reflect.DeepEqual(old, value) {
+			changes = append(changes, Change{Key: key, Old: old, New: value})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// Watch surveille le fichier de configuration lu par LoadConfig. À chaque modification,
+// le fichier est relu et validé, puis 'onChange' est appelée avec la nouvelle configuration,
+// ou avec l'erreur de lecture ou de validation (la configuration en cours reste alors inchangée).
+// Les variables d'environnement et les options de ligne de commande gardent leur priorité.
+func Watch(onChange func(*Config, error)) error {
+	if viper.ConfigFileUsed() == "" {
+		return errors.New("no configuration file to watch")
+	}
+	viper.OnConfigChange(func(event fsnotify.Event) {
+		// Un fichier vide est en général en cours de réécriture (troncature puis écriture) :
+		// l'appliquer reviendrait aux valeurs par défaut. L'écriture suivante déclenchera un nouvel appel.
+		if info, err := os.Stat(event.Name); err == nil && info.Size() == 0 {
+			return
+		}
+		// Viper a déjà relu le fichier mais n'expose pas l'erreur éventuelle : relire pour la récupérer.
+		if err := viper.ReadInConfig(); err != nil {
+			onChange(nil, fmt.Errorf("failed to read configuration file: %w", err))
+			return
+		}
+		onChange(decode())
+	})
+	viper.WatchConfig()
+	return nil
+}
+
+// decode démappe et valide la configuration courante de Viper.
+func decode() (*Config, error) {
+	var cfg Config
+	if err := viper.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("erreur lors du mapping de la configuration: %w", err)
+	}
+
+	// Refuser une configuration incohérente plutôt que de démarrer avec.
+	if err := cfg.Validate(); err != nil {
+		return nil, &ValidationError{Err: err}
+	}
+	return &cfg, nil
+}
+
+// flatten associe chaque clé de la configuration (par exemple 'server.port') à sa valeur,
+// en suivant les tags mapstructure des structures imbriquées.
+func flatten(cfg *Config) map[string]any {
+	values := make(map[string]any)
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			key := prefix + t.Field(i).Tag.Get("mapstructure")
+			if field := v.Field(i); field.Kind() == reflect.Struct {
+				walk(key+".", field)
+			} else {
+				values[key] = field.Interface()
+			}
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return values
+}
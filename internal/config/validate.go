@@ -0,0 +1,250 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/axellelanca/urlshortener/internal/database"
+	"github.com/axellelanca/urlshortener/internal/logging"
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+// Bornes des valeurs numériques de la configuration.
+const (
+	maxWorkerCount        = 1000
+	maxMonitorConcurrency = 1000
+	minSpillSegmentBytes  = 1024
+	maxShutdownTimeoutSec = 600
+	maxWebhookAttempts    = 50
+	maxWebhookTimeoutSec  = 300
+	minWebhookSecretLen   = 16
+)
+
+// webhookNamePattern décrit les caractères autorisés dans le nom d'un endpoint de webhook.
+var webhookNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// ValidationError regroupe les problèmes détectés par Validate lors du chargement de la configuration.
+type ValidationError struct {
+	Err error // Erreurs jointes par errors.Join, une par problème
+}
+
+// Error liste les problèmes, un par ligne.
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.ReplaceAll(e.Err.Error(), "\n", "\n  - ")
+}
+
+// Unwrap donne accès aux erreurs individuelles.
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate vérifie la cohérence de la configuration : plages numériques, forme des URLs
+// et existence des fichiers et dossiers référencés.
+// Tous les problèmes détectés sont regroupés dans une seule erreur (errors.Join), chacun
+// préfixé par la clé concernée et indiquant la valeur attendue.
+func (c *Config) Validate() error {
+	var errs []error
+	add := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+	}
+	atLeast := func(key string, value, min int) {
+		if value < min {
+			add(key, "must be at least %d (got %d)", min, value)
+		}
+	}
+
+	// Serveur
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		add("server.port", "must be between 1 and 65535 (got %d)", c.Server.Port)
+	}
+	if err := validateBaseURL(c.Server.BaseURL); err != nil {
+		add("server.base_url", "%v", err)
+	}
+	if c.Server.ShutdownTimeoutSeconds < 1 || c.Server.ShutdownTimeoutSeconds > maxShutdownTimeoutSec {
+		add("server.shutdown_timeout_seconds", "must be between 1 and %d (got %d)", maxShutdownTimeoutSec, c.Server.ShutdownTimeoutSeconds)
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if !validIPOrCIDR(proxy) {
+			add("server.trusted_proxies", "'%s' is neither an IP address nor a CIDR range", proxy)
+		}
+	}
+
+	// Base de données
+	switch c.Database.Driver {
+	case database.DriverSQLite:
+		if strings.TrimSpace(c.Database.Name) == "" {
+			add("database.name", "must not be empty")
+		}
+	case database.DriverPostgres, database.DriverMySQL:
+		if strings.TrimSpace(c.Database.DSN) == "" {
+			add("database.dsn", "must not be empty when database.driver is '%s'", c.Database.Driver)
+		}
+	default:
+		add("database.driver", "must be one of %s (got '%s')", strings.Join(database.Drivers, ", "), c.Database.Driver)
+	}
+	atLeast("database.max_open_conns", c.Database.MaxOpenConns, 0)
+	atLeast("database.max_idle_conns", c.Database.MaxIdleConns, 0)
+	atLeast("database.conn_max_lifetime_seconds", c.Database.ConnMaxLifetimeSeconds, 0)
+	atLeast("database.conn_max_idle_time_seconds", c.Database.ConnMaxIdleTimeSeconds, 0)
+
+	// Cache des liens
+	if c.Cache.Enabled {
+		atLeast("cache.size", c.Cache.Size, 1)
+		atLeast("cache.ttl_seconds", c.Cache.TTLSeconds, 1)
+		atLeast("cache.negative_ttl_seconds", c.Cache.NegativeTTLSeconds, 0)
+	}
+
+	// Limitation de débit
+	if c.RateLimit.Enabled {
+		policies := []struct {
+			name   string
+			policy RateLimitPolicy
+		}{{"api", c.RateLimit.API}, {"create", c.RateLimit.Create}, {"stats", c.RateLimit.Stats}, {"redirect", c.RateLimit.Redirect}}
+		for _, p := range policies {
+			atLeast("rate_limit."+p.name+".requests_per_minute", p.policy.RequestsPerMinute, 1)
+			atLeast("rate_limit."+p.name+".burst", p.policy.Burst, 1)
+		}
+	}
+
+	// Protection du réseau interne
+	for _, network := range c.NetworkGuard.AllowedNetworks {
+		if !validIPOrCIDR(network) {
+			add("network_guard.allowed_networks", "'%s' is neither an IP address nor a CIDR range", network)
+		}
+	}
+	if c.NetworkGuard.CheckOnCreate && !c.NetworkGuard.Enabled {
+		add("network_guard.check_on_create", "requires network_guard.enabled to be true")
+	}
+
+	// Analytics
+	atLeast("analytics.buffer_size", c.Analytics.BufferSize, 1)
+	if c.Analytics.WorkerCount < 1 || c.Analytics.WorkerCount > maxWorkerCount {
+		add("analytics.worker_count", "must be between 1 and %d, otherwise no click is ever persisted (got %d)", maxWorkerCount, c.Analytics.WorkerCount)
+	}
+	atLeast("analytics.batch_size", c.Analytics.BatchSize, 1)
+	atLeast("analytics.flush_interval_ms", c.Analytics.FlushIntervalMs, 1)
+	if file := c.Analytics.BotSignaturesFile; file != "" {
+		if info, err := os.Stat(file); err != nil {
+			add("analytics.bot_signatures_file", "cannot read '%s': %v", file, err)
+		} else if info.IsDir() {
+			add("analytics.bot_signatures_file", "'%s' is a directory, expected a file", file)
+		}
+	}
+	if spill := c.Analytics.Spill; spill.Enabled {
+		if strings.TrimSpace(spill.Dir) == "" {
+			add("analytics.spill.dir", "must not be empty when analytics.spill.enabled is true")
+		} else if info, err := os.Stat(spill.Dir); err == nil && !info.IsDir() {
+			add("analytics.spill.dir", "'%s' exists and is not a directory", spill.Dir)
+		}
+		if spill.MaxSegmentBytes < minSpillSegmentBytes {
+			add("analytics.spill.max_segment_bytes", "must be at least %d (got %d)", minSpillSegmentBytes, spill.MaxSegmentBytes)
+		}
+		atLeast("analytics.spill.replay_interval_seconds", spill.ReplayIntervalSeconds, 1)
+	}
+
+	// Moniteur
+	atLeast("monitor.interval_minutes", c.Monitor.IntervalMinutes, 1)
+	if c.Monitor.Concurrency < 1 || c.Monitor.Concurrency > maxMonitorConcurrency {
+		add("monitor.concurrency", "must be between 1 and %d (got %d)", maxMonitorConcurrency, c.Monitor.Concurrency)
+	}
+	atLeast("monitor.per_host_concurrency", c.Monitor.PerHostConcurrency, 1)
+	atLeast("monitor.host_delay_ms", c.Monitor.HostDelayMs, 0)
+	atLeast("monitor.pass_timeout_seconds", c.Monitor.PassTimeoutSeconds, 0)
+	atLeast("monitor.check_retention_days", c.Monitor.CheckRetentionDays, 0)
+
+	// Webhooks
+	names := make(map[string]bool)
+	for i, endpoint := range c.Webhooks.Endpoints {
+		key := fmt.Sprintf("webhooks.endpoints[%d]", i)
+		switch {
+		case !webhookNamePattern.MatchString(endpoint.Name):
+			add(key+".name", "must be 1 to 64 letters, digits, '-' or '_' (got '%s')", endpoint.Name)
+		case names[endpoint.Name]:
+			add(key+".name", "'%s' is used by another endpoint", endpoint.Name)
+		}
+		names[endpoint.Name] = true
+		if err := validateWebhookURL(endpoint.URL); err != nil {
+			add(key+".url", "%v", err)
+		}
+		if len(endpoint.Secret) < minWebhookSecretLen {
+			add(key+".secret", "must be at least %d characters long", minWebhookSecretLen)
+		}
+		for _, event := range endpoint.Events {
+			if !slices.Contains(models.WebhookEvents, event) {
+				add(key+".events", "unknown event '%s', expected one of %s", event, strings.Join(models.WebhookEvents, ", "))
+			}
+		}
+	}
+	if c.Webhooks.MaxAttempts < 1 || c.Webhooks.MaxAttempts > maxWebhookAttempts {
+		add("webhooks.max_attempts", "must be between 1 and %d (got %d)", maxWebhookAttempts, c.Webhooks.MaxAttempts)
+	}
+	atLeast("webhooks.initial_backoff_seconds", c.Webhooks.InitialBackoffSeconds, 1)
+	atLeast("webhooks.max_backoff_seconds", c.Webhooks.MaxBackoffSeconds, c.Webhooks.InitialBackoffSeconds)
+	if c.Webhooks.TimeoutSeconds < 1 || c.Webhooks.TimeoutSeconds > maxWebhookTimeoutSec {
+		add("webhooks.timeout_seconds", "must be between 1 and %d (got %d)", maxWebhookTimeoutSec, c.Webhooks.TimeoutSeconds)
+	}
+	atLeast("webhooks.delivery_retention_days", c.Webhooks.DeliveryRetentionDays, 0)
+
+	// Logs
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		add("log.level", "%v", err)
+	}
+	if err := logging.ValidateFormat(c.Log.Format); err != nil {
+		add("log.format", "%v", err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// validIPOrCIDR indique si 's' est une adresse IP ou une plage CIDR.
+func validIPOrCIDR(s string) bool {
+	if _, err := netip.ParseAddr(s); err == nil {
+		return true
+	}
+	_, err := netip.ParsePrefix(s)
+	return err == nil
+}
+
+// validateBaseURL vérifie que l'URL de base est une URL http(s) absolue, sans barre oblique
+// finale, requête ni fragment : les URLs courtes sont construites par simple concaténation.
+func validateBaseURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s': %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must start with http:// or https:// (got '%s')", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host (got '%s')", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("must not contain a query string or fragment (got '%s')", raw)
+	}
+	if strings.HasSuffix(raw, "/") {
+		return fmt.Errorf("must not end with '/', use '%s' (short URLs would otherwise contain '//')", strings.TrimRight(raw, "/"))
+	}
+	return nil
+}
+
+// validateWebhookURL vérifie que l'URL d'un endpoint de webhook est une URL http(s) absolue.
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid URL '%s': %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("must start with http:// or https:// (got '%s')", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("must include a host (got '%s')", raw)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package database
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/logging"
+	"github.com/glebarez/sqlite" // Driver SQLite pour GORM (pur Go, sans cgo)
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// Pilotes de base de données supportés (clé database.driver).
+// Ils correspondent aux noms de dialecte de GORM (gorm.Dialector.Name).
+const (
+	DriverSQLite   = "sqlite"
+	DriverPostgres = "postgres"
+	DriverMySQL    = "mysql"
+)
+
+// Drivers liste les pilotes supportés.
+var Drivers = []string{DriverSQLite, DriverPostgres, DriverMySQL}
+
+// Options décrit la base de données à ouvrir et le paramétrage de son pool de connexions.
+// Une valeur nulle pour les réglages du pool conserve le comportement par défaut de database/sql
+// (connexions ouvertes illimitées, durée de vie illimitée).
+type Options struct {
+	Driver          string        // DriverSQLite, DriverPostgres ou DriverMySQL
+	Name            string        // Fichier de la base SQLite
+	DSN             string        // Chaîne de connexion PostgreSQL ou MySQL
+	MaxOpenConns    int           // Nombre maximal de connexions ouvertes
+	MaxIdleConns    int           // Nombre maximal de connexions inactives conservées
+	ConnMaxLifetime time.Duration // Durée de vie maximale d'une connexion
+	ConnMaxIdleTime time.Duration // Durée d'inactivité maximale d'une connexion
+}
+
+// Open ouvre la base de données décrite par 'opts' avec GORM, dont les logs SQL passent par 'logger',
+// puis applique les réglages du pool de connexions.
+// C'est le seul point d'ouverture de la base : serveur et commandes CLI l'utilisent tous.
+func Open(opts Options, logger *slog.Logger) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch opts.Driver {
+	case DriverSQLite:
+		dialector = sqlite.Open(opts.Name)
+	case DriverPostgres:
+		dialector = postgres.Open(opts.DSN)
+	case DriverMySQL:
+		dsn, err := mysqlDSN(opts.DSN)
+		if err != nil {
+			return nil, err
+		}
+		dialector = mysql.Open(dsn)
+	default:
+		return nil, fmt.Errorf("unsupported database driver '%s'", opts.Driver)
+	}
+
+	// TranslateError convertit les erreurs propres à chaque pilote en erreurs GORM communes :
+	// une violation d'unicité retourne gorm.ErrDuplicatedKey quel que soit le pilote.
+	db, err := gorm.Open(dialector, &gorm.Config{Logger: logging.NewGormLogger(logger), TranslateError: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", opts.Driver, err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying %s connection pool: %w", opts.Driver, err)
+	}
+	sqlDB.SetMaxOpenConns(opts.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(opts.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+
+	return db, nil
+}
+
+// mysqlDSN complète la chaîne de connexion MySQL : les DATETIME sont lus dans des time.Time
+// (parseTime) et la session travaille en UTC, comme SQLite et PostgreSQL. Sans cela,
+// UNIX_TIMESTAMP interpréterait les horodatages dans le fuseau du serveur MySQL.
+// Le nombre de lignes affectées par un UPDATE est celui des lignes trouvées (clientFoundRows),
+// comme sous SQLite et PostgreSQL : une mise à jour qui ne change aucune valeur ne passe pas
+// pour une ligne manquante.
+func mysqlDSN(dsn string) (string, error) {
+	cfg, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", fmt.Errorf("invalid mysql dsn: %w", err)
+	}
+	cfg.ParseTime = true
+	cfg.Loc = time.UTC
+	cfg.ClientFoundRows = true
+	if cfg.Params == nil {
+		cfg.Params = map[string]string{}
+	}
+	cfg.Params["time_zone"] = "'+00:00'"
+	return cfg.FormatDSN(), nil
+}
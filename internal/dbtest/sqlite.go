@@ -0,0 +1,14 @@
+//go:build !integration
+
+package dbtest
+
+import "testing"
+
+// serverDrivers est vide sans le tag 'integration' : seul SQLite est testé.
+var serverDrivers []string
+
+// createServerDatabase n'est appelée que pour les pilotes de serverDrivers.
+func createServerDatabase(t testing.TB, driver, name string) string {
+	t.Fatalf("driver %s requires the 'integration' build tag", driver)
+	return ""
+}
@@ -0,0 +1,272 @@
+package journal
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+// Format d'un segment : une suite d'enregistrements, chacun composé d'un en-tête de 8 octets
+// (longueur de la charge utile puis CRC-32 IEEE de celle-ci, en big-endian) suivi de la charge
+// utile, un ClickEvent encodé en JSON.
+const (
+	headerSize       = 8
+	maxRecordSize    = 1 << 20 // Garde-fou contre une longueur corrompue
+	segmentPrefix    = "clicks-"
+	segmentExtension = ".seg"
+)
+
+// ErrClosed est retournée par Append lorsque le journal a été fermé.
+var ErrClosed = errors.New("journal is closed")
+
+// Journal est un journal local en ajout seul d'événements de clic, découpé en segments.
+// Les nouveaux enregistrements sont écrits dans le segment actif ; lorsqu'il dépasse la taille
+// maximale, il est scellé et un nouveau segment est ouvert. Seuls les segments scellés sont relus.
+// Journal peut être utilisé par plusieurs goroutines.
+type Journal struct {
+	dir             string
+	maxSegmentBytes int64
+
+	mu         sync.Mutex
+	active     *os.File // Segment en cours d'écriture (nil tant qu'aucun événement n'a été écrit)
+	activePath string
+	activeSize int64
+	nextSeq    uint64
+	closed     bool
+}
+
+// Open ouvre (ou crée) le journal dans le répertoire 'dir'.
+// Les segments existants, laissés par une exécution précédente, sont considérés comme scellés :
+// les nouveaux événements sont toujours écrits dans un nouveau segment.
+func Open(dir string, maxSegmentBytes int64) (*Journal, error) {
+	if maxSegmentBytes <= 0 {
+		return nil, fmt.Errorf("invalid max segment size: %d", maxSegmentBytes)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create journal directory: %w", err)
+	}
+
+	j := &Journal{dir: dir, maxSegmentBytes: maxSegmentBytes}
+	segments, err := j.listSegments()
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) > 0 {
+		last, _ := segmentSeq(segments[len(segments)-1])
+		j.nextSeq = last + 1
+	}
+	return j, nil
+}
+
+// Dir retourne le répertoire du journal.
+func (j *Journal) Dir() string {
+	return j.dir
+}
+
+// Append ajoute un événement de clic au segment actif.
+// L'écriture est confiée au système d'exploitation sans fsync : elle survit à un arrêt brutal
+// du processus, le segment étant synchronisé sur disque lorsqu'il est scellé.
+func (j *Journal) Append(event models.ClickEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode click event: %w", err)
+	}
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	copy(record[headerSize:], payload)
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return ErrClosed
+	}
+	if j.active != nil && j.activeSize+int64(len(record)) > j.maxSegmentBytes {
+		if err := j.sealLocked(); err != nil {
+			return err
+		}
+	}
+	if j.active == nil {
+		if err := j.openSegmentLocked(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.active.Write(record)
+	j.activeSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write journal record: %w", err)
+	}
+	return nil
+}
+
+// Seal scelle le segment actif, s'il existe, pour qu'il puisse être relu.
+func (j *Journal) Seal() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.sealLocked()
+}
+
+// SealedSegments retourne les chemins des segments scellés, du plus ancien au plus récent.
+func (j *Journal) SealedSegments() ([]string, error) {
+	j.mu.Lock()
+	activePath := j.activePath
+	j.mu.Unlock()
+
+	segments, err := j.listSegments()
+	if err != nil {
+		return nil, err
+	}
+	sealed := segments[:0]
+	for _, path := range segments {
+		if path != activePath {
+			sealed = append(sealed, path)
+		}
+	}
+	return sealed, nil
+}
+
+// Remove supprime un segment scellé dont les événements ont été enregistrés.
+func (j *Journal) Remove(path string) error {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal segment: %w", err)
+	}
+	return nil
+}
+
+// Close scelle le segment actif et refuse tout nouvel ajout.
+func (j *Journal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+	return j.sealLocked()
+}
+
+// ReadSegment lit tous les événements d'un segment.
+// La lecture s'arrête au premier enregistrement tronqué ou dont la somme de contrôle est invalide
+// (écriture interrompue par un arrêt brutal) : les événements valides qui le précèdent sont
+// retournés, et 'corrupted' vaut true.
+func ReadSegment(path string) (events []models.ClickEvent, corrupted bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open journal segment: %w", err)
+	}
+	defer f.Close()
+
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(f, header); err != nil {
+			if errors.Is(err, io.EOF) {
+				return events, false, nil
+			}
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return events, true, nil
+			}
+			return events, false, fmt.Errorf("failed to read journal segment: %w", err)
+		}
+
+		size := binary.BigEndian.Uint32(header[0:4])
+		checksum := binary.BigEndian.Uint32(header[4:8])
+		if size > maxRecordSize {
+			return events, true, nil
+		}
+		payload := make([]byte, size)
+		if _, err := io.ReadFull(f, payload); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return events, true, nil
+			}
+			return events, false, fmt.Errorf("failed to read journal segment: %w", err)
+		}
+		if crc32.ChecksumIEEE(payload) != checksum {
+			return events, true, nil
+		}
+
+		var event models.ClickEvent
+		if err := json.Unmarshal(payload, &event); err != nil {
+			return events, true, nil
+		}
+		events = append(events, event)
+	}
+}
+
+// openSegmentLocked crée un nouveau segment actif. Doit être appelée avec j.mu verrouillé.
+func (j *Journal) openSegmentLocked() error {
+	path := filepath.Join(j.dir, fmt.Sprintf("%s%020d%s", segmentPrefix, j.nextSeq, segmentExtension))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create journal segment: %w", err)
+	}
+	j.nextSeq++
+	j.active = f
+	j.activePath = path
+	j.activeSize = 0
+	return nil
+}
+
+// sealLocked synchronise et ferme le segment actif. Doit être appelée avec j.mu verrouillé.
+func (j *Journal) sealLocked() error {
+	if j.active == nil {
+		return nil
+	}
+	syncErr := j.active.Sync()
+	closeErr := j.active.Close()
+	j.active = nil
+	j.activePath = ""
+	j.activeSize = 0
+	if err := errors.Join(syncErr, closeErr); err != nil {
+		return fmt.Errorf("failed to seal journal segment: %w", err)
+	}
+	return nil
+}
+
+// listSegments retourne les chemins de tous les segments du répertoire, triés par numéro de séquence.
+func (j *Journal) listSegments() ([]string, error) {
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list journal segments: %w", err)
+	}
+	var segments []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if _, ok := segmentSeq(entry.Name()); ok {
+			segments = append(segments, filepath.Join(j.dir, entry.Name()))
+		}
+	}
+	sort.Slice(segments, func(a, b int) bool {
+		seqA, _ := segmentSeq(segments[a])
+		seqB, _ := segmentSeq(segments[b])
+		return seqA < seqB
+	})
+	return segments, nil
+}
+
+// segmentSeq extrait le numéro de séquence d'un nom (ou chemin) de segment.
+func segmentSeq(path string) (uint64, bool) {
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentExtension) {
+		return 0, false
+	}
+	seq, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentExtension), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	return seq, true
+}
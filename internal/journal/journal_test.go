@@ -0,0 +1,160 @@
+package journal
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+func testEvents(n int) []models.ClickEvent {
+	events := make([]models.ClickEvent, n)
+	for i := range events {
+		events[i] = models.ClickEvent{
+			LinkID:    uint(i + 1),
+			Timestamp: time.Date(2025, 1, 10, 12, 0, i, 0, time.UTC),
+			UserAgent: "Mozilla/5.0",
+			IP:        "203.0.113.7",
+			Referrer:  "https://t.co/abc",
+			Method:    "GET",
+		}
+	}
+	return events
+}
+
+// readAll relit tous les segments scellés du journal, dans l'ordre.
+func readAll(t *testing.T, j *Journal) ([]models.ClickEvent, []string) {
+	t.Helper()
+	segments, err := j.SealedSegments()
+	if err != nil {
+		t.Fatalf("SealedSegments: %v", err)
+	}
+	var events []models.ClickEvent
+	for _, path := range segments {
+		read, corrupted, err := ReadSegment(path)
+		if err != nil || corrupted {
+			t.Fatalf("ReadSegment(%s) = corrupted %v, error %v", path, corrupted, err)
+		}
+		events = append(events, read...)
+	}
+	return events, segments
+}
+
+func TestJournalRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	j, err := Open(dir, 300) // Quelques enregistrements par segment
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	want := testEvents(10)
+	for _, event := range want {
+		if err := j.Append(event); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	got, segments := readAll(t, j)
+	if len(segments) < 2 {
+		t.Errorf("%d segments written, want the journal to rotate", len(segments))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed events = %+v, want %+v", got, want)
+	}
+
+	// Un nouveau journal sur le même répertoire écrit après les segments existants.
+	reopened, err := Open(dir, 300)
+	if err != nil {
+		t.Fatalf("Open (reopen): %v", err)
+	}
+	more := testEvents(1)
+	if err := reopened.Append(more[0]); err != nil {
+		t.Fatalf("Append (reopen): %v", err)
+	}
+	if err := reopened.Close(); err != nil {
+		t.Fatalf("Close (reopen): %v", err)
+	}
+	got, _ = readAll(t, reopened)
+	if !reflect.DeepEqual(got, append(want, more...)) {
+		t.Errorf("events after reopen = %+v, want previous events followed by the new one", got)
+	}
+}
+
+func TestJournalActiveSegmentNotSealed(t *testing.T) {
+	j, err := Open(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer j.Close()
+	if err := j.Append(testEvents(1)[0]); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+
+	if segments, _ := j.SealedSegments(); len(segments) != 0 {
+		t.Fatalf("active segment listed as sealed: %v", segments)
+	}
+	if err := j.Seal(); err != nil {
+		t.Fatalf("Seal: %v", err)
+	}
+	if segments, _ := j.SealedSegments(); len(segments) != 1 {
+		t.Fatalf("%d sealed segments after Seal, want 1", len(segments))
+	}
+}
+
+func TestReadSegmentTruncatedRecord(t *testing.T) {
+	j, err := Open(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	want := testEvents(3)
+	for _, event := range want {
+		if err := j.Append(event); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	segments, err := j.SealedSegments()
+	if err != nil || len(segments) != 1 {
+		t.Fatalf("SealedSegments = %v, %v", segments, err)
+	}
+
+	// Simule une écriture interrompue : le dernier enregistrement est tronqué.
+	info, err := os.Stat(segments[0])
+	if err != nil {
+		t.Fatalf("Stat: %v", err)
+	}
+	if err := os.Truncate(segments[0], info.Size()-5); err != nil {
+		t.Fatalf("Truncate: %v", err)
+	}
+
+	got, corrupted, err := ReadSegment(segments[0])
+	if err != nil {
+		t.Fatalf("ReadSegment: %v", err)
+	}
+	if !corrupted {
+		t.Error("truncated segment not reported as corrupted")
+	}
+	if !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("events = %+v, want the first two", got)
+	}
+}
+
+func TestAppendAfterClose(t *testing.T) {
+	j, err := Open(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if err := j.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if err := j.Append(testEvents(1)[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("Append after Close = %v, want ErrClosed", err)
+	}
+}
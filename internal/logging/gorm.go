@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// slowQueryThreshold est la durée au-delà de laquelle une requête SQL est signalée comme lente.
+const slowQueryThreshold = 200 * time.Millisecond
+
+// GormLogger adapte un *slog.Logger à l'interface de logger de GORM :
+// erreurs SQL (hors enregistrement introuvable) en error, requêtes lentes en warn,
+// et toutes les requêtes en debug.
+type GormLogger struct {
+	logger *slog.Logger
+}
+
+// NewGormLogger crée un logger GORM écrivant dans 'logger'.
+func NewGormLogger(logger *slog.Logger) *GormLogger {
+	return &GormLogger{logger: logger.With("component", "gorm")}
+}
+
+// LogMode est ignorée : le niveau est celui du logger slog.
+func (l *GormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+// Info journalise un message d'information de GORM.
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	l.logger.InfoContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Warn journalise un avertissement de GORM.
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	l.logger.WarnContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Error journalise une erreur de GORM.
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	l.logger.ErrorContext(ctx, fmt.Sprintf(msg, args...))
+}
+
+// Trace journalise une requête SQL exécutée.
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		sql, rows := fc()
+		l.logger.ErrorContext(ctx, "sql query failed", "sql", sql, "rows", rows, "duration", elapsed, "error", err)
+	case elapsed > slowQueryThreshold:
+		sql, rows := fc()
+		l.logger.WarnContext(ctx, "slow sql query", "sql", sql, "rows", rows, "duration", elapsed)
+	case l.logger.Enabled(ctx, slog.LevelDebug):
+		sql, rows := fc()
+		l.logger.DebugContext(ctx, "sql query", "sql", sql, "rows", rows, "duration", elapsed)
+	}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Formats de sortie acceptés par New (clé de configuration log.format).
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// ParseLevel convertit un niveau de log de la configuration (debug, info, warn, error) en slog.Level.
+func ParseLevel(level string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return slog.LevelInfo, fmt.Errorf("invalid log level '%s': expected debug, info, warn or error", level)
+	}
+	return l, nil
+}
+
+// ValidateFormat vérifie qu'un format de sortie de la configuration est accepté par New.
+func ValidateFormat(format string) error {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case FormatText, FormatJSON, "":
+		return nil
+	default:
+		return fmt.Errorf("invalid log format '%s': expected %s or %s", format, FormatText, FormatJSON)
+	}
+}
+
+// New crée le logger structuré de l'application, écrivant sur 'w' au format texte (clé=valeur)
+// ou JSON. Le niveau minimal est lu à chaque message depuis 'level' : passer un *slog.LevelVar
+// permet de le modifier à chaud.
+func New(w io.Writer, format string, level slog.Leveler) (*slog.Logger, error) {
+	if err := ValidateFormat(format); err != nil {
+		return nil, err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.EqualFold(strings.TrimSpace(format), FormatJSON) {
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	}
+	return slog.New(slog.NewTextHandler(w, opts)), nil
+}
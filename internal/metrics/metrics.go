@@ -0,0 +1,266 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace préfixe toutes les métriques exposées par l'application.
+const namespace = "urlshortener"
+
+// Résultats possibles d'une redirection (label "result" de RedirectsTotal et RedirectDuration).
+const (
+	RedirectFound    = "found"
+	RedirectNotFound = "not_found"
+	RedirectExpired  = "expired"
+	RedirectError    = "error"
+)
+
+// Résultats possibles d'une vérification du moniteur (label "result" de MonitorChecksTotal).
+const (
+	CheckAccessible   = "accessible"
+	CheckInaccessible = "inaccessible"
+	CheckBlocked      = "blocked"   // Destination refusée par la protection du réseau interne
+	LinksUnchecked    = "unchecked" // Lien non vérifié avant la fin du passage (label de MonitorLastPassLinks)
+)
+
+// Issues possibles d'un passage du moniteur (label "outcome" de MonitorPassesTotal).
+const (
+	PassCompleted        = "completed"
+	PassDeadlineExceeded = "deadline_exceeded"
+	PassInterrupted      = "interrupted"
+	PassSkipped          = "skipped" // Déclenchement abandonné, le passage précédent étant en cours
+)
+
+// Résultats possibles d'une tentative de livraison de webhook (label "result" de WebhookAttemptsTotal).
+const (
+	WebhookSucceeded = "succeeded"
+	WebhookFailed    = "failed"
+)
+
+// Résultats possibles d'une recherche dans le cache des liens (label "result" de LinkCacheLookupsTotal).
+const (
+	CacheHit         = "hit"
+	CacheNegativeHit = "negative_hit"
+	CacheMiss        = "miss"
+)
+
+// registry regroupe les métriques de l'application ainsi que celles du runtime Go et du processus.
+var registry = prometheus.NewRegistry()
+
+var (
+	// RedirectDuration mesure la latence de traitement des redirections, par résultat.
+	RedirectDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "redirect_duration_seconds",
+		Help:      "Latency of short link redirects, by result.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"result"})
+
+	// RedirectsTotal compte les redirections, par résultat.
+	RedirectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "redirects_total",
+		Help:      "Short link redirects, by result (found, not_found, expired, error).",
+	}, []string{"result"})
+
+	// LinksCreatedTotal compte les liens courts créés.
+	LinksCreatedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "links_created_total",
+		Help:      "Short links created.",
+	})
+
+	// LinkCacheLookupsTotal compte les recherches de liens par code court dans le cache, par résultat.
+	LinkCacheLookupsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "link_cache_lookups_total",
+		Help:      "Short code lookups in the link cache, by result (hit, negative_hit, miss).",
+	}, []string{"result"})
+
+	// LinkCacheEvictionsTotal compte les entrées évincées du cache des liens faute de place.
+	LinkCacheEvictionsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "link_cache_evictions_total",
+		Help:      "Entries evicted from the link cache because it was full.",
+	})
+
+	// LinkCacheEntries mesure le nombre d'entrées du cache des liens.
+	LinkCacheEntries = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "link_cache_entries",
+		Help:      "Entries (links and unknown short codes) in the link cache.",
+	})
+
+	// RateLimitedTotal compte les requêtes refusées par la limitation de débit, par politique.
+	RateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "rate_limited_requests_total",
+		Help:      "Requests rejected with 429 by the rate limiter, by policy (create, stats, redirect).",
+	}, []string{"policy"})
+
+	// ClickEventsEnqueuedTotal compte les événements de clic envoyés dans le channel des workers.
+	ClickEventsEnqueuedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "click_events_enqueued_total",
+		Help:      "Click events sent to the worker channel.",
+	})
+
+	// ClickEventsDroppedTotal compte les événements de clic abandonnés.
+	ClickEventsDroppedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "click_events_dropped_total",
+		Help:      "Click events dropped because the worker channel was full or closed.",
+	})
+
+	// ClickEventsSpilledTotal compte les événements de clic écrits dans le journal de débordement.
+	ClickEventsSpilledTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "click_events_spilled_total",
+		Help:      "Click events written to the on-disk spill journal.",
+	})
+
+	// ClicksPersistedTotal compte les clics enregistrés en base par les workers.
+	ClicksPersistedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "clicks_persisted_total",
+		Help:      "Clicks persisted to the database by the workers.",
+	})
+
+	// ClickInsertErrorsTotal compte les échecs d'insertion des workers, par type d'insertion.
+	ClickInsertErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "click_insert_errors_total",
+		Help:      "Failed click inserts, by kind (batch, single, replay).",
+	}, []string{"kind"})
+
+	// MonitorChecksTotal compte les vérifications d'URLs du moniteur, par résultat.
+	MonitorChecksTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "monitor_checks_total",
+		Help:      "URL monitor checks, by result (accessible, inaccessible, blocked).",
+	}, []string{"result"})
+
+	// MonitorCheckDuration mesure la durée de vérification d'une URL par le moniteur.
+	MonitorCheckDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "monitor_check_duration_seconds",
+		Help:      "Duration of a single URL check by the monitor.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+
+	// MonitorPassesTotal compte les passages du moniteur, par issue.
+	MonitorPassesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "monitor_passes_total",
+		Help:      "URL monitor passes, by outcome (completed, deadline_exceeded, interrupted, skipped).",
+	}, []string{"outcome"})
+
+	// MonitorLastPassLinks indique le nombre de liens du dernier passage terminé, par résultat.
+	MonitorLastPassLinks = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "monitor_last_pass_links",
+		Help:      "Links in the last URL monitor pass, by result (accessible, inaccessible, blocked, unchecked).",
+	}, []string{"result"})
+
+	// MonitorPassDuration mesure la durée d'un passage complet du moniteur sur tous les liens.
+	MonitorPassDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "monitor_pass_duration_seconds",
+		Help:      "Duration of a full URL monitor pass over all links.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 12),
+	})
+
+	// WebhookEventsTotal compte les événements publiés vers au moins un endpoint de webhook, par type.
+	WebhookEventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_events_total",
+		Help:      "Events queued for delivery to at least one webhook endpoint, by event type.",
+	}, []string{"event"})
+
+	// WebhookAttemptsTotal compte les tentatives de livraison de webhooks, par résultat.
+	WebhookAttemptsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempts_total",
+		Help:      "Webhook delivery attempts, by result (succeeded, failed).",
+	}, []string{"result"})
+
+	// WebhookDeliveriesFailedTotal compte les livraisons abandonnées après leur dernière tentative.
+	WebhookDeliveriesFailedTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_failed_total",
+		Help:      "Webhook deliveries abandoned after their last attempt.",
+	})
+
+	// WebhookAttemptDuration mesure la durée d'une tentative de livraison de webhook.
+	WebhookAttemptDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempt_duration_seconds",
+		Help:      "Duration of a webhook delivery attempt.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		RedirectDuration,
+		RedirectsTotal,
+		LinksCreatedTotal,
+		LinkCacheLookupsTotal,
+		LinkCacheEvictionsTotal,
+		LinkCacheEntries,
+		RateLimitedTotal,
+		ClickEventsEnqueuedTotal,
+		ClickEventsDroppedTotal,
+		ClickEventsSpilledTotal,
+		ClicksPersistedTotal,
+		ClickInsertErrorsTotal,
+		MonitorChecksTotal,
+		MonitorCheckDuration,
+		MonitorPassesTotal,
+		MonitorLastPassLinks,
+		MonitorPassDuration,
+		WebhookEventsTotal,
+		WebhookAttemptsTotal,
+		WebhookDeliveriesFailedTotal,
+		WebhookAttemptDuration,
+	)
+}
+
+// RegisterClickChannel expose l'occupation du channel des événements de clic :
+// nombre d'événements en attente, capacité (Analytics.BufferSize) et ratio entre les deux.
+// 'length' est appelée à chaque collecte ; RegisterClickChannel ne doit être appelée qu'une fois.
+func RegisterClickChannel(length func() int, capacity int) {
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_channel_length",
+			Help:      "Click events waiting in the worker channel.",
+		}, func() float64 { return float64(length()) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_channel_capacity",
+			Help:      "Capacity of the worker channel (analytics.buffer_size).",
+		}, func() float64 { return float64(capacity) }),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "click_channel_occupancy_ratio",
+			Help:      "Fraction of the worker channel capacity in use.",
+		}, func() float64 {
+			if capacity <= 0 {
+				return 0
+			}
+			return float64(length()) / float64(capacity)
+		}),
+	)
+}
+
+// Handler retourne le handler HTTP qui expose les métriques au format texte de Prometheus.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
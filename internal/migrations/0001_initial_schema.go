@@ -0,0 +1,78 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Schéma initial : les tables links, clicks, api_keys et visitor_salts telles que créées
+// auparavant par AutoMigrate. Les structures ci-dessous sont une copie figée des modèles :
+// les modèles peuvent évoluer, cette migration doit toujours produire le même schéma.
+// Sur une base créée par l'ancienne commande 'migrate', AutoMigrate ne modifie rien :
+// la migration y est simplement enregistrée comme appliquée.
+
+type link0001 struct {
+	ID        uint   `gorm:"primaryKey"`
+	ShortCode string `gorm:"size:32;uniqueIndex;not null"`
+	LongURL   string `gorm:"type:text;not null"`
+	CreatedAt time.Time
+	ExpiresAt *time.Time
+	MaxClicks int
+	OwnerID   *uint       `gorm:"index"`
+	Clicks    []click0001 `gorm:"foreignKey:LinkID"`
+}
+
+func (link0001) TableName() string { return "links" }
+
+type click0001 struct {
+	ID           uint     `gorm:"primaryKey"`
+	LinkID       uint     `gorm:"index"`
+	Link         link0001 `gorm:"foreignKey:LinkID"`
+	Timestamp    time.Time
+	UserAgent    string `gorm:"size:255"`
+	IPAddress    string `gorm:"size:50"`
+	Referrer     string `gorm:"size:512"`
+	ReferrerHost string `gorm:"size:255;index"`
+	Browser      string `gorm:"size:50"`
+	OS           string `gorm:"size:50"`
+	DeviceType   string `gorm:"size:20"`
+	IsBot        bool   `gorm:"index;not null;default:false"`
+	VisitorHash  string `gorm:"size:64;index"`
+}
+
+func (click0001) TableName() string { return "clicks" }
+
+type apiKey0001 struct {
+	ID         uint   `gorm:"primaryKey"`
+	Name       string `gorm:"size:100;not null"`
+	Prefix     string `gorm:"size:16;not null"`
+	KeyHash    string `gorm:"size:64;uniqueIndex;not null"`
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+}
+
+func (apiKey0001) TableName() string { return "api_keys" }
+
+type visitorSalt0001 struct {
+	Day       string `gorm:"primaryKey;size:10"`
+	Salt      string `gorm:"size:64;not null"`
+	CreatedAt time.Time
+}
+
+func (visitorSalt0001) TableName() string { return "visitor_salts" }
+
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			return tx.AutoMigrate(&link0001{}, &click0001{}, &apiKey0001{}, &visitorSalt0001{})
+		},
+		Down: func(tx *gorm.DB) error {
+			// clicks d'abord : elle référence links.
+			return tx.Migrator().DropTable(&click0001{}, &link0001{}, &apiKey0001{}, &visitorSalt0001{})
+		},
+	})
+}
@@ -0,0 +1,70 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Santé des liens : historique des vérifications du moniteur (table link_checks)
+// et état de santé courant sur chaque lien.
+
+type link0002 struct {
+	ID              uint   `gorm:"primaryKey"`
+	HealthStatus    string `gorm:"size:16;not null;default:unknown;index"`
+	LastCheckedAt   *time.Time
+	HealthChangedAt *time.Time
+}
+
+func (link0002) TableName() string { return "links" }
+
+type linkCheck0002 struct {
+	ID         uint      `gorm:"primaryKey"`
+	LinkID     uint      `gorm:"not null;index:idx_link_checks_link_checked,priority:1"`
+	CheckedAt  time.Time `gorm:"not null;index:idx_link_checks_link_checked,priority:2;index"`
+	Healthy    bool      `gorm:"not null"`
+	StatusCode int
+	LatencyMs  int64
+	ErrorClass string `gorm:"size:32"`
+}
+
+func (linkCheck0002) TableName() string { return "link_checks" }
+
+// link0002Columns liste les colonnes ajoutées à links, dans l'ordre d'ajout.
+var link0002Columns = []string{"health_status", "last_checked_at", "health_changed_at"}
+
+func init() {
+	register(Migration{
+		Version: 2,
+		Name:    "link_health",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			for _, column := range link0002Columns {
+				if err := migrator.AddColumn(&link0002{}, column); err != nil {
+					return err
+				}
+			}
+			if err := migrator.CreateIndex(&link0002{}, "HealthStatus"); err != nil {
+				return err
+			}
+			return migrator.CreateTable(&linkCheck0002{})
+		},
+		Down: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.DropTable(&linkCheck0002{}); err != nil {
+				return err
+			}
+			if err := migrator.DropIndex(&link0002{}, "HealthStatus"); err != nil {
+				return err
+			}
+			// ALTER TABLE ... DROP COLUMN plutôt que Migrator().DropColumn : sous SQLite, ce dernier
+			// recrée la table links et perd ses index. SQLite le gère nativement depuis la version 3.35.
+			for i := len(link0002Columns) - 1; i >= 0; i-- {
+				if err := tx.Exec("ALTER TABLE links DROP COLUMN " + link0002Columns[i]).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
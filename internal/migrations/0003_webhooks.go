@@ -0,0 +1,68 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhooks : journal des livraisons (table webhook_deliveries) et date à laquelle un lien
+// a été constaté expiré, pour n'émettre l'événement link.expired qu'une fois par lien.
+
+type link0003 struct {
+	ID        uint `gorm:"primaryKey"`
+	ExpiredAt *time.Time
+}
+
+func (link0003) TableName() string { return "links" }
+
+type webhookDelivery0003 struct {
+	ID            uint       `gorm:"primaryKey"`
+	Endpoint      string     `gorm:"size:64;not null;index"`
+	URL           string     `gorm:"type:text;not null"`
+	Event         string     `gorm:"size:32;not null;index"`
+	EventID       string     `gorm:"size:32;not null;index"`
+	Payload       string     `gorm:"type:text;not null"`
+	Status        string     `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts      int        `gorm:"not null;default:0"`
+	NextAttemptAt *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt *time.Time
+	ResponseCode  int
+	LastError     string `gorm:"size:512"`
+	ReplayOf      *uint
+	CreatedAt     time.Time `gorm:"index"`
+	DeliveredAt   *time.Time
+}
+
+func (webhookDelivery0003) TableName() string { return "webhook_deliveries" }
+
+func init() {
+	register(Migration{
+		Version: 3,
+		Name:    "webhooks",
+		Up: func(tx *gorm.DB) error {
+			migrator := tx.Migrator()
+			if err := migrator.AddColumn(&link0003{}, "ExpiredAt"); err != nil {
+				return err
+			}
+			// Les liens déjà expirés sont marqués comme tels : activer les webhooks ne doit pas
+			// émettre link.expired pour des liens expirés avant la migration.
+			now := time.Now()
+			err := tx.Exec(`UPDATE links SET expired_at = ?
+				WHERE (expires_at IS NOT NULL AND expires_at <= ?)
+				OR (max_clicks > 0 AND (SELECT COUNT(*) FROM clicks WHERE clicks.link_id = links.id AND clicks.is_bot = ?) >= max_clicks)`,
+				now, now, false).Error
+			if err != nil {
+				return err
+			}
+			return migrator.CreateTable(&webhookDelivery0003{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&webhookDelivery0003{}); err != nil {
+				return err
+			}
+			// Voir 0002_link_health : DropColumn recréerait la table links sous SQLite.
+			return tx.Exec("ALTER TABLE links DROP COLUMN expired_at").Error
+		},
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Budget de clics : compteur des clics humains décomptés du budget max_clicks, incrémenté
+// atomiquement à chaque redirection au lieu de compter les clics enregistrés.
+
+type link0004 struct {
+	ID          uint `gorm:"primaryKey"`
+	HumanClicks int  `gorm:"not null;default:0"`
+}
+
+func (link0004) TableName() string { return "links" }
+
+func init() {
+	register(Migration{
+		Version: 4,
+		Name:    "link_click_budget",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&link0004{}, "HumanClicks"); err != nil {
+				return err
+			}
+			// Le budget déjà consommé est repris des clics humains enregistrés.
+			return tx.Exec(`UPDATE links SET human_clicks =
+				(SELECT COUNT(*) FROM clicks WHERE clicks.link_id = links.id AND clicks.is_bot = ?)
+				WHERE max_clicks > 0`, false).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			// Voir 0002_link_health : DropColumn recréerait la table links sous SQLite.
+			return tx.Exec("ALTER TABLE links DROP COLUMN human_clicks").Error
+		},
+	})
+}
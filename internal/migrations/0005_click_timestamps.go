@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Horodatages des clics : index (link_id, timestamp) pour les filtres par période, et conversion
+// en UTC des horodatages SQLite, stockés sous forme de texte avec le décalage du serveur.
+
+type click0005 struct {
+	ID        uint      `gorm:"primaryKey"`
+	LinkID    uint      `gorm:"index:idx_clicks_link_timestamp,priority:1"`
+	Timestamp time.Time `gorm:"index:idx_clicks_link_timestamp,priority:2"`
+}
+
+func (click0005) TableName() string { return "clicks" }
+
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "click_timestamps",
+		Up: func(tx *gorm.DB) error {
+			// Même format que le pilote SQLite ('2006-01-02 15:04:05.999999999-07:00'), à la milliseconde :
+			// les dates SQLite n'ont pas de précision supérieure.
+			if tx.Dialector.Name() == "sqlite" {
+				err := tx.Exec(`UPDATE clicks SET timestamp = strftime('%Y-%m-%d %H:%M:%f', timestamp) || '+00:00'
+					WHERE timestamp NOT LIKE '%+00:00' AND strftime('%s', timestamp) IS NOT NULL`).Error
+				if err != nil {
+					return err
+				}
+			}
+			return tx.Migrator().CreateIndex(&click0005{}, "idx_clicks_link_timestamp")
+		},
+		Down: func(tx *gorm.DB) error {
+			// Les horodatages restent en UTC : ils sont lus de la même manière.
+			return tx.Migrator().DropIndex(&click0005{}, "idx_clicks_link_timestamp")
+		},
+	})
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Dates des liens et de leurs vérifications en UTC : sous SQLite, elles sont stockées sous forme
+// de texte et comparées comme telles, ce qui n'est correct que si elles partagent le même décalage.
+// Les dates écrites avant cette migration portaient le décalage du serveur, ou celui du client
+// pour les dates d'expiration ; elles sont converties
+// comme les horodatages des clics (0005). PostgreSQL et MySQL stockent des instants, rien à faire.
+
+// utcColumns sont les colonnes de dates converties, par table.
+var utcColumns = []struct{ table, column string }{
+	{"link_checks", "checked_at"},
+	{"links", "created_at"},
+	{"links", "last_checked_at"},
+	{"links", "health_changed_at"},
+	{"links", "expires_at"},
+	{"links", "expired_at"},
+}
+
+func init() {
+	register(Migration{
+		Version: 6,
+		Name:    "utc_timestamps",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "sqlite" {
+				return nil
+			}
+			for _, c := range utcColumns {
+				// Même format que 0005 ; les valeurs NULL sont ignorées par le filtre sur strftime.
+				err := tx.Exec(fmt.Sprintf(`UPDATE %[1]s SET %[2]s = strftime('%%Y-%%m-%%d %%H:%%M:%%f', %[2]s) || '+00:00'
+					WHERE %[2]s NOT LIKE '%%+00:00' AND strftime('%%s', %[2]s) IS NOT NULL`, c.table, c.column)).Error
+				if err != nil {
+					return fmt.Errorf("convert %s.%s to UTC: %w", c.table, c.column, err)
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Les dates restent en UTC : elles sont lues de la même manière.
+			return nil
+		},
+	})
+}
@@ -0,0 +1,90 @@
+package migrations
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// migrationFilePattern reconnaît les fichiers de migration 'NNNN_nom.go' et capture leur numéro.
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_[a-z0-9_]+\.go$`)
+
+// nameSanitizer remplace les caractères non autorisés dans le nom d'une migration.
+var nameSanitizer = regexp.MustCompile(`[^a-z0-9]+`)
+
+// migrationTemplate est le squelette d'un nouveau fichier de migration.
+var migrationTemplate = template.Must(template.New("migration").Parse(`package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	register(Migration{
+		Version: {{.Version}},
+		Name:    "{{.Name}}",
+		Up: func(tx *gorm.DB) error {
+			// Utiliser des structures figées propres à cette migration plutôt que les modèles,
+			// qui continueront d'évoluer.
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			// Annuler exactement ce que fait Up.
+			return nil
+		},
+	})
+}
+`))
+
+// Create écrit dans 'dir' le squelette d'une nouvelle migration nommée 'name', numérotée à la suite
+// des migrations connues et des fichiers déjà présents dans 'dir'. Retourne le chemin du fichier créé.
+func Create(dir, name string) (string, error) {
+	name = strings.Trim(nameSanitizer.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", fmt.Errorf("invalid migration name: it must contain letters or digits")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read migrations directory: %w", err)
+	}
+	var version int64
+	for _, m := range All() {
+		version = max(version, m.Version)
+	}
+	for _, entry := range entries {
+		if match := migrationFilePattern.FindStringSubmatch(entry.Name()); match != nil {
+			n, _ := strconv.ParseInt(match[1], 10, 64)
+			version = max(version, n)
+		}
+	}
+	version++
+
+	var buf bytes.Buffer
+	if err := migrationTemplate.Execute(&buf, Migration{Version: version, Name: name}); err != nil {
+		return "", fmt.Errorf("failed to render migration template: %w", err)
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("failed to format migration source: %w", err)
+	}
+
+	path := filepath.Join(dir, Migration{Version: version, Name: name}.String()+".go")
+	// O_EXCL : ne jamais écraser une migration existante.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create migration file: %w", err)
+	}
+	if _, err := f.Write(source); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write migration file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write migration file: %w", err)
+	}
+	return path, nil
+}
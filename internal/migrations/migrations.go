@@ -0,0 +1,232 @@
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrSchemaBehind indique que des migrations de ce binaire ne sont pas appliquées en base.
+var ErrSchemaBehind = errors.New("database schema is behind")
+
+// Migration est une évolution numérotée et réversible du schéma de la base de données.
+// Up et Down reçoivent une transaction : sous SQLite et PostgreSQL, une migration qui échoue
+// est entièrement annulée. MySQL valide implicitement les instructions DDL, une migration
+// MySQL qui échoue peut donc laisser le schéma à moitié modifié.
+type Migration struct {
+	Version int64                   // Numéro de la migration, unique et croissant
+	Name    string                  // Nom court en snake_case
+	Up      func(tx *gorm.DB) error // Applique la migration
+	Down    func(tx *gorm.DB) error // Annule la migration
+}
+
+// String retourne la migration sous la forme '0001_initial_schema'.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status décrit l'état d'une migration dans une base de données.
+type Status struct {
+	Migration
+	AppliedAt *time.Time // nil si la migration n'est pas appliquée
+	Unknown   bool       // Appliquée en base mais absente de ce binaire (base plus récente que le code)
+}
+
+// schemaMigration est une ligne de la table schema_migrations : une migration appliquée.
+type schemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"size:255;not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// TableName fixe le nom de la table de suivi des migrations.
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// registry contient toutes les migrations connues, enregistrées par les fonctions init() des fichiers NNNN_*.go.
+var registry = map[int64]Migration{}
+
+// register ajoute une migration au registre. Deux migrations ne peuvent pas partager un numéro.
+func register(m Migration) {
+	if existing, ok := registry[m.Version]; ok {
+		panic(fmt.Sprintf("migrations: version %d registered twice (%s and %s)", m.Version, existing, m))
+	}
+	registry[m.Version] = m
+}
+
+// All retourne toutes les migrations connues, triées par numéro croissant.
+func All() []Migration {
+	all := make([]Migration, 0, len(registry))
+	for _, m := range registry {
+		all = append(all, m)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+	return all
+}
+
+// Migrator applique et annule les migrations sur une base de données,
+// en enregistrant les migrations appliquées dans la table schema_migrations.
+type Migrator struct {
+	db     *gorm.DB
+	logger *slog.Logger
+}
+
+// NewMigrator crée un Migrator pour la base 'db'.
+func NewMigrator(db *gorm.DB, logger *slog.Logger) *Migrator {
+	if db == nil {
+		panic("NewMigrator: db cannot be nil")
+	}
+	return &Migrator{db: db, logger: logger.With("component", "migrations")}
+}
+
+// Status retourne l'état de chaque migration : celles de ce binaire, appliquées ou non,
+// puis celles appliquées en base mais inconnues de ce binaire. Le tout est trié par numéro.
+func (m *Migrator) Status() ([]Status, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, migration := range All() {
+		status := Status{Migration: migration}
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range applied {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: row.Version, Name: row.Name},
+			AppliedAt: &appliedAt,
+			Unknown:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// Pending retourne les migrations de ce binaire qui ne sont pas encore appliquées, dans l'ordre.
+func (m *Migrator) Pending() ([]Migration, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []Migration
+	for _, migration := range All() {
+		if _, ok := applied[migration.Version]; !ok {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// CheckUpToDate retourne une erreur enveloppant ErrSchemaBehind si des migrations sont en attente.
+func (m *Migrator) CheckUpToDate() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d pending migration(s), starting with %s", ErrSchemaBehind, len(pending), pending[0])
+	}
+	return nil
+}
+
+// Up applique toutes les migrations en attente, dans l'ordre, chacune dans sa propre transaction.
+// Elle s'arrête à la première erreur et retourne les migrations appliquées jusque-là.
+func (m *Migrator) Up() ([]Migration, error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	if len(pending) == 0 {
+		return nil, nil
+	}
+	if err := m.db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var done []Migration
+	for _, migration := range pending {
+		start := time.Now()
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to apply migration %s: %w", migration, err)
+		}
+		m.logger.Info("migration applied", "migration", migration.String(), "duration", time.Since(start))
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down annule les 'steps' dernières migrations appliquées, de la plus récente à la plus ancienne,
+// chacune dans sa propre transaction. Une migration appliquée mais inconnue de ce binaire
+// ne peut pas être annulée : Down s'arrête alors avec une erreur.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("invalid number of migrations to roll back: %d", steps)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(statuses) - 1; i >= 0 && len(done) < steps; i-- {
+		status := statuses[i]
+		if status.AppliedAt == nil {
+			continue
+		}
+		if status.Unknown {
+			return done, fmt.Errorf("cannot roll back migration %s: it is not known to this binary", status.Migration)
+		}
+		migration := status.Migration
+		start := time.Now()
+		err := m.db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("failed to roll back migration %s: %w", migration, err)
+		}
+		m.logger.Info("migration rolled back", "migration", migration.String(), "duration", time.Since(start))
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// applied retourne les migrations enregistrées dans schema_migrations, indexées par numéro.
+// Une base sans table schema_migrations n'a aucune migration appliquée : la table n'est créée
+// que par Up, les lectures (status, vérification au démarrage) ne modifient pas la base.
+func (m *Migrator) applied() (map[int64]schemaMigration, error) {
+	applied := map[int64]schemaMigration{}
+	if !m.db.Migrator().HasTable(&schemaMigration{}) {
+		return applied, nil
+	}
+
+	var rows []schemaMigration
+	if err := m.db.Order("version").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
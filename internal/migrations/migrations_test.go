@@ -0,0 +1,110 @@
+package migrations_test
+
+import (
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/database"
+	"github.com/axellelanca/urlshortener/internal/migrations"
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+func TestMigratorReadsDoNotCreateTable(t *testing.T) {
+	db, err := database.Open(database.Options{
+		Driver: database.DriverSQLite,
+		Name:   filepath.Join(t.TempDir(), "test.db"),
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	migrator := migrations.NewMigrator(db, slog.New(slog.DiscardHandler))
+
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	for _, status := range statuses {
+		if status.AppliedAt != nil {
+			t.Errorf("migration %s reported as applied on an empty database", status.Migration)
+		}
+	}
+	if err := migrator.CheckUpToDate(); !errors.Is(err, migrations.ErrSchemaBehind) {
+		t.Errorf("CheckUpToDate error = %v, want ErrSchemaBehind", err)
+	}
+	if db.Migrator().HasTable("schema_migrations") {
+		t.Fatal("schema_migrations created by a read-only operation")
+	}
+
+	done, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if len(done) != len(migrations.All()) {
+		t.Errorf("%d migrations applied, want %d", len(done), len(migrations.All()))
+	}
+	if err := migrator.CheckUpToDate(); err != nil {
+		t.Errorf("CheckUpToDate after Up: %v", err)
+	}
+}
+
+func TestUTCTimestampsMigration(t *testing.T) {
+	db, err := database.Open(database.Options{
+		Driver: database.DriverSQLite,
+		Name:   filepath.Join(t.TempDir(), "test.db"),
+	}, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+	migrator := migrations.NewMigrator(db, slog.New(slog.DiscardHandler))
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	if _, err := migrator.Down(1); err != nil {
+		t.Fatalf("Down: %v", err)
+	}
+
+	// Dates écrites avant la migration, avec le décalage du serveur ou du client.
+	local := time.Date(2025, 6, 1, 14, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60))
+	link := &models.Link{ShortCode: "local", LongURL: "https://example.com", CreatedAt: local,
+		LastCheckedAt: &local, HealthChangedAt: &local, ExpiresAt: &local, ExpiredAt: &local}
+	if err := db.Create(link).Error; err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	if err := db.Create(&models.LinkCheck{LinkID: link.ID, CheckedAt: local}).Error; err != nil {
+		t.Fatalf("create check: %v", err)
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("Up: %v", err)
+	}
+	const want = "2025-06-01 12:00:00.000+00:00"
+	for _, c := range []struct{ table, column string }{
+		{"link_checks", "checked_at"},
+		{"links", "created_at"},
+		{"links", "last_checked_at"},
+		{"links", "health_changed_at"},
+		{"links", "expires_at"},
+		{"links", "expired_at"},
+	} {
+		var count int64
+		if err := db.Table(c.table).Where(c.column+" = ?", want).Count(&count).Error; err != nil {
+			t.Fatalf("count %s.%s: %v", c.table, c.column, err)
+		}
+		if count != 1 {
+			t.Errorf("%s.%s not converted to %q", c.table, c.column, want)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// APIKey représente une clé d'API permettant d'accéder aux routes /api/v1.
+// Seule l'empreinte SHA-256 de la clé est stockée : la clé en clair n'est affichée qu'à sa création.
+type APIKey struct {
+	ID         uint       `gorm:"primaryKey"`                   // ID est la clé primaire
+	Name       string     `gorm:"size:100;not null"`            // Nom descriptif de la clé (équipe, application...)
+	Prefix     string     `gorm:"size:16;not null"`             // Premiers caractères de la clé, pour l'identifier sans la révéler
+	KeyHash    string     `gorm:"size:64;uniqueIndex;not null"` // Empreinte SHA-256 (hexadécimale) de la clé
+	CreatedAt  time.Time  // Horodatage de la création de la clé
+	LastUsedAt *time.Time // Dernière utilisation connue de la clé
+	RevokedAt  *time.Time // Date de révocation (nil = clé active)
+}
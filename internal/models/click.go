@@ -5,12 +5,19 @@ import "time"
 // Click représente un événement de clic sur un lien raccourci.
 // GORM utilisera ces tags pour créer la table 'clicks'.
 type Click struct {
-	ID        uint      `gorm:"primaryKey"`        // Clé primaire
-	LinkID    uint      `gorm:"index"`             // Clé étrangère vers la table 'links', indexée pour des requêtes efficaces
-	Link      Link      `gorm:"foreignKey:LinkID"` // Relation GORM: indique que LinkID est une FK vers le champ ID de Link
-	Timestamp time.Time // Horodatage précis du clic
-	UserAgent string    `gorm:"size:255"` // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
-	IPAddress string    `gorm:"size:50"`  // Adresse IP de l'utilisateur
+	ID           uint      `gorm:"primaryKey"`                                       // Clé primaire
+	LinkID       uint      `gorm:"index;index:idx_clicks_link_timestamp,priority:1"` // Clé étrangère vers la table 'links', indexée pour des requêtes efficaces
+	Link         Link      `gorm:"foreignKey:LinkID"`                                // Relation GORM: indique que LinkID est une FK vers le champ ID de Link
+	Timestamp    time.Time `gorm:"index:idx_clicks_link_timestamp,priority:2"`       // Horodatage précis du clic, en UTC
+	UserAgent    string    `gorm:"size:255"`                                         // User-Agent de l'utilisateur qui a cliqué (informations sur le navigateur/OS)
+	IPAddress    string    `gorm:"size:50"`                                          // Adresse IP de l'utilisateur
+	Referrer     string    `gorm:"size:512"`                                         // En-tête Referer de la requête (vide = accès direct)
+	ReferrerHost string    `gorm:"size:255;index"`                                   // Hôte extrait du Referer, utilisé pour les agrégations
+	Browser      string    `gorm:"size:50"`                                          // Famille de navigateur déduite du User-Agent
+	OS           string    `gorm:"size:50"`                                          // Famille de système d'exploitation déduite du User-Agent
+	DeviceType   string    `gorm:"size:20"`                                          // Type d'appareil : desktop, mobile, tablet, bot ou other
+	IsBot        bool      `gorm:"index;not null;default:false"`                     // Clic attribué à un robot (aperçu de lien, supervision...)
+	VisitorHash  string    `gorm:"size:64;index"`                                    // Empreinte anonyme du visiteur (IP + User-Agent, sel quotidien)
 }
 
 // ClickEvent représente un événement de clic brut, destiné à être passé via un channel.
@@ -20,4 +27,7 @@ type ClickEvent struct {
 	Timestamp time.Time // Horodatage du clic
 	UserAgent string    // User-Agent du client
 	IP        string    // Adresse IP du client
+	Referrer  string    // En-tête Referer de la requête
+	Method    string    // Méthode HTTP de la requête (GET, HEAD)
+	Purpose   string    // En-tête de préchargement éventuel (Sec-Purpose, Purpose, X-Purpose...)
 }
@@ -5,10 +5,20 @@ import "time"
 // Link représente un lien raccourci dans la base de données.
 // Les tags `gorm:"..."` définissent comment GORM doit mapper cette structure à une table SQL.
 type Link struct {
-	ID        uint      `gorm:"primaryKey"`                   // ID est la clé primaire
-	ShortCode string    `gorm:"size:10;uniqueIndex;not null"` // ShortCode doit être unique, indexé, taille max 10 caractères
-	LongURL   string    `gorm:"type:text;not null"`           // LongURL ne doit pas être null
-	CreatedAt time.Time // Horodatage de la création du lien
+	ID          uint       `gorm:"primaryKey"`                   // ID est la clé primaire
+	ShortCode   string     `gorm:"size:32;uniqueIndex;not null"` // ShortCode doit être unique, indexé, taille max 32 caractères (cf. services.MaxAliasLength)
+	LongURL     string     `gorm:"type:text;not null"`           // LongURL ne doit pas être null
+	CreatedAt   time.Time  // Horodatage de la création du lien
+	ExpiresAt   *time.Time // Date d'expiration optionnelle (nil = le lien n'expire jamais)
+	MaxClicks   int        // Budget de clics optionnel (0 = illimité)
+	HumanClicks int        `gorm:"not null;default:0"` // Clics humains décomptés du budget, tenu uniquement si MaxClicks > 0
+	OwnerID     *uint      `gorm:"index"`              // Clé d'API propriétaire du lien (nil = lien créé hors API)
+	ExpiredAt   *time.Time // Date à laquelle le lien a été constaté expiré (nil = pas encore constaté)
+
+	// État de santé tenu par le moniteur d'URLs (voir LinkCheck).
+	HealthStatus    string     `gorm:"size:16;not null;default:unknown;index"` // HealthUnknown, HealthUp ou HealthDown
+	LastCheckedAt   *time.Time // Date de la dernière vérification (nil = jamais vérifié)
+	HealthChangedAt *time.Time // Date du dernier changement de HealthStatus
 
 	// Relation avec les clics : un lien peut avoir plusieurs clics
 	Clicks []Click `gorm:"foreignKey:LinkID"`
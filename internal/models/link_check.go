@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// États de santé d'un lien (Link.HealthStatus).
+const (
+	HealthUnknown = "unknown" // Jamais vérifié
+	HealthUp      = "up"      // Dernière vérification réussie
+	HealthDown    = "down"    // Dernière vérification en échec
+)
+
+// Classes d'erreur d'une vérification (LinkCheck.ErrorClass).
+const (
+	CheckErrorNone       = ""
+	CheckErrorInvalidURL = "invalid_url" // URL impossible à interpréter
+	CheckErrorBlocked    = "blocked"     // Destination refusée par la protection du réseau interne
+	CheckErrorDNS        = "dns"         // Nom d'hôte introuvable
+	CheckErrorTimeout    = "timeout"     // Délai de vérification dépassé
+	CheckErrorConnection = "connection"  // Connexion refusée ou interrompue
+	CheckErrorTLS        = "tls"         // Échec de la négociation TLS ou certificat invalide
+	CheckErrorHTTPStatus = "http_status" // Réponse reçue avec un code 4xx ou 5xx
+	CheckErrorOther      = "other"
+)
+
+// LinkCheck représente une vérification de l'URL longue d'un lien par le moniteur.
+type LinkCheck struct {
+	ID         uint      `gorm:"primaryKey"`
+	LinkID     uint      `gorm:"not null;index:idx_link_checks_link_checked,priority:1"` // Lien vérifié
+	CheckedAt  time.Time `gorm:"not null;index:idx_link_checks_link_checked,priority:2;index"`
+	Healthy    bool      `gorm:"not null"` // Code de statut 2xx ou 3xx reçu
+	StatusCode int       // Code de statut HTTP reçu (0 = aucune réponse)
+	LatencyMs  int64     // Durée de la vérification, redirections comprises
+	ErrorClass string    `gorm:"size:32"` // CheckErrorNone si la vérification a abouti à une réponse 2xx ou 3xx
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// VisitorSalt représente le sel aléatoire utilisé pendant une journée (UTC) pour calculer
+// les empreintes anonymes des visiteurs. Les sels des jours passés sont supprimés,
+// ce qui rend impossible de relier les empreintes d'un jour à l'autre ou de les recalculer.
+type VisitorSalt struct {
+	Day       string    `gorm:"primaryKey;size:10"` // Jour UTC au format AAAA-MM-JJ
+	Salt      string    `gorm:"size:64;not null"`   // Sel aléatoire (hexadécimal)
+	CreatedAt time.Time // Horodatage de la création du sel
+}
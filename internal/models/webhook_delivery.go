@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// Types d'événements envoyés aux webhooks (WebhookDelivery.Event).
+const (
+	EventLinkCreated = "link.created" // Lien créé (API ou CLI)
+	EventLinkDeleted = "link.deleted" // Lien supprimé
+	EventLinkExpired = "link.expired" // Lien constaté expiré (date dépassée ou budget de clics consommé)
+	EventLinkDown    = "link.down"    // Lien devenu inaccessible
+	EventLinkUp      = "link.up"      // Lien redevenu accessible
+	EventWebhookTest = "webhook.test" // Envoi de test ('webhooks test'), quel que soit l'abonnement
+)
+
+// WebhookEvents liste les événements auxquels un endpoint peut s'abonner.
+var WebhookEvents = []string{EventLinkCreated, EventLinkDeleted, EventLinkExpired, EventLinkDown, EventLinkUp}
+
+// États d'une livraison de webhook (WebhookDelivery.Status).
+const (
+	DeliveryPending   = "pending"   // En attente d'une tentative
+	DeliverySucceeded = "succeeded" // Réponse 2xx reçue
+	DeliveryFailed    = "failed"    // Tentatives épuisées
+)
+
+// WebhookDelivery représente l'envoi d'un événement à un endpoint de webhook et ses tentatives.
+// Payload est le corps JSON envoyé tel quel à chaque tentative : la signature porte sur ces octets.
+type WebhookDelivery struct {
+	ID            uint       `gorm:"primaryKey"`
+	Endpoint      string     `gorm:"size:64;not null;index"` // Nom de l'endpoint dans la configuration
+	URL           string     `gorm:"type:text;not null"`     // URL de l'endpoint lors de la dernière tentative
+	Event         string     `gorm:"size:32;not null;index"` // Type d'événement (EventLinkDown...)
+	EventID       string     `gorm:"size:32;not null;index"` // Identifiant de l'événement, commun à ses livraisons
+	Payload       string     `gorm:"type:text;not null"`
+	Status        string     `gorm:"size:16;not null;index:idx_webhook_deliveries_due,priority:1"` // DeliveryPending, DeliverySucceeded ou DeliveryFailed
+	Attempts      int        `gorm:"not null;default:0"`                                           // Nombre de tentatives commencées
+	NextAttemptAt *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`                  // Prochaine tentative (nil si la livraison est terminée)
+	LastAttemptAt *time.Time
+	ResponseCode  int        // Code HTTP de la dernière réponse (0 = aucune réponse)
+	LastError     string     `gorm:"size:512"` // Cause du dernier échec
+	ReplayOf      *uint      // Livraison rejouée par celle-ci ('webhooks replay')
+	CreatedAt     time.Time  `gorm:"index"`
+	DeliveredAt   *time.Time // Date de la réponse 2xx
+}
@@ -0,0 +1,94 @@
+package monitor
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hostLimiter limite les vérifications simultanées vers un même hôte et espace leurs débuts
+// d'un délai de politesse, pour ne pas surcharger un site vers lequel pointent beaucoup de liens.
+// Un hostLimiter est créé pour chaque passage du moniteur.
+type hostLimiter struct {
+	perHost int           // Vérifications simultanées maximum par hôte
+	delay   time.Duration // Délai minimal entre les débuts de deux vérifications d'un même hôte
+
+	mu    sync.Mutex
+	hosts map[string]*hostSlot
+}
+
+// hostSlot est l'état d'un hôte : places de vérification et prochain début autorisé.
+type hostSlot struct {
+	slots chan struct{} // Une valeur par vérification en cours
+	next  time.Time     // Début au plus tôt de la prochaine vérification
+}
+
+// newHostLimiter crée un hostLimiter.
+func newHostLimiter(perHost int, delay time.Duration) *hostLimiter {
+	return &hostLimiter{
+		perHost: perHost,
+		delay:   delay,
+		hosts:   make(map[string]*hostSlot),
+	}
+}
+
+// acquire attend qu'une place se libère pour 'host' puis que le délai de politesse soit écoulé.
+// Retourne l'erreur de ctx si elle expire avant ; sinon release doit être appelée après la vérification.
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	l.mu.Lock()
+	slot, ok := l.hosts[host]
+	if !ok {
+		slot = &hostSlot{slots: make(chan struct{}, l.perHost)}
+		l.hosts[host] = slot
+	}
+	l.mu.Unlock()
+
+	select {
+	case slot.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	// Réserver le prochain créneau de l'hôte : les débuts sont espacés d'au moins 'delay'.
+	l.mu.Lock()
+	now := time.Now()
+	start := slot.next
+	if start.Before(now) {
+		start = now
+	}
+	slot.next = start.Add(l.delay)
+	l.mu.Unlock()
+
+	wait := time.Until(start)
+	if wait <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		<-slot.slots
+		return ctx.Err()
+	}
+}
+
+// release libère la place prise par acquire pour 'host'.
+func (l *hostLimiter) release(host string) {
+	l.mu.Lock()
+	slot := l.hosts[host]
+	l.mu.Unlock()
+	<-slot.slots
+}
+
+// hostOf retourne le nom d'hôte d'une URL, en minuscules, ou une chaîne vide si l'URL est invalide.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -1,110 +1,399 @@
 package monitor
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
-	"sync" // Pour protéger l'accès concurrentiel à knownStates
+	"sync" // Pour protéger l'accès concurrentiel à interval
+	"syscall"
 	"time"
 
-	_ "github.com/axellelanca/urlshortener/internal/models"   // Importe les modèles de liens
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/models" // Importe les modèles de liens
+	"github.com/axellelanca/urlshortener/internal/netguard"
 	"github.com/axellelanca/urlshortener/internal/repository" // Importe le repository de liens
+	"github.com/axellelanca/urlshortener/internal/webhooks"
 )
 
+// Config regroupe les réglages du moniteur.
+type Config struct {
+	Interval           time.Duration // Intervalle entre deux passages (modifiable via SetInterval)
+	Concurrency        int           // Vérifications simultanées maximum sur l'ensemble des liens
+	PerHostConcurrency int           // Vérifications simultanées maximum vers un même hôte
+	HostDelay          time.Duration // Délai minimal entre les débuts de deux vérifications d'un même hôte
+	PassTimeout        time.Duration // Durée maximale d'un passage (0 = l'intervalle courant)
+	CheckRetention     time.Duration // Durée de conservation des vérifications (0 = conservées indéfiniment)
+}
+
 // UrlMonitor gère la surveillance périodique des URLs longues.
 type UrlMonitor struct {
-	linkRepo    repository.LinkRepository // Pour récupérer les URLs à surveiller
-	interval    time.Duration             // Intervalle entre chaque vérification (ex: 5 minutes)
-	knownStates map[uint]bool             // État connu de chaque URL: map[LinkID]estAccessible (true/false)
-	mu          sync.Mutex                // Mutex pour protéger l'accès concurrentiel à knownStates
+	linkRepo  repository.LinkRepository      // Pour récupérer les URLs à surveiller et leur état de santé connu
+	checkRepo repository.LinkCheckRepository // Pour enregistrer chaque vérification et l'état de santé des liens
+	interval  time.Duration                  // Intervalle entre chaque vérification (ex: 5 minutes)
+	cfg       Config                         // Réglages des passages (l'intervalle courant est 'interval')
+	client    *http.Client                   // Client des requêtes HEAD, protégé par netguard le cas échéant
+	mu        sync.Mutex                     // Mutex pour protéger l'accès concurrentiel à interval
+	logger    *slog.Logger                   // Logger structuré du moniteur
+	webhooks  *webhooks.Dispatcher           // Publie les changements d'état ; nil si les webhooks sont désactivés
+
+	ctx      context.Context    // Annulé par Stop pour interrompre une vérification en cours
+	cancel   context.CancelFunc // Fonction d'annulation associée à ctx
+	done     chan struct{}      // Fermé lorsque la boucle de Start s'est terminée
+	resetC   chan struct{}      // Signale à la boucle de Start que l'intervalle a changé
+	stopOnce sync.Once          // Garantit que Stop n'agit qu'une seule fois
 }
 
 // NewUrlMonitor crée et retourne une nouvelle instance de UrlMonitor.
+// 'client' effectue les vérifications ; il doit avoir un timeout (voir netguard.Guard.NewHTTPClient).
 // Attention: retourne un pointeur
-func NewUrlMonitor(linkRepo repository.LinkRepository, interval time.Duration) *UrlMonitor {
+func NewUrlMonitor(linkRepo repository.LinkRepository, checkRepo repository.LinkCheckRepository, cfg Config, client *http.Client, logger *slog.Logger) *UrlMonitor {
+	ctx, cancel := context.WithCancel(context.Background())
 	return &UrlMonitor{
-		linkRepo:    linkRepo,
-		interval:    interval,
-		knownStates: make(map[uint]bool),
+		linkRepo:  linkRepo,
+		checkRepo: checkRepo,
+		interval:  cfg.Interval,
+		cfg:       cfg,
+		client:    client,
+		logger:    logger.With("component", "monitor"),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		resetC:    make(chan struct{}, 1),
 	}
 }
 
+// SetWebhooks fait publier par 'dispatcher' les changements d'état des liens (link.down, link.up).
+// Doit être appelée avant Start.
+func (m *UrlMonitor) SetWebhooks(dispatcher *webhooks.Dispatcher) {
+	m.webhooks = dispatcher
+}
+
 // Start lance la boucle de surveillance périodique des URLs.
 // Cette fonction est conçue pour être lancée dans une goroutine séparée.
+// Elle se termine lorsque Stop est appelée, après la fin du passage en cours.
 func (m *UrlMonitor) Start() {
-	log.Printf("[MONITOR] Démarrage du moniteur d'URLs avec un intervalle de %v...", m.interval)
-	ticker := time.NewTicker(m.interval) // Crée un ticker qui envoie un signal à chaque intervalle
-	defer ticker.Stop()                  // S'assure que le ticker est arrêté quand Start se termine
+	defer close(m.done)
+
+	m.logger.Info("starting url monitor", "interval", m.currentInterval(), "concurrency", m.cfg.Concurrency,
+		"per_host_concurrency", m.cfg.PerHostConcurrency, "host_delay", m.cfg.HostDelay)
+	ticker := time.NewTicker(m.currentInterval()) // Crée un ticker qui envoie un signal à chaque intervalle
+	defer ticker.Stop()                           // S'assure que le ticker est arrêté quand Start se termine
+
+	// Chaque passage s'exécute dans sa propre goroutine : la boucle reste disponible pour les
+	// changements d'intervalle et l'arrêt. passDone est fermé à la fin du passage en cours.
+	var passDone chan struct{}
+	startPass := func() {
+		if passDone != nil {
+			select {
+			case <-passDone:
+			default:
+				// Le passage précédent n'est pas terminé : ce déclenchement est abandonné.
+				m.logger.Warn("previous url check pass still running, tick skipped")
+				metrics.MonitorPassesTotal.WithLabelValues(metrics.PassSkipped).Inc()
+				return
+			}
+		}
+		passDone = make(chan struct{})
+		go func(done chan struct{}) {
+			defer close(done)
+			m.checkUrls()
+		}(passDone)
+	}
 
 	// Exécute une première vérification immédiatement au démarrage
-	m.checkUrls()
+	startPass()
+
+	// Boucle principale du moniteur, déclenchée par le ticker jusqu'à l'appel de Stop
+	for {
+		select {
+		case <-ticker.C:
+			startPass()
+		case <-m.resetC:
+			// Le prochain passage a lieu un intervalle complet après la modification.
+			ticker.Reset(m.currentInterval())
+		case <-m.ctx.Done():
+			if passDone != nil {
+				<-passDone
+			}
+			m.logger.Info("url monitor stopped")
+			return
+		}
+	}
+}
+
+// SetInterval modifie l'intervalle entre deux vérifications, sans interrompre le passage en cours.
+// Le prochain passage a lieu 'interval' après la modification.
+func (m *UrlMonitor) SetInterval(interval time.Duration) {
+	m.mu.Lock()
+	m.interval = interval
+	m.mu.Unlock()
 
-	// Boucle principale du moniteur, déclenchée par le ticker
-	for range ticker.C {
-		m.checkUrls()
+	select {
+	case m.resetC <- struct{}{}:
+	default: // Une modification est déjà en attente : elle lira la nouvelle valeur.
 	}
+	m.logger.Info("url monitor interval changed", "interval", interval)
+}
+
+// currentInterval retourne l'intervalle courant entre deux vérifications.
+func (m *UrlMonitor) currentInterval() time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.interval
+}
+
+// Stop arrête le moniteur : la vérification en cours est interrompue et le ticker est arrêté.
+// Stop attend la fin de la boucle de Start, ou l'expiration de ctx.
+func (m *UrlMonitor) Stop(ctx context.Context) error {
+	m.stopOnce.Do(m.cancel)
+
+	select {
+	case <-m.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// passCounts compte les liens d'un passage par résultat (metrics.CheckAccessible, etc.).
+type passCounts struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+// add compte un lien pour 'result'.
+func (p *passCounts) add(result string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[result]++
 }
 
 // checkUrls effectue une vérification de l'état de toutes les URLs longues enregistrées.
+// Les liens sont répartis entre cfg.Concurrency goroutines ; les liens non vérifiés avant
+// la fin du délai du passage (ou l'arrêt du moniteur) le seront au passage suivant.
 func (m *UrlMonitor) checkUrls() {
-	log.Println("[MONITOR] Lancement de la vérification de l'état des URLs...")
+	passStart := time.Now()
+	defer func() { metrics.MonitorPassDuration.Observe(time.Since(passStart).Seconds()) }()
 
-	// Récupérer toutes les URLs longues actives depuis le linkRepo (GetAllLinks).
+	timeout := m.cfg.PassTimeout
+	if timeout == 0 {
+		timeout = m.currentInterval()
+	}
+	ctx, cancel := context.WithTimeout(m.ctx, timeout)
+	defer cancel()
+
+	// Récupérer toutes les URLs longues actives depuis le linkRepo (GetAllLinks), les moins
+	// récemment vérifiées d'abord : les liens non vérifiés à la fin du délai passent en tête au passage suivant.
 	links, err := m.linkRepo.GetAllLinks()
 	if err != nil {
-		log.Printf("[MONITOR] ERREUR lors de la récupération des liens pour la surveillance : %v", err)
+		m.logger.Error("failed to load links to monitor", "error", err)
 		return
 	}
+	m.logger.Debug("starting url check pass", "links", len(links), "deadline", timeout)
+
+	counts := &passCounts{counts: make(map[string]int)}
+	hosts := newHostLimiter(m.cfg.PerHostConcurrency, m.cfg.HostDelay)
+	jobs := make(chan models.Link)
+	var wg sync.WaitGroup
+	for i := 0; i < min(m.cfg.Concurrency, len(links)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range jobs {
+				counts.add(m.checkLink(ctx, hosts, link))
+			}
+		}()
+	}
 
+	// Distribuer les liens jusqu'à la fin du délai du passage ou l'arrêt du moniteur.
+feed:
 	for _, link := range links {
-		// Pour chaque lien, vérifier son accessibilité (isUrlAccessible).
-		currentState := m.isUrlAccessible(link.LongURL)
-
-		// Protéger l'accès à la map 'knownStates' car 'checkUrls' peut être exécuté concurremment
-		m.mu.Lock()
-		previousState, exists := m.knownStates[link.ID] // Récupère l'état précédent
-		m.knownStates[link.ID] = currentState           // Met à jour l'état actuel
-		m.mu.Unlock()
-
-		// Si c'est la première vérification pour ce lien, on initialise l'état sans notifier.
-		if !exists {
-			log.Printf("[MONITOR] État initial pour le lien %s (%s) : %s",
-				link.ShortCode, link.LongURL, formatState(currentState))
-			continue
+		select {
+		case jobs <- link:
+		case <-ctx.Done():
+			break feed
 		}
+	}
+	close(jobs)
+	wg.Wait()
 
-		// Comparer l'état actuel avec l'état précédent.
-		if currentState != previousState {
-			// Si l'état a changé, générer une notification dans les logs.
-			log.Printf("[NOTIFICATION] Le lien %s (%s) est passé de %s à %s !",
-				link.ShortCode, link.LongURL, formatState(previousState), formatState(currentState))
-		}
+	checked := counts.counts[metrics.CheckAccessible] + counts.counts[metrics.CheckInaccessible] + counts.counts[metrics.CheckBlocked]
+	unchecked := len(links) - checked
+	for _, result := range []string{metrics.CheckAccessible, metrics.CheckInaccessible, metrics.CheckBlocked} {
+		metrics.MonitorLastPassLinks.WithLabelValues(result).Set(float64(counts.counts[result]))
 	}
-	log.Println("[MONITOR] Vérification de l'état des URLs terminée.")
+	metrics.MonitorLastPassLinks.WithLabelValues(metrics.LinksUnchecked).Set(float64(unchecked))
+
+	outcome := metrics.PassCompleted
+	switch {
+	case unchecked > 0 && m.ctx.Err() != nil:
+		outcome = metrics.PassInterrupted
+	case unchecked > 0:
+		outcome = metrics.PassDeadlineExceeded
+	}
+	metrics.MonitorPassesTotal.WithLabelValues(outcome).Inc()
+
+	attrs := []any{"outcome", outcome, "links", len(links),
+		"accessible", counts.counts[metrics.CheckAccessible], "inaccessible", counts.counts[metrics.CheckInaccessible],
+		"blocked", counts.counts[metrics.CheckBlocked], "unchecked", unchecked, "duration", time.Since(passStart)}
+	switch outcome {
+	case metrics.PassDeadlineExceeded:
+		m.logger.Warn("url check pass deadline exceeded, remaining links postponed to the next pass",
+			append(attrs, "deadline", timeout)...)
+	case metrics.PassInterrupted:
+		m.logger.Info("url check pass interrupted by shutdown", attrs...)
+	default:
+		m.logger.Info("url check pass finished", attrs...)
+	}
+
+	m.pruneChecks()
 }
 
-// isUrlAccessible effectue une requête HTTP HEAD pour vérifier l'accessibilité d'une URL.
-func (m *UrlMonitor) isUrlAccessible(url string) bool {
-	// Définir un timeout pour éviter de bloquer trop longtemps (5 secondes c'est bien)
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+// pruneChecks supprime les vérifications plus anciennes que cfg.CheckRetention.
+func (m *UrlMonitor) pruneChecks() {
+	if m.cfg.CheckRetention == 0 || m.ctx.Err() != nil {
+		return
+	}
+	deleted, err := m.checkRepo.DeleteChecksBefore(time.Now().Add(-m.cfg.CheckRetention))
+	if err != nil {
+		m.logger.Error("failed to prune link checks", "error", err)
+		return
+	}
+	if deleted > 0 {
+		m.logger.Debug("old link checks pruned", "deleted", deleted, "retention", m.cfg.CheckRetention)
+	}
+}
+
+// checkLink vérifie un lien en respectant les limites par hôte, enregistre la vérification et
+// l'état de santé du lien, puis retourne le résultat, ou metrics.LinksUnchecked si 'ctx' a expiré
+// avant la fin de la vérification.
+func (m *UrlMonitor) checkLink(ctx context.Context, hosts *hostLimiter, link models.Link) string {
+	host := hostOf(link.LongURL)
+	if err := hosts.acquire(ctx, host); err != nil {
+		return metrics.LinksUnchecked
+	}
+	checkStart := time.Now()
+	check := m.checkUrl(ctx, link.LongURL)
+	latency := time.Since(checkStart)
+	hosts.release(host)
+
+	// Une vérification interrompue ne dit rien de l'état du lien.
+	if ctx.Err() != nil {
+		return metrics.LinksUnchecked
+	}
+	metrics.MonitorCheckDuration.Observe(latency.Seconds())
+	result := metrics.CheckInaccessible
+	switch {
+	case check.Healthy:
+		result = metrics.CheckAccessible
+	case check.ErrorClass == models.CheckErrorBlocked:
+		result = metrics.CheckBlocked
+	}
+	metrics.MonitorChecksTotal.WithLabelValues(result).Inc()
+
+	// L'état précédent est celui enregistré en base : il survit aux redémarrages du serveur.
+	// Une destination refusée par netguard est considérée comme inaccessible.
+	previousStatus := link.HealthStatus
+	check.LinkID = link.ID
+	check.CheckedAt = checkStart
+	check.LatencyMs = latency.Milliseconds()
+	if err := m.checkRepo.RecordCheck(&link, check); err != nil {
+		m.logger.Error("failed to record link check", "short_code", link.ShortCode, "error", err)
+		return result
+	}
+
+	// Si c'est la première vérification pour ce lien, on initialise l'état sans notifier.
+	if previousStatus == models.HealthUnknown {
+		m.logger.Info("initial link state",
+			"short_code", link.ShortCode, "long_url", link.LongURL, "state", formatState(link.HealthStatus))
+		return result
 	}
 
+	// Comparer l'état actuel avec l'état précédent.
+	if link.HealthStatus != previousStatus {
+		// Si l'état a changé, générer une notification dans les logs.
+		m.logger.Warn("link state changed",
+			"short_code", link.ShortCode, "long_url", link.LongURL,
+			"previous_state", formatState(previousStatus), "state", formatState(link.HealthStatus),
+			"status_code", check.StatusCode, "error_class", check.ErrorClass)
+		event := models.EventLinkDown
+		if check.Healthy {
+			event = models.EventLinkUp
+		}
+		m.webhooks.Publish(event, &link, check)
+	}
+	return result
+}
+
+// checkUrl effectue une requête HTTP HEAD pour vérifier l'accessibilité d'une URL.
+// Retourne une vérification dont seuls Healthy, StatusCode et ErrorClass sont renseignés.
+func (m *UrlMonitor) checkUrl(ctx context.Context, url string) *models.LinkCheck {
 	// Effectuer une requête HEAD (plus légère que GET) sur l'URL.
-	resp, err := client.Head(url)
+	// La requête est liée au contexte du passage pour être annulée à son expiration ou lors de l'arrêt.
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
 	if err != nil {
-		log.Printf("[MONITOR] Erreur d'accès à l'URL '%s': %v", url, err)
-		return false
+		m.logger.Warn("invalid url", "url", url, "error", err)
+		return &models.LinkCheck{ErrorClass: models.CheckErrorInvalidURL}
+	}
+	resp, err := m.client.Do(req)
+	if err != nil {
+		errorClass := classifyError(err)
+		if errorClass == models.CheckErrorBlocked {
+			m.logger.Info("url blocked by network guard", "url", url, "error", err)
+		} else {
+			m.logger.Debug("url not reachable", "url", url, "error_class", errorClass, "error", err)
+		}
+		return &models.LinkCheck{ErrorClass: errorClass}
 	}
 	defer resp.Body.Close()
 
-	// Déterminer l'accessibilité basée sur le code de statut HTTP.
-	return resp.StatusCode >= 200 && resp.StatusCode < 400 // Codes 2xx ou 3xx
+	// Déterminer l'accessibilité basée sur le code de statut HTTP (codes 2xx ou 3xx).
+	check := &models.LinkCheck{StatusCode: resp.StatusCode}
+	if resp.StatusCode >= 200 && resp.StatusCode < 400 {
+		check.Healthy = true
+	} else {
+		check.ErrorClass = models.CheckErrorHTTPStatus
+	}
+	return check
+}
+
+// classifyError ramène l'erreur d'une requête à l'une des classes models.CheckError*.
+func classifyError(err error) string {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var recordErr tls.RecordHeaderError
+	switch {
+	case errors.Is(err, netguard.ErrBlockedAddress):
+		return models.CheckErrorBlocked
+	case errors.As(err, &dnsErr):
+		return models.CheckErrorDNS
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return models.CheckErrorTimeout
+	case errors.As(err, &certErr), errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr), errors.As(err, &recordErr):
+		return models.CheckErrorTLS
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return models.CheckErrorConnection
+	default:
+		return models.CheckErrorOther
+	}
 }
 
-// formatState est une fonction utilitaire pour rendre l'état plus lisible dans les logs.
-func formatState(accessible bool) string {
-	if accessible {
+// formatState est une fonction utilitaire pour rendre l'état de santé plus lisible dans les logs.
+func formatState(status string) string {
+	switch status {
+	case models.HealthUp:
 		return "ACCESSIBLE"
+	case models.HealthDown:
+		return "INACCESSIBLE"
+	default:
+		return "UNKNOWN"
 	}
-	return "INACCESSIBLE"
 }
@@ -0,0 +1,180 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// maxRedirects est le nombre maximal de redirections suivies par le client HTTP, comme net/http par défaut.
+const maxRedirects = 10
+
+// ErrBlockedAddress est retournée lorsqu'une destination se résout vers une adresse interdite.
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// blockedRange est une plage d'adresses interdite, avec la raison affichée dans les erreurs.
+type blockedRange struct {
+	prefix netip.Prefix
+	reason string
+}
+
+// blockedRanges liste les plages refusées : réseaux privés, boucle locale, lien local (dont les
+// services de métadonnées des clouds, ex. 169.254.169.254), et plages réservées ou non routables.
+// Les adresses IPv4 mappées en IPv6 (::ffff:a.b.c.d) sont ramenées à leur forme IPv4 avant comparaison.
+// Les préfixes de traduction et de tunnel (NAT64, Teredo, 6to4) sont refusés en entier : ils
+// encapsulent une adresse IPv4 quelconque, y compris interne.
+var blockedRanges = []blockedRange{
+	{netip.MustParsePrefix("0.0.0.0/8"), "unspecified"},
+	{netip.MustParsePrefix("10.0.0.0/8"), "private"},
+	{netip.MustParsePrefix("100.64.0.0/10"), "shared address space"},
+	{netip.MustParsePrefix("127.0.0.0/8"), "loopback"},
+	{netip.MustParsePrefix("169.254.0.0/16"), "link-local or metadata"},
+	{netip.MustParsePrefix("172.16.0.0/12"), "private"},
+	{netip.MustParsePrefix("192.0.0.0/24"), "reserved"},
+	{netip.MustParsePrefix("192.168.0.0/16"), "private"},
+	{netip.MustParsePrefix("198.18.0.0/15"), "reserved"},
+	{netip.MustParsePrefix("224.0.0.0/4"), "multicast"},
+	{netip.MustParsePrefix("240.0.0.0/4"), "reserved"},
+	{netip.MustParsePrefix("::/128"), "unspecified"},
+	{netip.MustParsePrefix("::1/128"), "loopback"},
+	{netip.MustParsePrefix("64:ff9b::/96"), "NAT64"},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), "NAT64"},
+	{netip.MustParsePrefix("2001::/32"), "Teredo"},
+	{netip.MustParsePrefix("2002::/16"), "6to4"},
+	{netip.MustParsePrefix("fc00::/7"), "private"},
+	{netip.MustParsePrefix("fe80::/10"), "link-local"},
+	{netip.MustParsePrefix("ff00::/8"), "multicast"},
+}
+
+// Guard refuse les connexions sortantes vers les adresses internes, sauf celles de la liste d'autorisation.
+// Un Guard nil n'applique aucune restriction.
+type Guard struct {
+	allowed []netip.Prefix
+}
+
+// New crée un Guard. 'allowed' liste les adresses IP ou plages CIDR autorisées malgré tout
+// (ex. un service interne à surveiller).
+func New(allowed []string) (*Guard, error) {
+	guard := &Guard{}
+	for _, entry := range allowed {
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, err
+		}
+		guard.allowed = append(guard.allowed, prefix)
+	}
+	return guard, nil
+}
+
+// parsePrefix interprète une adresse IP (plage d'une seule adresse) ou une plage CIDR.
+func parsePrefix(entry string) (netip.Prefix, error) {
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(entry)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("'%s' is neither an IP address nor a CIDR range", entry)
+	}
+	return prefix.Masked(), nil
+}
+
+// CheckAddr retourne une erreur enveloppant ErrBlockedAddress si 'addr' est interdite.
+func (g *Guard) CheckAddr(addr netip.Addr) error {
+	if g == nil {
+		return nil
+	}
+	addr = addr.WithZone("").Unmap()
+	for _, prefix := range g.allowed {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+	for _, blocked := range blockedRanges {
+		if blocked.prefix.Contains(addr) {
+			return fmt.Errorf("%w: %s is %s", ErrBlockedAddress, addr, blocked.reason)
+		}
+	}
+	return nil
+}
+
+// CheckURL vérifie qu'une URL http(s) ne désigne pas une adresse interdite, en résolvant son nom d'hôte :
+// toutes les adresses retournées doivent être autorisées.
+// Un nom qui ne se résout pas n'est pas refusé ; le client de NewHTTPClient le vérifiera à la connexion.
+func (g *Guard) CheckURL(ctx context.Context, rawURL string) error {
+	if g == nil {
+		return nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme '%s', expected http or https", u.Scheme)
+	}
+
+	host := u.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return g.CheckAddr(addr)
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return nil
+	}
+	for _, addr := range addrs {
+		if err := g.CheckAddr(addr); err != nil {
+			return fmt.Errorf("%s resolves to a forbidden address: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// NewHTTPClient crée un client HTTP dont chaque connexion est vérifiée par le Guard.
+// La vérification a lieu au moment de la connexion, sur l'adresse effectivement résolue : elle couvre
+// chaque redirection et ne peut pas être contournée par un nom dont la résolution change entre-temps.
+// Les proxies définis par l'environnement (HTTP_PROXY) sont ignorés, la connexion se ferait sinon au proxy.
+// Avec un Guard nil, le client n'applique aucune restriction.
+func (g *Guard) NewHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if g != nil {
+		dialer.Control = g.control
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:           dialer.DialContext,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   timeout,
+			ExpectContinueTimeout: time.Second,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("redirect to unsupported scheme '%s'", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+}
+
+// control est appelée par net.Dialer juste avant chaque connexion, avec l'adresse IP résolue.
+func (g *Guard) control(network, address string, _ syscall.RawConn) error {
+	if network != "tcp4" && network != "tcp6" {
+		return fmt.Errorf("%w: network %s", ErrBlockedAddress, network)
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: unparsable address %s", ErrBlockedAddress, address)
+	}
+	return g.CheckAddr(addrPort.Addr())
+}
@@ -0,0 +1,188 @@
+package netguard
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestCheckAddr(t *testing.T) {
+	guard, err := New([]string{"10.20.0.0/16", "::ffff:192.168.1.10"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		// Plages refusées.
+		{"0.0.0.0", true},
+		{"10.0.0.1", true},
+		{"100.64.0.1", true},
+		{"127.0.0.1", true},
+		{"169.254.169.254", true},
+		{"172.16.0.1", true},
+		{"172.31.255.255", true},
+		{"192.0.0.8", true},
+		{"192.168.0.1", true},
+		{"198.18.0.1", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::", true},
+		{"::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"::ffff:169.254.169.254", true},
+		{"64:ff9b::a9fe:a9fe", true},
+		{"64:ff9b:1::1", true},
+		{"2001:0:4136:e378:8000:63bf:3fff:fdd2", true},
+		{"2002:a9fe:a9fe::1", true},
+		{"2002:7f00:1::", true},
+		{"fc00::1", true},
+		{"fd12:3456::1", true},
+		{"fe80::1", true},
+		{"fe80::1%eth0", true},
+		{"ff02::1", true},
+		// Adresses publiques.
+		{"1.1.1.1", false},
+		{"8.8.8.8", false},
+		{"172.32.0.1", false},
+		{"100.128.0.1", false},
+		{"::ffff:93.184.216.34", false},
+		{"2001:db8::1", false},
+		{"2001:4860:4860::8888", false},
+		{"2606:4700:4700::1111", false},
+		// Liste d'autorisation, y compris sous forme mappée.
+		{"10.20.3.4", false},
+		{"::ffff:10.20.3.4", false},
+		{"10.21.0.1", true},
+		{"192.168.1.10", false},
+		{"192.168.1.11", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			err := guard.CheckAddr(netip.MustParseAddr(tt.addr))
+			if blocked := errors.Is(err, ErrBlockedAddress); blocked != tt.blocked {
+				t.Errorf("CheckAddr(%s) = %v, want blocked=%v", tt.addr, err, tt.blocked)
+			}
+		})
+	}
+}
+
+func TestNilGuardAllowsEverything(t *testing.T) {
+	var guard *Guard
+	if err := guard.CheckAddr(netip.MustParseAddr("127.0.0.1")); err != nil {
+		t.Errorf("nil Guard CheckAddr: %v", err)
+	}
+	if err := guard.CheckURL(context.Background(), "http://169.254.169.254/"); err != nil {
+		t.Errorf("nil Guard CheckURL: %v", err)
+	}
+}
+
+func TestNewInvalidEntry(t *testing.T) {
+	if _, err := New([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("New accepted an invalid CIDR range")
+	}
+	if _, err := New([]string{"intranet"}); err == nil {
+		t.Error("New accepted a host name")
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	guard, err := New(nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	tests := []struct {
+		url     string
+		blocked bool
+		wantErr bool
+	}{
+		{"http://127.0.0.1:8080/admin", true, true},
+		{"http://[::1]/", true, true},
+		{"http://[2002:a9fe:a9fe::1]/", true, true},
+		{"https://169.254.169.254/latest/meta-data/", true, true},
+		{"http://localhost/", true, true},
+		{"https://1.1.1.1/", false, false},
+		{"ftp://1.1.1.1/", false, true},
+		{"file:///etc/passwd", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := guard.CheckURL(context.Background(), tt.url)
+			if blocked := errors.Is(err, ErrBlockedAddress); blocked != tt.blocked || (err != nil) != tt.wantErr {
+				t.Errorf("CheckURL(%s) = %v, want blocked=%v error=%v", tt.url, err, tt.blocked, tt.wantErr)
+			}
+		})
+	}
+}
+
+// listen démarre un serveur HTTP de test sur 'addr' (adresse de boucle locale).
+func listen(t *testing.T, addr string, handler http.Handler) *httptest.Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("cannot listen on %s: %v", addr, err)
+	}
+	server := httptest.NewUnstartedServer(handler)
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestHTTPClientChecksRedirectHops(t *testing.T) {
+	// La cible de la redirection écoute sur une autre adresse de boucle locale, non autorisée.
+	internal := listen(t, "127.0.0.2:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	public := listen(t, "127.0.0.1:0", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/internal":
+			http.Redirect(w, r, internal.URL, http.StatusFound)
+		case "/ftp":
+			http.Redirect(w, r, "ftp://127.0.0.1/", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	}))
+
+	guard, err := New([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	client := guard.NewHTTPClient(5 * time.Second)
+
+	get := func(client *http.Client, url string) error {
+		resp, err := client.Get(url)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	if err := get(client, public.URL+"/"); err != nil {
+		t.Fatalf("GET allowed address: %v", err)
+	}
+	if err := get(client, public.URL+"/internal"); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("redirect to a blocked address: error = %v, want ErrBlockedAddress", err)
+	}
+	if err := get(client, internal.URL); !errors.Is(err, ErrBlockedAddress) {
+		t.Errorf("GET blocked address: error = %v, want ErrBlockedAddress", err)
+	}
+	if err := get(client, public.URL+"/ftp"); err == nil {
+		t.Error("redirect to ftp:// followed")
+	}
+
+	// Sans Guard, la même redirection est suivie : le refus vient bien de la vérification du saut.
+	var unguarded *Guard
+	if err := get(unguarded.NewHTTPClient(5*time.Second), public.URL+"/internal"); err != nil {
+		t.Errorf("unguarded redirect: %v", err)
+	}
+}
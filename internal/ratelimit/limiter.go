@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval est l'intervalle minimal entre deux nettoyages des seaux inactifs.
+const sweepInterval = time.Minute
+
+// Policy décrit une politique de limitation par seau à jetons : chaque client dispose de
+// Burst jetons au plus, regagnés au rythme de RequestsPerMinute ; chaque requête consomme un jeton.
+type Policy struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// rate retourne le nombre de jetons regagnés par seconde.
+func (p Policy) rate() float64 {
+	return float64(p.RequestsPerMinute) / 60
+}
+
+// Decision est le résultat d'une demande de jeton.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // Capacité du seau (Burst)
+	Remaining  int           // Jetons restants après la requête
+	Reset      time.Duration // Délai avant que le seau soit de nouveau plein
+	RetryAfter time.Duration // Délai avant le prochain jeton disponible (requête refusée uniquement)
+}
+
+// bucket est le seau à jetons d'un client.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter applique une politique de limitation par seau à jetons, un seau par clé de client
+// (adresse IP, clé d'API...). Il est sûr pour un usage concurrent ; la politique peut être
+// modifiée à chaud via SetPolicy.
+type Limiter struct {
+	mu        sync.Mutex
+	policy    Policy
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time // Horloge, remplacée par les tests
+}
+
+// NewLimiter crée un Limiter appliquant 'policy'.
+func NewLimiter(policy Policy) *Limiter {
+	return &Limiter{
+		policy:    policy,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Policy retourne la politique appliquée.
+func (l *Limiter) Policy() Policy {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.policy
+}
+
+// SetPolicy remplace la politique appliquée. Les jetons déjà accumulés sont conservés,
+// dans la limite de la nouvelle capacité.
+func (l *Limiter) SetPolicy(policy Policy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.policy = policy
+}
+
+// Allow consomme un jeton du seau de 'key' s'il en reste un.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	burst, rate := float64(l.policy.Burst), l.policy.rate()
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	decision := Decision{Limit: l.policy.Burst}
+	if b.tokens >= 1 {
+		b.tokens--
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = secondsToDuration((1 - b.tokens) / rate)
+	}
+	decision.Remaining = int(b.tokens)
+	decision.Reset = secondsToDuration((burst - b.tokens) / rate)
+	return decision
+}
+
+// sweep supprime les seaux redevenus pleins : ils seraient recréés à l'identique. l.mu doit être verrouillé.
+func (l *Limiter) sweep(now time.Time) {
+	burst, rate := float64(l.policy.Burst), l.policy.rate()
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= burst {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// secondsToDuration convertit un nombre de secondes en durée.
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock est une horloge avancée manuellement par les tests.
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestLimiter crée un Limiter dont l'horloge est contrôlée par le test.
+func newTestLimiter(policy Policy) (*Limiter, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+	l := NewLimiter(policy)
+	l.now = clock.Now
+	l.lastSweep = clock.now
+	return l, clock
+}
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	// 60 requêtes/minute : un jeton par seconde, rafale de 3.
+	l, clock := newTestLimiter(Policy{RequestsPerMinute: 60, Burst: 3})
+
+	for i := range 3 {
+		d := l.Allow("ip:192.0.2.1")
+		if !d.Allowed {
+			t.Fatalf("request %d refused within burst", i+1)
+		}
+		if d.Limit != 3 || d.Remaining != 2-i {
+			t.Errorf("request %d: limit=%d remaining=%d, want 3 and %d", i+1, d.Limit, d.Remaining, 2-i)
+		}
+	}
+
+	d := l.Allow("ip:192.0.2.1")
+	if d.Allowed {
+		t.Fatal("request beyond burst allowed")
+	}
+	if d.RetryAfter != time.Second {
+		t.Errorf("RetryAfter = %v, want 1s", d.RetryAfter)
+	}
+	if d.Reset != 3*time.Second {
+		t.Errorf("Reset = %v, want 3s", d.Reset)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if d := l.Allow("ip:192.0.2.1"); d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Errorf("after 0.5s: allowed=%v RetryAfter=%v, want refused and 500ms", d.Allowed, d.RetryAfter)
+	}
+
+	clock.Advance(500 * time.Millisecond)
+	if d := l.Allow("ip:192.0.2.1"); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("after 1s: allowed=%v remaining=%d, want allowed and 0", d.Allowed, d.Remaining)
+	}
+
+	// Le seau ne dépasse jamais sa capacité, quelle que soit la durée d'inactivité.
+	clock.Advance(time.Hour)
+	if d := l.Allow("ip:192.0.2.1"); !d.Allowed || d.Remaining != 2 {
+		t.Errorf("after 1h: allowed=%v remaining=%d, want allowed and 2", d.Allowed, d.Remaining)
+	}
+}
+
+func TestAllowPerClientKeys(t *testing.T) {
+	l, _ := newTestLimiter(Policy{RequestsPerMinute: 60, Burst: 1})
+
+	if !l.Allow("ip:192.0.2.1").Allowed {
+		t.Fatal("first request of ip:192.0.2.1 refused")
+	}
+	if l.Allow("ip:192.0.2.1").Allowed {
+		t.Fatal("second request of ip:192.0.2.1 allowed")
+	}
+	for _, key := range []string{"ip:192.0.2.2", "key:1", "key:2"} {
+		if !l.Allow(key).Allowed {
+			t.Errorf("first request of %s refused: buckets are not independent", key)
+		}
+	}
+}
+
+func TestSetPolicyCapsTokens(t *testing.T) {
+	l, _ := newTestLimiter(Policy{RequestsPerMinute: 60, Burst: 10})
+	l.Allow("key:1")
+
+	l.SetPolicy(Policy{RequestsPerMinute: 60, Burst: 2})
+	d := l.Allow("key:1")
+	if !d.Allowed || d.Limit != 2 || d.Remaining != 1 {
+		t.Errorf("after SetPolicy: allowed=%v limit=%d remaining=%d, want allowed, 2 and 1", d.Allowed, d.Limit, d.Remaining)
+	}
+	if got := l.Policy(); got.Burst != 2 {
+		t.Errorf("Policy().Burst = %d, want 2", got.Burst)
+	}
+}
+
+func TestSweepRemovesFullBuckets(t *testing.T) {
+	l, clock := newTestLimiter(Policy{RequestsPerMinute: 60, Burst: 5})
+	l.Allow("ip:192.0.2.1")
+	for range 5 {
+		l.Allow("ip:192.0.2.2")
+	}
+
+	// Après une minute, le premier seau est plein, le second (5 jetons à regagner
+	// en 5 secondes) aussi : ils sont supprimés au nettoyage.
+	clock.Advance(sweepInterval)
+	l.Allow("ip:192.0.2.3")
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.buckets) != 1 {
+		t.Errorf("%d buckets after sweep, want 1", len(l.buckets))
+	}
+	if _, ok := l.buckets["ip:192.0.2.3"]; !ok {
+		t.Error("bucket of the current request removed by sweep")
+	}
+}
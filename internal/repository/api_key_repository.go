@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// APIKeyRepository est une interface qui définit les méthodes d'accès aux données
+// pour les clés d'API.
+type APIKeyRepository interface {
+	// CreateAPIKey enregistre une nouvelle clé d'API.
+	CreateAPIKey(key *models.APIKey) error
+	// GetAPIKeyByHash récupère une clé d'API à partir de l'empreinte de sa valeur.
+	GetAPIKeyByHash(keyHash string) (*models.APIKey, error)
+	// GetAPIKeyByID récupère une clé d'API à partir de son identifiant.
+	GetAPIKeyByID(id uint) (*models.APIKey, error)
+	// ListAPIKeys retourne toutes les clés d'API, révoquées comprises.
+	ListAPIKeys() ([]models.APIKey, error)
+	// RevokeAPIKey marque une clé d'API comme révoquée.
+	RevokeAPIKey(id uint, revokedAt time.Time) error
+	// TouchAPIKey met à jour la date de dernière utilisation d'une clé d'API.
+	TouchAPIKey(id uint, usedAt time.Time) error
+}
+
+// GormAPIKeyRepository est l'implémentation de APIKeyRepository utilisant GORM.
+type GormAPIKeyRepository struct {
+	db *gorm.DB
+}
+
+// NewAPIKeyRepository crée une nouvelle instance de GormAPIKeyRepository.
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	if db == nil {
+		panic("nil *gorm.DB passed to NewAPIKeyRepository")
+	}
+	return &GormAPIKeyRepository{db: db}
+}
+
+// CreateAPIKey insère une nouvelle clé d'API dans la base de données.
+func (r *GormAPIKeyRepository) CreateAPIKey(key *models.APIKey) error {
+	if err := r.db.Create(key).Error; err != nil {
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+	return nil
+}
+
+// GetAPIKeyByHash récupère une clé d'API en fonction de son empreinte.
+func (r *GormAPIKeyRepository) GetAPIKeyByHash(keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByID récupère une clé d'API en fonction de son identifiant.
+func (r *GormAPIKeyRepository) GetAPIKeyByID(id uint) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.First(&key, id).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListAPIKeys retourne toutes les clés d'API, de la plus ancienne à la plus récente.
+func (r *GormAPIKeyRepository) ListAPIKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := r.db.Order("id ASC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch api keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey renseigne la date de révocation d'une clé d'API.
+func (r *GormAPIKeyRepository) RevokeAPIKey(id uint, revokedAt time.Time) error {
+	if err := r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("revoked_at", revokedAt).Error; err != nil {
+		return fmt.Errorf("failed to revoke api key %d: %w", id, err)
+	}
+	return nil
+}
+
+// TouchAPIKey renseigne la date de dernière utilisation d'une clé d'API.
+func (r *GormAPIKeyRepository) TouchAPIKey(id uint, usedAt time.Time) error {
+	if err := r.db.Model(&models.APIKey{}).Where("id = ?", id).Update("last_used_at", usedAt).Error; err != nil {
+		return fmt.Errorf("failed to update last use of api key %d: %w", id, err)
+	}
+	return nil
+}
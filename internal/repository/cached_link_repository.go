@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// LinkCacheConfig regroupe les réglages du cache de CachedLinkRepository.
+type LinkCacheConfig struct {
+	Size        int           // Nombre maximal de codes courts conservés (liens et codes inconnus)
+	TTL         time.Duration // Durée de conservation d'un lien trouvé
+	NegativeTTL time.Duration // Durée de conservation d'un code inconnu (0 = codes inconnus non conservés)
+}
+
+// CachedLinkRepository ajoute un cache LRU en mémoire devant GetLinkByShortCode d'un autre LinkRepository.
+// Les codes inconnus sont aussi conservés (cache négatif), pour une durée plus courte.
+// Les entrées sont invalidées lorsque le lien est créé, modifié ou supprimé via ce repository ;
+// une modification faite par une autre instance n'est visible qu'après expiration de l'entrée.
+// Les autres méthodes sont transmises telles quelles au repository sous-jacent.
+type CachedLinkRepository struct {
+	LinkRepository // Repository sous-jacent, utilisé pour tout ce qui n'est pas en cache
+
+	cfg     LinkCacheConfig
+	mu      sync.Mutex
+	entries map[string]*list.Element // Code court -> élément de order
+	order   *list.List               // Entrées de la plus récemment utilisée à la plus ancienne
+	// generation est incrémentée à chaque invalidation : un lien lu en base avant une invalidation
+	// n'est pas mis en cache, il pourrait être antérieur à la modification.
+	generation uint64
+}
+
+// linkCacheEntry est une entrée du cache : un lien, ou nil pour un code inconnu.
+type linkCacheEntry struct {
+	shortCode string
+	link      *models.Link // nil si le code est inconnu
+	expiresAt time.Time
+}
+
+// NewCachedLinkRepository crée un CachedLinkRepository devant 'inner'.
+func NewCachedLinkRepository(inner LinkRepository, cfg LinkCacheConfig) *CachedLinkRepository {
+	if inner == nil {
+		panic("nil LinkRepository passed to NewCachedLinkRepository")
+	}
+	return &CachedLinkRepository{
+		LinkRepository: inner,
+		cfg:            cfg,
+		entries:        make(map[string]*list.Element),
+		order:          list.New(),
+	}
+}
+
+// GetLinkByShortCode retourne le lien depuis le cache s'il y est encore valide, sinon depuis
+// le repository sous-jacent, puis le met en cache. Un code inconnu retourne gorm.ErrRecordNotFound.
+// Chaque appel retourne une copie du lien : l'appelant peut la modifier sans altérer le cache.
+func (r *CachedLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	if link, found, ok := r.lookup(shortCode); ok {
+		if !found {
+			metrics.LinkCacheLookupsTotal.WithLabelValues(metrics.CacheNegativeHit).Inc()
+			return nil, gorm.ErrRecordNotFound
+		}
+		metrics.LinkCacheLookupsTotal.WithLabelValues(metrics.CacheHit).Inc()
+		return link, nil
+	}
+	metrics.LinkCacheLookupsTotal.WithLabelValues(metrics.CacheMiss).Inc()
+
+	generation := r.currentGeneration()
+	link, err := r.LinkRepository.GetLinkByShortCode(shortCode)
+	switch {
+	case err == nil:
+		r.store(shortCode, link, r.cfg.TTL, generation)
+	case errors.Is(err, gorm.ErrRecordNotFound) && r.cfg.NegativeTTL > 0:
+		r.store(shortCode, nil, r.cfg.NegativeTTL, generation)
+	}
+	return link, err
+}
+
+// CreateLink crée le lien puis invalide son code, qui pouvait être en cache comme code inconnu.
+func (r *CachedLinkRepository) CreateLink(link *models.Link) error {
+	err := r.LinkRepository.CreateLink(link)
+	r.Invalidate(link.ShortCode)
+	return err
+}
+
+// UpdateLongURL modifie l'URL de destination du lien puis invalide son entrée.
+func (r *CachedLinkRepository) UpdateLongURL(link *models.Link, longURL string) error {
+	err := r.LinkRepository.UpdateLongURL(link, longURL)
+	r.Invalidate(link.ShortCode)
+	return err
+}
+
+// SetOwner rattache le lien à une clé d'API puis invalide son entrée : le propriétaire en cache
+// ne doit plus donner accès au lien (voir LinkService.GetOwnedLink).
+func (r *CachedLinkRepository) SetOwner(link *models.Link, ownerID uint) error {
+	err := r.LinkRepository.SetOwner(link, ownerID)
+	r.Invalidate(link.ShortCode)
+	return err
+}
+
+// DeleteLink supprime le lien puis invalide son entrée.
+func (r *CachedLinkRepository) DeleteLink(link *models.Link) error {
+	err := r.LinkRepository.DeleteLink(link)
+	r.Invalidate(link.ShortCode)
+	return err
+}
+
+// MarkLinkExpired marque le lien comme expiré puis invalide son entrée.
+func (r *CachedLinkRepository) MarkLinkExpired(link *models.Link, at time.Time) (bool, error) {
+	marked, err := r.LinkRepository.MarkLinkExpired(link, at)
+	if marked {
+		r.Invalidate(link.ShortCode)
+	}
+	return marked, err
+}
+
+// ConsumeClick décompte un clic du budget du lien, puis reporte ce clic sur son entrée en cache
+// sans l'invalider : les redirections suivantes continuent d'être servies depuis le cache.
+// Un budget consommé par une autre instance n'est visible qu'après expiration de l'entrée.
+func (r *CachedLinkRepository) ConsumeClick(link *models.Link) (bool, error) {
+	consumed, err := r.LinkRepository.ConsumeClick(link)
+	if consumed {
+		r.mu.Lock()
+		if element, ok := r.entries[link.ShortCode]; ok {
+			if cached := element.Value.(*linkCacheEntry).link; cached != nil && cached.ID == link.ID {
+				cached.HumanClicks++
+			}
+		}
+		r.mu.Unlock()
+	}
+	return consumed, err
+}
+
+// Invalidate retire un code court du cache.
+func (r *CachedLinkRepository) Invalidate(shortCode string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	if element, ok := r.entries[shortCode]; ok {
+		r.remove(element)
+	}
+}
+
+// currentGeneration retourne le nombre d'invalidations effectuées.
+func (r *CachedLinkRepository) currentGeneration() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation
+}
+
+// lookup cherche un code court dans le cache. 'ok' vaut false si le code n'y est pas ou a expiré ;
+// sinon 'found' indique si le code correspond à un lien, retourné sous forme de copie.
+func (r *CachedLinkRepository) lookup(shortCode string) (link *models.Link, found, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	element, ok := r.entries[shortCode]
+	if !ok {
+		return nil, false, false
+	}
+	entry := element.Value.(*linkCacheEntry)
+	if !time.Now().Before(entry.expiresAt) {
+		r.remove(element)
+		return nil, false, false
+	}
+	r.order.MoveToFront(element)
+	if entry.link == nil {
+		return nil, false, true
+	}
+	copied := *entry.link
+	return &copied, true, true
+}
+
+// store met en cache une copie de 'link' (nil pour un code inconnu) pour la durée 'ttl',
+// en évinçant l'entrée la moins récemment utilisée si le cache est plein.
+// Rien n'est mis en cache si une invalidation a eu lieu depuis 'generation'.
+func (r *CachedLinkRepository) store(shortCode string, link *models.Link, ttl time.Duration, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return
+	}
+
+	var stored *models.Link
+	if link != nil {
+		copied := *link
+		stored = &copied
+	}
+	entry := &linkCacheEntry{shortCode: shortCode, link: stored, expiresAt: time.Now().Add(ttl)}
+
+	if element, ok := r.entries[shortCode]; ok {
+		element.Value = entry
+		r.order.MoveToFront(element)
+		return
+	}
+	r.entries[shortCode] = r.order.PushFront(entry)
+	for r.order.Len() > r.cfg.Size {
+		r.remove(r.order.Back())
+		metrics.LinkCacheEvictionsTotal.Inc()
+	}
+	metrics.LinkCacheEntries.Set(float64(r.order.Len()))
+}
+
+// remove retire un élément du cache. r.mu doit être verrouillé.
+func (r *CachedLinkRepository) remove(element *list.Element) {
+	r.order.Remove(element)
+	delete(r.entries, element.Value.(*linkCacheEntry).shortCode)
+	metrics.LinkCacheEntries.Set(float64(r.order.Len()))
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/axellelanca/urlshortener/internal/models"
 	"gorm.io/gorm"
@@ -13,8 +14,74 @@ import (
 type ClickRepository interface {
 	// CreateClick enregistre un nouvel événement de clic.
 	CreateClick(click *models.Click) error
+	// CreateClicks enregistre plusieurs clics en une seule transaction, avec des insertions multi-lignes.
+	CreateClicks(clicks []models.Click) error
 	// CountClicksByLinkID retourne le nombre de clics pour un lien donné.
-	CountClicksByLinkID(linkID uint) (int, error)
+	// Les clics de robots ne sont comptés que si includeBots vaut true, comme pour les agrégations suivantes.
+	CountClicksByLinkID(linkID uint, includeBots bool) (int, error)
+	// CountBotClicksByLinkID retourne le nombre de clics de robots pour un lien donné.
+	CountBotClicksByLinkID(linkID uint) (int, error)
+	// CountUniqueVisitorsByLinkID retourne le nombre d'empreintes de visiteurs distinctes pour un lien donné.
+	CountUniqueVisitorsByLinkID(linkID uint, includeBots bool) (int, error)
+	// CountClicksByInterval agrège les clics d'un lien par intervalles de temps de taille fixe.
+	CountClicksByInterval(linkID uint, from, to time.Time, bucketSeconds, offsetSeconds int64, includeBots bool) ([]ClickBucket, error)
+	// CountClicksByDimension retourne les valeurs les plus fréquentes d'une dimension des clics d'un lien.
+	CountClicksByDimension(linkID uint, dimension ClickDimension, limit int, includeBots bool) ([]ValueCount, error)
+}
+
+// ClickDimension désigne une colonne de la table "clicks" selon laquelle les clics peuvent être regroupés.
+type ClickDimension string
+
+// Dimensions de regroupement supportées par CountClicksByDimension.
+const (
+	DimensionReferrerHost ClickDimension = "referrer_host"
+	DimensionBrowser      ClickDimension = "browser"
+	DimensionOS           ClickDimension = "os"
+	DimensionDeviceType   ClickDimension = "device_type"
+)
+
+// validDimensions sert de liste blanche : la dimension est injectée telle quelle dans la requête SQL.
+var validDimensions = map[ClickDimension]struct{}{
+	DimensionReferrerHost: {},
+	DimensionBrowser:      {},
+	DimensionOS:           {},
+	DimensionDeviceType:   {},
+}
+
+// ValueCount associe une valeur d'une colonne de la table "clicks" à son nombre d'occurrences.
+type ValueCount struct {
+	Value string
+	Count int
+}
+
+// ClickBucket représente le nombre de clics et de visiteurs distincts enregistrés dans un intervalle de temps.
+// Bucket est l'index de l'intervalle : (secondes Unix + décalage) / taille de l'intervalle.
+type ClickBucket struct {
+	Bucket  int64
+	Count   int
+	Uniques int
+}
+
+// uniqueVisitorsExpr compte les empreintes distinctes, en ignorant les clics sans empreinte.
+const uniqueVisitorsExpr = "COUNT(DISTINCT NULLIF(visitor_hash, ''))"
+
+// clickInsertChunkSize limite le nombre de lignes par requête INSERT multi-lignes,
+// pour rester sous la limite de paramètres liés des bases de données.
+const clickInsertChunkSize = 500
+
+// Les horodatages des clics sont enregistrés en UTC : SQLite les stocke sous forme de texte,
+// qui n'est comparable avec les bornes des requêtes que dans un même fuseau.
+
+// epochExprs convertit la colonne 'timestamp' en secondes Unix (UTC), selon le dialecte SQL.
+// Elle ne sert qu'au calcul des intervalles : les filtres portent directement sur la colonne,
+// pour pouvoir utiliser l'index (link_id, timestamp).
+// Les horodatages MySQL sont interprétés en UTC : la session est configurée ainsi par database.Open.
+// Les fractions de seconde sont tronquées (FLOOR) : arrondies, elles feraient passer un clic
+// de la dernière seconde d'un intervalle dans le suivant.
+var epochExprs = map[string]string{
+	"sqlite":   "CAST(strftime('%s', timestamp) AS INTEGER)",
+	"postgres": "CAST(FLOOR(EXTRACT(EPOCH FROM timestamp)) AS BIGINT)",
+	"mysql":    "FLOOR(UNIX_TIMESTAMP(timestamp))",
 }
 
 // GormClickRepository est l'implémentation de ClickRepository utilisant GORM.
@@ -32,18 +99,116 @@ func NewClickRepository(db *gorm.DB) ClickRepository {
 
 // CreateClick utilise GORM pour créer une nouvelle entrée dans la table "clicks".
 func (r *GormClickRepository) CreateClick(click *models.Click) error {
+	click.Timestamp = click.Timestamp.UTC()
 	if err := r.db.Create(click).Error; err != nil {
 		return fmt.Errorf("failed to create click: %w", err)
 	}
 	return nil
 }
 
+// CreateClicks insère les clics par paquets de clickInsertChunkSize lignes.
+// GORM exécute l'ensemble dans une transaction : soit tous les clics sont enregistrés, soit aucun.
+func (r *GormClickRepository) CreateClicks(clicks []models.Click) error {
+	if len(clicks) == 0 {
+		return nil
+	}
+	for i := range clicks {
+		clicks[i].Timestamp = clicks[i].Timestamp.UTC()
+	}
+	if err := r.db.CreateInBatches(&clicks, clickInsertChunkSize).Error; err != nil {
+		return fmt.Errorf("failed to create %d clicks: %w", len(clicks), err)
+	}
+	return nil
+}
+
+// clicksOf retourne la requête de base sur les clics d'un lien, robots exclus sauf si includeBots vaut true.
+func (r *GormClickRepository) clicksOf(linkID uint, includeBots bool) *gorm.DB {
+	query := r.db.Model(&models.Click{}).Where("link_id = ?", linkID)
+	if !includeBots {
+		query = query.Where("is_bot = ?", false)
+	}
+	return query
+}
+
 // CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
 // Cette méthode est utilisée pour fournir des statistiques pour une URL courte.
-func (r *GormClickRepository) CountClicksByLinkID(linkID uint) (int, error) {
+func (r *GormClickRepository) CountClicksByLinkID(linkID uint, includeBots bool) (int, error) {
 	var count int64 // GORM retourne un int64 pour les décomptes
-	if err := r.db.Model(&models.Click{}).Where("link_id = ?", linkID).Count(&count).Error; err != nil {
+	if err := r.clicksOf(linkID, includeBots).Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count clicks for link %d: %w", linkID, err)
 	}
 	return int(count), nil // Conversion de int64 vers int
 }
+
+// CountBotClicksByLinkID compte les clics attribués à des robots pour un ID de lien donné.
+func (r *GormClickRepository) CountBotClicksByLinkID(linkID uint) (int, error) {
+	var count int64
+	if err := r.db.Model(&models.Click{}).Where("link_id = ? AND is_bot = ?", linkID, true).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count bot clicks for link %d: %w", linkID, err)
+	}
+	return int(count), nil
+}
+
+// CountUniqueVisitorsByLinkID compte les empreintes de visiteurs distinctes d'un lien.
+// Les empreintes étant salées différemment chaque jour, un visiteur revenant plusieurs jours
+// est compté une fois par jour : le résultat correspond à la somme des visiteurs uniques quotidiens.
+func (r *GormClickRepository) CountUniqueVisitorsByLinkID(linkID uint, includeBots bool) (int, error) {
+	var count int64
+	if err := r.clicksOf(linkID, includeBots).Select(uniqueVisitorsExpr).Scan(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unique visitors for link %d: %w", linkID, err)
+	}
+	return int(count), nil
+}
+
+// CountClicksByInterval regroupe les clics d'un lien survenus dans [from, to) par intervalles
+// de 'bucketSeconds' secondes. 'offsetSeconds' décale l'origine des intervalles (par exemple
+// pour aligner les semaines sur le lundi). Seuls les intervalles non vides sont retournés.
+func (r *GormClickRepository) CountClicksByInterval(linkID uint, from, to time.Time, bucketSeconds, offsetSeconds int64, includeBots bool) ([]ClickBucket, error) {
+	if bucketSeconds <= 0 {
+		return nil, fmt.Errorf("invalid bucket size: %d", bucketSeconds)
+	}
+
+	dialect := r.db.Dialector.Name()
+	epochExpr, ok := epochExprs[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unsupported database dialect for click aggregation: %s", dialect)
+	}
+	// Division entière : '/' donnerait un décimal avec MySQL.
+	divide := "/"
+	if dialect == "mysql" {
+		divide = "DIV"
+	}
+	bucketExpr := fmt.Sprintf("(%s + %d) %s %d", epochExpr, offsetSeconds, divide, bucketSeconds)
+
+	var buckets []ClickBucket
+	err := r.clicksOf(linkID, includeBots).
+		Select(bucketExpr+" AS bucket, COUNT(*) AS count, "+uniqueVisitorsExpr+" AS uniques").
+		Where("timestamp >= ? AND timestamp < ?", from.UTC(), to.UTC()).
+		Group("bucket").
+		Order("bucket").
+		Scan(&buckets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks for link %d: %w", linkID, err)
+	}
+	return buckets, nil
+}
+
+// CountClicksByDimension retourne les 'limit' valeurs de la dimension ayant le plus de clics pour un lien.
+// Les clics sans valeur (ainsi que ceux enregistrés avant l'apparition de la colonne) sont regroupés sous une valeur vide.
+func (r *GormClickRepository) CountClicksByDimension(linkID uint, dimension ClickDimension, limit int, includeBots bool) ([]ValueCount, error) {
+	if _, ok := validDimensions[dimension]; !ok {
+		return nil, fmt.Errorf("invalid click dimension: %s", dimension)
+	}
+
+	var counts []ValueCount
+	err := r.clicksOf(linkID, includeBots).
+		Select(fmt.Sprintf("COALESCE(%s, '') AS value, COUNT(*) AS count", dimension)).
+		Group("value").
+		Order("count DESC").Order("value").
+		Limit(limit).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate clicks by %s for link %d: %w", dimension, linkID, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// createTestClicks enregistre les clics en une insertion groupée et échoue le test en cas d'erreur.
+func createTestClicks(t *testing.T, repo ClickRepository, clicks []models.Click) {
+	t.Helper()
+	if err := repo.CreateClicks(clicks); err != nil {
+		t.Fatalf("CreateClicks: %v", err)
+	}
+}
+
+func TestCreateClicksAndCounts(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		link := createTestLink(t, NewLinkRepository(db), &models.Link{ShortCode: "counted"})
+		other := createTestLink(t, NewLinkRepository(db), &models.Link{ShortCode: "other"})
+		repo := NewClickRepository(db)
+		now := time.Now()
+
+		single := &models.Click{LinkID: link.ID, Timestamp: now, VisitorHash: "visitor-a"}
+		if err := repo.CreateClick(single); err != nil {
+			t.Fatalf("CreateClick: %v", err)
+		}
+		if single.ID == 0 {
+			t.Error("CreateClick did not set the click ID")
+		}
+
+		// Plus de clics que clickInsertChunkSize : l'insertion est découpée en plusieurs requêtes.
+		clicks := make([]models.Click, 0, clickInsertChunkSize+10)
+		for range clickInsertChunkSize {
+			clicks = append(clicks, models.Click{LinkID: other.ID, Timestamp: now})
+		}
+		clicks = append(clicks,
+			models.Click{LinkID: link.ID, Timestamp: now, VisitorHash: "visitor-a"},
+			models.Click{LinkID: link.ID, Timestamp: now, VisitorHash: "visitor-b"},
+			models.Click{LinkID: link.ID, Timestamp: now}, // Sans empreinte : ne compte pas dans les visiteurs
+			models.Click{LinkID: link.ID, Timestamp: now, IsBot: true, VisitorHash: "bot"},
+			models.Click{LinkID: link.ID, Timestamp: now, IsBot: true, VisitorHash: "bot"},
+		)
+		createTestClicks(t, repo, clicks)
+		if err := repo.CreateClicks(nil); err != nil {
+			t.Errorf("CreateClicks(nil): %v", err)
+		}
+
+		counts := []struct {
+			name string
+			got  func() (int, error)
+			want int
+		}{
+			{"humans", func() (int, error) { return repo.CountClicksByLinkID(link.ID, false) }, 4},
+			{"all", func() (int, error) { return repo.CountClicksByLinkID(link.ID, true) }, 6},
+			{"bots", func() (int, error) { return repo.CountBotClicksByLinkID(link.ID) }, 2},
+			{"human visitors", func() (int, error) { return repo.CountUniqueVisitorsByLinkID(link.ID, false) }, 2},
+			{"all visitors", func() (int, error) { return repo.CountUniqueVisitorsByLinkID(link.ID, true) }, 3},
+			{"other link", func() (int, error) { return repo.CountClicksByLinkID(other.ID, true) }, clickInsertChunkSize},
+		}
+		for _, c := range counts {
+			got, err := c.got()
+			if err != nil {
+				t.Fatalf("%s: %v", c.name, err)
+			}
+			if got != c.want {
+				t.Errorf("%s = %d, want %d", c.name, got, c.want)
+			}
+		}
+	})
+}
+
+func TestCountClicksByInterval(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		link := createTestLink(t, NewLinkRepository(db), &models.Link{ShortCode: "series"})
+		repo := NewClickRepository(db)
+		paris := time.FixedZone("UTC+2", 2*3600)
+		newYork := time.FixedZone("UTC-5", -5*3600)
+		day := func(y int, m time.Month, d int) int64 { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / 86400 }
+
+		// Les horodatages des clics en fuseau UTC+2 et UTC-5 tombent un autre jour en heure locale qu'en UTC.
+		createTestClicks(t, repo, []models.Click{
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 5, 23, 59, 59, 0, time.UTC)},                  // Avant 'from'
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 6, 10, 15, 0, 0, time.UTC), VisitorHash: "a"}, // Lundi 6
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 5, 23, 30, 0, 0, newYork), VisitorHash: "a"},  // Lundi 6, 04:30 UTC
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 8, 0, 30, 0, 0, paris), VisitorHash: "b"},     // Mardi 7, 22:30 UTC
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC), IsBot: true},        // Robot
+			{LinkID: link.ID, Timestamp: time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)},                     // 'to' est exclu
+		})
+
+		// Bornes exprimées dans un autre fuseau : seul l'instant compte.
+		from := time.Date(2025, 1, 6, 2, 0, 0, 0, paris) // Lundi 6, 00:00 UTC
+		to := time.Date(2025, 1, 8, 0, 0, 0, 0, time.UTC)
+
+		buckets, err := repo.CountClicksByInterval(link.ID, from, to, 86400, 0, false)
+		if err != nil {
+			t.Fatalf("CountClicksByInterval: %v", err)
+		}
+		want := []ClickBucket{
+			{Bucket: day(2025, 1, 6), Count: 2, Uniques: 1},
+			{Bucket: day(2025, 1, 7), Count: 1, Uniques: 1},
+		}
+		if !reflect.DeepEqual(buckets, want) {
+			t.Errorf("daily buckets = %+v, want %+v", buckets, want)
+		}
+
+		buckets, err = repo.CountClicksByInterval(link.ID, from, to, 86400, 0, true)
+		if err != nil {
+			t.Fatalf("CountClicksByInterval (bots included): %v", err)
+		}
+		if len(buckets) != 2 || buckets[1].Count != 2 {
+			t.Errorf("daily buckets with bots = %+v, want 2 clicks on the 7th", buckets)
+		}
+
+		// Semaines alignées sur le lundi : le 1er janvier 1970 était un jeudi.
+		const week, mondayOffset = 7 * 86400, 3 * 86400
+		sunday := time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC)
+		buckets, err = repo.CountClicksByInterval(link.ID, sunday, to, week, mondayOffset, false)
+		if err != nil {
+			t.Fatalf("CountClicksByInterval (weeks): %v", err)
+		}
+		mondayBucket := (time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC).Unix() + mondayOffset) / week
+		want = []ClickBucket{
+			{Bucket: mondayBucket - 1, Count: 1, Uniques: 0},
+			{Bucket: mondayBucket, Count: 3, Uniques: 2},
+		}
+		if !reflect.DeepEqual(buckets, want) {
+			t.Errorf("weekly buckets = %+v, want %+v", buckets, want)
+		}
+
+		if _, err := repo.CountClicksByInterval(link.ID, from, to, 0, 0, false); err == nil {
+			t.Error("CountClicksByInterval with a zero bucket size did not fail")
+		}
+	})
+}
+
+func TestCountClicksByDimension(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		link := createTestLink(t, NewLinkRepository(db), &models.Link{ShortCode: "browsers"})
+		repo := NewClickRepository(db)
+		now := time.Now()
+		var clicks []models.Click
+		for browser, n := range map[string]int{"Chrome": 3, "Firefox": 2, "Safari": 2, "": 1} {
+			for range n {
+				clicks = append(clicks, models.Click{LinkID: link.ID, Timestamp: now, Browser: browser})
+			}
+		}
+		clicks = append(clicks,
+			models.Click{LinkID: link.ID, Timestamp: now, Browser: "Chrome", IsBot: true},
+			models.Click{LinkID: link.ID, Timestamp: now, Browser: "Chrome", IsBot: true},
+		)
+		createTestClicks(t, repo, clicks)
+
+		tests := []struct {
+			name        string
+			limit       int
+			includeBots bool
+			want        []ValueCount
+		}{
+			{"top 3, ties by value", 3, false, []ValueCount{{"Chrome", 3}, {"Firefox", 2}, {"Safari", 2}}},
+			{"empty values grouped", 10, false, []ValueCount{{"Chrome", 3}, {"Firefox", 2}, {"Safari", 2}, {"", 1}}},
+			{"bots included", 1, true, []ValueCount{{"Chrome", 5}}},
+		}
+		for _, tt := range tests {
+			got, err := repo.CountClicksByDimension(link.ID, DimensionBrowser, tt.limit, tt.includeBots)
+			if err != nil {
+				t.Fatalf("%s: %v", tt.name, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %+v, want %+v", tt.name, got, tt.want)
+			}
+		}
+
+		if _, err := repo.CountClicksByDimension(link.ID, ClickDimension("ip_address; DROP TABLE clicks"), 10, false); err == nil {
+			t.Error("CountClicksByDimension with an unknown dimension did not fail")
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// LinkCheckRepository définit l'accès aux vérifications du moniteur et à l'état de santé des liens.
+type LinkCheckRepository interface {
+	// RecordCheck enregistre une vérification et met à jour l'état de santé du lien.
+	RecordCheck(link *models.Link, check *models.LinkCheck) error
+	// GetHealthState relit en base l'état de santé courant d'un lien.
+	GetHealthState(linkID uint) (*HealthState, error)
+	// ListRecentChecks retourne les 'limit' dernières vérifications d'un lien, de la plus récente à la plus ancienne.
+	ListRecentChecks(linkID uint, limit int) ([]models.LinkCheck, error)
+	// CountChecks compte les vérifications d'un lien dans [from, to), ainsi que celles réussies.
+	CountChecks(linkID uint, from, to time.Time) (total, healthy int64, err error)
+	// ListLinksByHealth retourne les liens dans l'état de santé 'status', du plus anciennement dans cet état au plus récent.
+	ListLinksByHealth(status string) ([]models.Link, error)
+	// ListLatestChecksByHealth retourne la dernière vérification de chaque lien dans l'état de santé 'status',
+	// indexée par ID de lien. Les liens dont l'historique a été purgé n'y figurent pas.
+	ListLatestChecksByHealth(status string) (map[uint]models.LinkCheck, error)
+	// DeleteChecksBefore supprime les vérifications antérieures à 'before' et retourne leur nombre.
+	DeleteChecksBefore(before time.Time) (int64, error)
+}
+
+// HealthState est l'état de santé courant d'un lien, tel qu'enregistré par le moniteur.
+type HealthState struct {
+	HealthStatus    string
+	LastCheckedAt   *time.Time
+	HealthChangedAt *time.Time
+}
+
+// GormLinkCheckRepository est l'implémentation de LinkCheckRepository utilisant GORM.
+type GormLinkCheckRepository struct {
+	db *gorm.DB
+}
+
+// NewLinkCheckRepository crée une nouvelle instance de GormLinkCheckRepository.
+func NewLinkCheckRepository(db *gorm.DB) LinkCheckRepository {
+	if db == nil {
+		panic("nil *gorm.DB passed to NewLinkCheckRepository")
+	}
+	return &GormLinkCheckRepository{db: db}
+}
+
+// RecordCheck insère la vérification et met à jour les colonnes de santé du lien dans une même
+// transaction. HealthChangedAt n'est modifiée que si l'état diffère de link.HealthStatus.
+// Les champs de santé de 'link' sont mis à jour en cas de succès.
+// Retourne gorm.ErrRecordNotFound si le lien a été supprimé entre-temps.
+func (r *GormLinkCheckRepository) RecordCheck(link *models.Link, check *models.LinkCheck) error {
+	// En UTC, comme les clics : sous SQLite, l'ordre des dates stockées en texte (GetAllLinks)
+	// n'est celui des instants que si elles partagent le même décalage horaire.
+	check.CheckedAt = check.CheckedAt.UTC()
+	status := models.HealthDown
+	if check.Healthy {
+		status = models.HealthUp
+	}
+	updates := map[string]any{"health_status": status, "last_checked_at": check.CheckedAt}
+	changed := status != link.HealthStatus
+	if changed {
+		updates["health_changed_at"] = check.CheckedAt
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Link{}).Where("id = ?", link.ID).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Create(check).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record check for link %d: %w", link.ID, err)
+	}
+
+	link.HealthStatus = status
+	link.LastCheckedAt = &check.CheckedAt
+	if changed {
+		link.HealthChangedAt = &check.CheckedAt
+	}
+	return nil
+}
+
+// GetHealthState lit les colonnes de santé du lien directement en base, sans passer par le cache des liens.
+func (r *GormLinkCheckRepository) GetHealthState(linkID uint) (*HealthState, error) {
+	var state HealthState
+	err := r.db.Model(&models.Link{}).
+		Select("health_status", "last_checked_at", "health_changed_at").
+		Where("id = ?", linkID).
+		Take(&state).Error
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+// ListRecentChecks retourne les dernières vérifications d'un lien.
+func (r *GormLinkCheckRepository) ListRecentChecks(linkID uint, limit int) ([]models.LinkCheck, error) {
+	var checks []models.LinkCheck
+	err := r.db.Where("link_id = ?", linkID).
+		Order("checked_at DESC").Order("id DESC").
+		Limit(limit).
+		Find(&checks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list checks for link %d: %w", linkID, err)
+	}
+	return checks, nil
+}
+
+// CountChecks compte les vérifications d'un lien sur la période [from, to[.
+// Les bornes sont converties en UTC, comme les dates enregistrées par RecordCheck.
+func (r *GormLinkCheckRepository) CountChecks(linkID uint, from, to time.Time) (total, healthy int64, err error) {
+	var counts struct {
+		Total   int64
+		Healthy int64
+	}
+	err = r.db.Model(&models.LinkCheck{}).
+		Select("COUNT(*) AS total, COALESCE(SUM(CASE WHEN healthy THEN 1 ELSE 0 END), 0) AS healthy").
+		Where("link_id = ? AND checked_at >= ? AND checked_at < ?", linkID, from.UTC(), to.UTC()).
+		Take(&counts).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count checks for link %d: %w", linkID, err)
+	}
+	return counts.Total, counts.Healthy, nil
+}
+
+// ListLinksByHealth retourne les liens dans un état de santé donné.
+func (r *GormLinkCheckRepository) ListLinksByHealth(status string) ([]models.Link, error) {
+	var links []models.Link
+	err := r.db.Where("health_status = ?", status).
+		Order("health_changed_at ASC").Order("id ASC").
+		Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s links: %w", status, err)
+	}
+	return links, nil
+}
+
+// ListLatestChecksByHealth retourne la dernière vérification des liens dans un état de santé donné,
+// en une seule requête : ROW_NUMBER() numérote les vérifications de chaque lien de la plus récente
+// à la plus ancienne, et seule la première est conservée.
+func (r *GormLinkCheckRepository) ListLatestChecksByHealth(status string) (map[uint]models.LinkCheck, error) {
+	ranked := r.db.Model(&models.LinkCheck{}).
+		Select("link_checks.*, ROW_NUMBER() OVER (PARTITION BY link_checks.link_id ORDER BY link_checks.checked_at DESC, link_checks.id DESC) AS row_num").
+		Joins("JOIN links ON links.id = link_checks.link_id").
+		Where("links.health_status = ?", status)
+
+	var checks []models.LinkCheck
+	if err := r.db.Table("(?) AS latest", ranked).Where("row_num = 1").Find(&checks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list latest checks of %s links: %w", status, err)
+	}
+	latest := make(map[uint]models.LinkCheck, len(checks))
+	for _, check := range checks {
+		latest[check.LinkID] = check
+	}
+	return latest, nil
+}
+
+// DeleteChecksBefore supprime les vérifications plus anciennes que 'before', converti en UTC
+// comme les dates enregistrées par RecordCheck.
+func (r *GormLinkCheckRepository) DeleteChecksBefore(before time.Time) (int64, error) {
+	result := r.db.Where("checked_at < ?", before.UTC()).Delete(&models.LinkCheck{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete checks before %s: %w", before.Format(time.RFC3339), result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestCountAndDeleteChecksNonUTCBounds(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		linkRepo := NewLinkRepository(db)
+		checkRepo := NewLinkCheckRepository(db)
+		link := createTestLink(t, linkRepo, &models.Link{ShortCode: "checked"})
+
+		base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		for i, healthy := range []bool{true, false, true} {
+			check := &models.LinkCheck{LinkID: link.ID, CheckedAt: base.Add(time.Duration(i) * time.Hour), Healthy: healthy}
+			if err := checkRepo.RecordCheck(link, check); err != nil {
+				t.Fatalf("RecordCheck: %v", err)
+			}
+		}
+
+		// Bornes exprimées avec un décalage de +05:00 : sous SQLite, comparées telles quelles aux dates
+		// UTC enregistrées, "16:00+05:00" (11:00 UTC) serait postérieure à "12:00+00:00".
+		zone := time.FixedZone("UTC+5", 5*60*60)
+		total, healthy, err := checkRepo.CountChecks(link.ID, base.Add(-time.Hour).In(zone), base.Add(90*time.Minute).In(zone))
+		if err != nil {
+			t.Fatalf("CountChecks: %v", err)
+		}
+		if total != 2 || healthy != 1 {
+			t.Errorf("CountChecks = %d total, %d healthy, want 2 and 1", total, healthy)
+		}
+
+		deleted, err := checkRepo.DeleteChecksBefore(base.Add(90 * time.Minute).In(zone))
+		if err != nil {
+			t.Fatalf("DeleteChecksBefore: %v", err)
+		}
+		if deleted != 2 {
+			t.Errorf("DeleteChecksBefore deleted %d checks, want 2", deleted)
+		}
+		checks, err := checkRepo.ListRecentChecks(link.ID, 10)
+		if err != nil {
+			t.Fatalf("ListRecentChecks: %v", err)
+		}
+		if len(checks) != 1 || !checks[0].CheckedAt.Equal(base.Add(2*time.Hour)) {
+			t.Errorf("remaining checks = %+v, want the check of %v", checks, base.Add(2*time.Hour))
+		}
+	})
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/axellelanca/urlshortener/internal/models"
 	"gorm.io/gorm"
@@ -14,12 +16,50 @@ type LinkRepository interface {
 	CreateLink(link *models.Link) error
 	// GetLinkByShortCode récupère un lien à partir de son code court.
 	GetLinkByShortCode(shortCode string) (*models.Link, error)
-	// GetAllLinks retourne tous les liens stockés.
+	// GetAllLinks retourne tous les liens stockés, les jamais vérifiés d'abord, puis du plus
+	// anciennement vérifié au plus récemment vérifié.
 	GetAllLinks() ([]models.Link, error)
-	// CountClicksByLinkID retourne le nombre total de clics pour un lien donné.
-	CountClicksByLinkID(linkID uint) (int, error)
+	// CountClicksByLinkID retourne le nombre total de clics pour un lien donné,
+	// clics de robots compris uniquement si includeBots vaut true.
+	CountClicksByLinkID(linkID uint, includeBots bool) (int, error)
+	// ListLinks retourne une page de liens correspondant au filtre, ainsi que le nombre total de résultats.
+	ListLinks(filter LinkFilter) ([]models.Link, int64, error)
+	// UpdateLongURL modifie l'URL de destination d'un lien existant.
+	// Retourne gorm.ErrRecordNotFound si le lien n'existe plus.
+	UpdateLongURL(link *models.Link, longURL string) error
+	// SetOwner rattache un lien existant à la clé d'API 'ownerID'.
+	// Retourne gorm.ErrRecordNotFound si le lien n'existe plus.
+	SetOwner(link *models.Link, ownerID uint) error
+	// DeleteLink supprime un lien ainsi que ses clics et ses vérifications.
+	DeleteLink(link *models.Link) error
+	// ListNewlyExpiredLinks retourne les liens expirés à l'instant 'now' qui n'ont pas encore été marqués par MarkLinkExpired.
+	ListNewlyExpiredLinks(now time.Time) ([]models.Link, error)
+	// MarkLinkExpired enregistre la date à laquelle un lien a été constaté expiré.
+	// Retourne false si le lien était déjà marqué (ou n'existe plus).
+	MarkLinkExpired(link *models.Link, at time.Time) (bool, error)
+	// ConsumeClick décompte un clic du budget MaxClicks d'un lien, atomiquement.
+	// Retourne false si le budget est déjà consommé (ou si le lien n'existe plus).
+	ConsumeClick(link *models.Link) (bool, error)
+	// AssignUnownedLinks rattache à la clé d'API 'ownerID' tous les liens sans propriétaire.
+	// Retourne le nombre de liens rattachés.
+	AssignUnownedLinks(ownerID uint) (int64, error)
 }
 
+// LinkFilter regroupe les critères de recherche et de pagination utilisés par ListLinks.
+// Les champs laissés à leur valeur zéro ne filtrent pas les résultats.
+type LinkFilter struct {
+	OwnerID       *uint      // Liens appartenant à cette clé d'API uniquement
+	CreatedAfter  *time.Time // Liens créés à partir de cette date (incluse)
+	CreatedBefore *time.Time // Liens créés avant cette date (exclue)
+	URLContains   string     // Sous-chaîne recherchée dans LongURL
+	Offset        int        // Nombre de liens à ignorer
+	Limit         int        // Nombre maximal de liens retournés (0 = pas de limite)
+}
+
+// likeEscaper protège les caractères spéciaux de LIKE avec '!' comme caractère d'échappement,
+// accepté de la même manière par SQLite, PostgreSQL et MySQL.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
 // GormLinkRepository est l'implémentation de LinkRepository utilisant GORM.
 type GormLinkRepository struct {
 	db *gorm.DB
@@ -34,7 +74,14 @@ func NewLinkRepository(db *gorm.DB) LinkRepository {
 }
 
 // CreateLink insère un nouveau lien dans la base de données.
+// Les dates de création et d'expiration sont enregistrées en UTC, quel que soit le décalage du serveur
+// ou du client : sous SQLite, ListLinks et ListNewlyExpiredLinks les comparent sous forme de texte.
 func (r *GormLinkRepository) CreateLink(link *models.Link) error {
+	link.CreatedAt = link.CreatedAt.UTC()
+	if link.ExpiresAt != nil {
+		expiresAt := link.ExpiresAt.UTC()
+		link.ExpiresAt = &expiresAt
+	}
 	if err := r.db.Create(link).Error; err != nil {
 		return fmt.Errorf("failed to create link: %w", err)
 	}
@@ -50,20 +97,172 @@ func (r *GormLinkRepository) GetLinkByShortCode(shortCode string) (*models.Link,
 	return &link, nil
 }
 
-// GetAllLinks retourne tous les liens présents dans la base.
+// GetAllLinks retourne tous les liens présents dans la base, dans l'ordre de vérification du moniteur :
+// les liens jamais vérifiés d'abord, puis par date de dernière vérification croissante. Un passage
+// interrompu par son délai reprend ainsi, au passage suivant, par les liens qu'il n'a pas vérifiés.
 func (r *GormLinkRepository) GetAllLinks() ([]models.Link, error) {
 	var links []models.Link
-	if err := r.db.Find(&links).Error; err != nil {
+	// "last_checked_at IS NOT NULL" vaut faux (0) pour les liens jamais vérifiés, sur les trois dialectes.
+	err := r.db.Order("last_checked_at IS NOT NULL").Order("last_checked_at").Order("id").Find(&links).Error
+	if err != nil {
 		return nil, fmt.Errorf("failed to fetch links: %w", err)
 	}
 	return links, nil
 }
 
 // CountClicksByLinkID compte le nombre total de clics pour un ID de lien donné.
-func (r *GormLinkRepository) CountClicksByLinkID(linkID uint) (int, error) {
+func (r *GormLinkRepository) CountClicksByLinkID(linkID uint, includeBots bool) (int, error) {
 	var count int64 // GORM retourne un int64 pour les comptes
-	if err := r.db.Model(&models.Click{}).Where("link_id = ?", linkID).Count(&count).Error; err != nil {
+	query := r.db.Model(&models.Click{}).Where("link_id = ?", linkID)
+	if !includeBots {
+		query = query.Where("is_bot = ?", false)
+	}
+	if err := query.Count(&count).Error; err != nil {
 		return 0, fmt.Errorf("failed to count clicks for link %d: %w", linkID, err)
 	}
 	return int(count), nil
 }
+
+// ListLinks retourne les liens correspondant au filtre, du plus récent au plus ancien,
+// ainsi que le nombre total de liens correspondants (avant pagination).
+func (r *GormLinkRepository) ListLinks(filter LinkFilter) ([]models.Link, int64, error) {
+	query := r.db.Model(&models.Link{})
+	if filter.OwnerID != nil {
+		query = query.Where("owner_id = ?", *filter.OwnerID)
+	}
+	// Bornes en UTC, comme les dates de création enregistrées par CreateLink.
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if filter.URLContains != "" {
+		query = query.Where("long_url LIKE ? ESCAPE '!'", "%"+likeEscaper.Replace(filter.URLContains)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count links: %w", err)
+	}
+
+	var links []models.Link
+	query = query.Order("created_at DESC").Order("id DESC").Offset(filter.Offset)
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if err := query.Find(&links).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list links: %w", err)
+	}
+	return links, total, nil
+}
+
+// UpdateLongURL modifie uniquement la colonne long_url : le lien peut provenir du cache et porter
+// des valeurs périmées dans ses autres colonnes. Un lien supprimé entre-temps n'est pas recréé.
+// En cas de succès, link.LongURL est mis à jour.
+func (r *GormLinkRepository) UpdateLongURL(link *models.Link, longURL string) error {
+	if err := r.updateColumn(link, "long_url", longURL); err != nil {
+		return err
+	}
+	link.LongURL = longURL
+	return nil
+}
+
+// SetOwner modifie uniquement la colonne owner_id, comme UpdateLongURL.
+// En cas de succès, link.OwnerID est mis à jour.
+func (r *GormLinkRepository) SetOwner(link *models.Link, ownerID uint) error {
+	if err := r.updateColumn(link, "owner_id", ownerID); err != nil {
+		return err
+	}
+	link.OwnerID = &ownerID
+	return nil
+}
+
+// updateColumn modifie une colonne d'un lien existant.
+// Retourne gorm.ErrRecordNotFound si aucune ligne ne correspond à son ID.
+func (r *GormLinkRepository) updateColumn(link *models.Link, column string, value any) error {
+	result := r.db.Model(&models.Link{}).Where("id = ?", link.ID).Update(column, value)
+	if result.Error != nil {
+		return fmt.Errorf("failed to update %s of link %d: %w", column, link.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// DeleteLink supprime un lien, ses clics et ses vérifications dans une même transaction.
+func (r *GormLinkRepository) DeleteLink(link *models.Link) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("link_id = ?", link.ID).Delete(&models.Click{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("link_id = ?", link.ID).Delete(&models.LinkCheck{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Link{}, link.ID).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete link %d: %w", link.ID, err)
+	}
+	return nil
+}
+
+// ListNewlyExpiredLinks retourne les liens non encore marqués dont la date d'expiration est dépassée
+// ou dont le budget de clics est consommé (voir ConsumeClick). 'now' est converti en UTC,
+// comme les dates d'expiration enregistrées par CreateLink.
+func (r *GormLinkRepository) ListNewlyExpiredLinks(now time.Time) ([]models.Link, error) {
+	var links []models.Link
+	err := r.db.Where("expired_at IS NULL").
+		Where(r.db.Where("expires_at IS NOT NULL AND expires_at <= ?", now.UTC()).
+			Or("max_clicks > 0 AND human_clicks >= max_clicks")).
+		Order("id ASC").
+		Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expired links: %w", err)
+	}
+	return links, nil
+}
+
+// MarkLinkExpired renseigne ExpiredAt si elle ne l'est pas déjà. Une seule instance peut
+// marquer un lien : c'est elle qui émet l'événement d'expiration.
+func (r *GormLinkRepository) MarkLinkExpired(link *models.Link, at time.Time) (bool, error) {
+	at = at.UTC()
+	result := r.db.Model(&models.Link{}).
+		Where("id = ? AND expired_at IS NULL", link.ID).
+		Update("expired_at", at)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to mark link %d as expired: %w", link.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	link.ExpiredAt = &at
+	return true, nil
+}
+
+// ConsumeClick incrémente HumanClicks par un UPDATE conditionnel : deux redirections simultanées
+// ne peuvent pas consommer la même unité du budget, quel que soit le nombre d'instances.
+// En cas de succès, link.HumanClicks est incrémenté.
+func (r *GormLinkRepository) ConsumeClick(link *models.Link) (bool, error) {
+	result := r.db.Model(&models.Link{}).
+		Where("id = ? AND human_clicks < max_clicks", link.ID).
+		Update("human_clicks", gorm.Expr("human_clicks + 1"))
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to consume click for link %d: %w", link.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	link.HumanClicks++
+	return true, nil
+}
+
+// AssignUnownedLinks renseigne owner_id sur tous les liens qui n'en ont pas.
+func (r *GormLinkRepository) AssignUnownedLinks(ownerID uint) (int64, error) {
+	result := r.db.Model(&models.Link{}).Where("owner_id IS NULL").Update("owner_id", ownerID)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to assign unowned links: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,327 @@
+package repository
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// createTestLink crée un lien et échoue le test en cas d'erreur.
+func createTestLink(t *testing.T, repo LinkRepository, link *models.Link) *models.Link {
+	t.Helper()
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+	if link.LongURL == "" {
+		link.LongURL = "https://example.com/" + link.ShortCode
+	}
+	if err := repo.CreateLink(link); err != nil {
+		t.Fatalf("CreateLink(%s): %v", link.ShortCode, err)
+	}
+	return link
+}
+
+func TestCreateLink(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		owner := uint(7)
+		created := createTestLink(t, repo, &models.Link{ShortCode: "abc123", ExpiresAt: &expiresAt, MaxClicks: 10, OwnerID: &owner})
+		if created.ID == 0 {
+			t.Fatal("CreateLink did not set the link ID")
+		}
+
+		got, err := repo.GetLinkByShortCode("abc123")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if got.ID != created.ID || got.LongURL != created.LongURL || got.MaxClicks != 10 {
+			t.Errorf("GetLinkByShortCode = %+v, want %+v", got, created)
+		}
+		if got.ExpiresAt == nil || !got.ExpiresAt.Equal(expiresAt) {
+			t.Errorf("ExpiresAt = %v, want %v", got.ExpiresAt, expiresAt)
+		}
+		if got.OwnerID == nil || *got.OwnerID != owner {
+			t.Errorf("OwnerID = %v, want %d", got.OwnerID, owner)
+		}
+		if got.HealthStatus != models.HealthUnknown {
+			t.Errorf("HealthStatus = %q, want %q", got.HealthStatus, models.HealthUnknown)
+		}
+
+		err = repo.CreateLink(&models.Link{ShortCode: "abc123", LongURL: "https://example.com/other", CreatedAt: time.Now()})
+		if !errors.Is(err, gorm.ErrDuplicatedKey) {
+			t.Errorf("CreateLink with a duplicate short code error = %v, want gorm.ErrDuplicatedKey", err)
+		}
+		if _, err := repo.GetLinkByShortCode("missing"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCode(missing) error = %v, want gorm.ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestListLinks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		owner := uint(1)
+		base := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+		createTestLink(t, repo, &models.Link{ShortCode: "first", LongURL: "https://example.com/100%_off", OwnerID: &owner, CreatedAt: base})
+		createTestLink(t, repo, &models.Link{ShortCode: "second", LongURL: "https://example.com/1000_off", OwnerID: &owner, CreatedAt: base.Add(time.Hour)})
+		createTestLink(t, repo, &models.Link{ShortCode: "third", OwnerID: &owner, CreatedAt: base.Add(2 * time.Hour)})
+		createTestLink(t, repo, &models.Link{ShortCode: "other", CreatedAt: base.Add(3 * time.Hour)})
+		// Créé avec le décalage horaire du serveur.
+		createTestLink(t, repo, &models.Link{ShortCode: "local", CreatedAt: base.Add(90 * time.Minute).In(time.FixedZone("UTC+2", 2*60*60))})
+
+		links, total, err := repo.ListLinks(LinkFilter{OwnerID: &owner, Limit: 2})
+		if err != nil {
+			t.Fatalf("ListLinks: %v", err)
+		}
+		if total != 3 || len(links) != 2 || links[0].ShortCode != "third" || links[1].ShortCode != "second" {
+			t.Errorf("ListLinks(owner, limit 2) = %d links %v of %d, want [third second] of 3", len(links), shortCodes(links), total)
+		}
+
+		// '%' et '_' sont recherchés littéralement.
+		links, total, err = repo.ListLinks(LinkFilter{URLContains: "100%_"})
+		if err != nil {
+			t.Fatalf("ListLinks: %v", err)
+		}
+		if total != 1 || len(links) != 1 || links[0].ShortCode != "first" {
+			t.Errorf("ListLinks(url contains '100%%_') = %v, want [first]", shortCodes(links))
+		}
+
+		// Bornes fournies avec le décalage du client : sous SQLite, les dates sont comparées sous forme de texte.
+		after := base.Add(time.Hour).In(time.FixedZone("UTC-5", -5*60*60))
+		before := base.Add(3 * time.Hour).In(time.FixedZone("UTC+9", 9*60*60))
+		links, _, err = repo.ListLinks(LinkFilter{CreatedAfter: &after, CreatedBefore: &before})
+		if err != nil {
+			t.Fatalf("ListLinks: %v", err)
+		}
+		if got, want := shortCodes(links), []string{"third", "local", "second"}; !slices.Equal(got, want) {
+			t.Errorf("ListLinks(created range) = %v, want %v", got, want)
+		}
+	})
+}
+
+func shortCodes(links []models.Link) []string {
+	codes := make([]string, len(links))
+	for i, link := range links {
+		codes[i] = link.ShortCode
+	}
+	return codes
+}
+
+func TestGetAllLinksCheckOrder(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		checkRepo := NewLinkCheckRepository(db)
+		base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		// Décalages horaires différents : l'ordre doit suivre les instants, pas l'heure locale.
+		checkedAt := map[string]time.Time{
+			"recent": base.In(time.FixedZone("UTC-5", -5*3600)),
+			"oldest": base.Add(-2 * time.Hour).In(time.FixedZone("UTC+2", 2*3600)),
+			"middle": base.Add(-time.Hour),
+		}
+		for _, code := range []string{"recent", "never-a", "oldest", "middle", "never-b"} {
+			link := createTestLink(t, repo, &models.Link{ShortCode: code})
+			at, ok := checkedAt[code]
+			if !ok {
+				continue
+			}
+			if err := checkRepo.RecordCheck(link, &models.LinkCheck{LinkID: link.ID, CheckedAt: at, Healthy: true}); err != nil {
+				t.Fatalf("RecordCheck(%s): %v", code, err)
+			}
+		}
+
+		links, err := repo.GetAllLinks()
+		if err != nil {
+			t.Fatalf("GetAllLinks: %v", err)
+		}
+		want := []string{"never-a", "never-b", "oldest", "middle", "recent"}
+		if got := shortCodes(links); !slices.Equal(got, want) {
+			t.Errorf("GetAllLinks order = %v, want %v", got, want)
+		}
+	})
+}
+
+func TestConsumeClick(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		limited := createTestLink(t, repo, &models.Link{ShortCode: "limited", MaxClicks: 2})
+		unlimited := createTestLink(t, repo, &models.Link{ShortCode: "unlimited"})
+
+		for i, want := range []bool{true, true, false} {
+			consumed, err := repo.ConsumeClick(limited)
+			if err != nil {
+				t.Fatalf("ConsumeClick #%d: %v", i+1, err)
+			}
+			if consumed != want {
+				t.Errorf("ConsumeClick #%d = %v, want %v", i+1, consumed, want)
+			}
+		}
+		if limited.HumanClicks != 2 {
+			t.Errorf("HumanClicks = %d, want 2", limited.HumanClicks)
+		}
+		// ConsumeClick n'est appelée que pour les liens disposant d'un budget.
+		if consumed, err := repo.ConsumeClick(unlimited); err != nil || consumed {
+			t.Errorf("ConsumeClick(unlimited) = %v, %v, want false", consumed, err)
+		}
+
+		// UpdateLongURL ne doit pas écraser le budget consommé avec une valeur périmée.
+		stale := *limited
+		stale.HumanClicks = 0
+		if err := repo.UpdateLongURL(&stale, "https://example.com/updated"); err != nil {
+			t.Fatalf("UpdateLongURL: %v", err)
+		}
+		got, err := repo.GetLinkByShortCode("limited")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if got.HumanClicks != 2 || got.LongURL != "https://example.com/updated" {
+			t.Errorf("after UpdateLongURL: HumanClicks = %d, LongURL = %s, want 2 and https://example.com/updated", got.HumanClicks, got.LongURL)
+		}
+	})
+}
+
+func TestUpdateLinkColumns(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewCachedLinkRepository(NewLinkRepository(db), LinkCacheConfig{Size: 10, TTL: time.Hour})
+		first, second := uint(1), uint(2)
+		createTestLink(t, repo, &models.Link{ShortCode: "shared", OwnerID: &first})
+
+		// Deux copies du lien en cache : une modification faite sur l'une ne doit pas être
+		// annulée par une modification faite ensuite sur l'autre.
+		owned, err := repo.GetLinkByShortCode("shared")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		cached, err := repo.GetLinkByShortCode("shared")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if err := repo.SetOwner(owned, second); err != nil {
+			t.Fatalf("SetOwner: %v", err)
+		}
+		if err := repo.UpdateLongURL(cached, "https://example.com/updated"); err != nil {
+			t.Fatalf("UpdateLongURL: %v", err)
+		}
+		// Même URL : le lien existe, la mise à jour réussit même si aucune valeur ne change.
+		if err := repo.UpdateLongURL(cached, "https://example.com/updated"); err != nil {
+			t.Fatalf("UpdateLongURL with the same URL: %v", err)
+		}
+		got, err := repo.GetLinkByShortCode("shared")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if got.OwnerID == nil || *got.OwnerID != second || got.LongURL != "https://example.com/updated" {
+			t.Errorf("after updates: OwnerID = %v, LongURL = %s, want %d and https://example.com/updated", got.OwnerID, got.LongURL, second)
+		}
+
+		// Un lien supprimé entre-temps n'est pas recréé.
+		if err := repo.DeleteLink(got); err != nil {
+			t.Fatalf("DeleteLink: %v", err)
+		}
+		if err := repo.UpdateLongURL(cached, "https://example.com/resurrected"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("UpdateLongURL of a deleted link error = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if err := repo.SetOwner(owned, first); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("SetOwner of a deleted link error = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if _, err := repo.GetLinkByShortCode("shared"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCode after updates of a deleted link error = %v, want gorm.ErrRecordNotFound", err)
+		}
+	})
+}
+
+func TestListNewlyExpiredLinks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		now := time.Now()
+		past := now.Add(-time.Minute)
+		future := now.Add(time.Hour)
+		createTestLink(t, repo, &models.Link{ShortCode: "dated", ExpiresAt: &past})
+		createTestLink(t, repo, &models.Link{ShortCode: "alive", ExpiresAt: &future, MaxClicks: 5})
+		exhausted := createTestLink(t, repo, &models.Link{ShortCode: "exhausted", MaxClicks: 1})
+		if _, err := repo.ConsumeClick(exhausted); err != nil {
+			t.Fatalf("ConsumeClick: %v", err)
+		}
+		// Dates d'expiration fournies avec le décalage du client : sous SQLite, comparées telles
+		// quelles, la première semblerait future et la seconde déjà dépassée.
+		east := now.Add(-30 * time.Minute).In(time.FixedZone("UTC+5", 5*60*60))
+		west := now.Add(30 * time.Minute).In(time.FixedZone("UTC-8", -8*60*60))
+		createTestLink(t, repo, &models.Link{ShortCode: "east", ExpiresAt: &east})
+		createTestLink(t, repo, &models.Link{ShortCode: "west", ExpiresAt: &west})
+
+		links, err := repo.ListNewlyExpiredLinks(now)
+		if err != nil {
+			t.Fatalf("ListNewlyExpiredLinks: %v", err)
+		}
+		if got, want := shortCodes(links), []string{"dated", "exhausted", "east"}; !slices.Equal(got, want) {
+			t.Fatalf("ListNewlyExpiredLinks = %v, want %v", got, want)
+		}
+
+		for i := range links {
+			marked, err := repo.MarkLinkExpired(&links[i], now)
+			if err != nil || !marked {
+				t.Fatalf("MarkLinkExpired(%s) = %v, %v, want true", links[i].ShortCode, marked, err)
+			}
+		}
+		if marked, err := repo.MarkLinkExpired(&links[0], now); err != nil || marked {
+			t.Errorf("second MarkLinkExpired = %v, %v, want false", marked, err)
+		}
+		if links, err := repo.ListNewlyExpiredLinks(now); err != nil || len(links) != 0 {
+			t.Errorf("ListNewlyExpiredLinks after marking = %v, %v, want none", shortCodes(links), err)
+		}
+	})
+}
+
+func TestAssignUnownedLinks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		owner, other := uint(1), uint(2)
+		createTestLink(t, repo, &models.Link{ShortCode: "cli-one"})
+		createTestLink(t, repo, &models.Link{ShortCode: "cli-two"})
+		createTestLink(t, repo, &models.Link{ShortCode: "owned", OwnerID: &other})
+
+		assigned, err := repo.AssignUnownedLinks(owner)
+		if err != nil {
+			t.Fatalf("AssignUnownedLinks: %v", err)
+		}
+		if assigned != 2 {
+			t.Errorf("AssignUnownedLinks = %d, want 2", assigned)
+		}
+		_, total, err := repo.ListLinks(LinkFilter{OwnerID: &other})
+		if err != nil || total != 1 {
+			t.Errorf("links of the other owner = %d, %v, want 1", total, err)
+		}
+	})
+}
+
+func TestDeleteLink(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := NewLinkRepository(db)
+		clickRepo := NewClickRepository(db)
+		link := createTestLink(t, repo, &models.Link{ShortCode: "doomed"})
+		kept := createTestLink(t, repo, &models.Link{ShortCode: "kept"})
+		for _, linkID := range []uint{link.ID, link.ID, kept.ID} {
+			if err := clickRepo.CreateClick(&models.Click{LinkID: linkID, Timestamp: time.Now()}); err != nil {
+				t.Fatalf("CreateClick: %v", err)
+			}
+		}
+
+		if err := repo.DeleteLink(link); err != nil {
+			t.Fatalf("DeleteLink: %v", err)
+		}
+		if _, err := repo.GetLinkByShortCode("doomed"); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("GetLinkByShortCode after DeleteLink error = %v, want gorm.ErrRecordNotFound", err)
+		}
+		if n, _ := repo.CountClicksByLinkID(link.ID, true); n != 0 {
+			t.Errorf("%d clicks left for the deleted link, want 0", n)
+		}
+		if n, _ := repo.CountClicksByLinkID(kept.ID, true); n != 1 {
+			t.Errorf("%d clicks for the other link, want 1", n)
+		}
+	})
+}
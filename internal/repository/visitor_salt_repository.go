@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// VisitorSaltRepository est une interface qui définit les méthodes d'accès aux données
+// pour les sels quotidiens des empreintes de visiteurs.
+type VisitorSaltRepository interface {
+	// GetOrCreateSalt retourne le sel du jour donné, en enregistrant 'candidate' s'il n'existe pas encore.
+	GetOrCreateSalt(day, candidate string) (string, error)
+	// DeleteSaltsBefore supprime les sels des jours antérieurs au jour donné.
+	DeleteSaltsBefore(day string) error
+}
+
+// GormVisitorSaltRepository est l'implémentation de VisitorSaltRepository utilisant GORM.
+type GormVisitorSaltRepository struct {
+	db *gorm.DB
+}
+
+// NewVisitorSaltRepository crée une nouvelle instance de GormVisitorSaltRepository.
+func NewVisitorSaltRepository(db *gorm.DB) VisitorSaltRepository {
+	if db == nil {
+		panic("nil *gorm.DB passed to NewVisitorSaltRepository")
+	}
+	return &GormVisitorSaltRepository{db: db}
+}
+
+// GetOrCreateSalt insère le sel candidat sans écraser un sel existant, puis relit le sel du jour.
+// Plusieurs workers (ou instances) partagent ainsi toujours le même sel pour une journée donnée.
+func (r *GormVisitorSaltRepository) GetOrCreateSalt(day, candidate string) (string, error) {
+	salt := models.VisitorSalt{Day: day, Salt: candidate}
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&salt).Error; err != nil {
+		return "", fmt.Errorf("failed to create visitor salt for %s: %w", day, err)
+	}
+
+	var stored models.VisitorSalt
+	if err := r.db.Where("day = ?", day).First(&stored).Error; err != nil {
+		return "", fmt.Errorf("failed to fetch visitor salt for %s: %w", day, err)
+	}
+	return stored.Salt, nil
+}
+
+// DeleteSaltsBefore supprime les sels des jours strictement antérieurs à 'day' (format AAAA-MM-JJ).
+func (r *GormVisitorSaltRepository) DeleteSaltsBefore(day string) error {
+	if err := r.db.Where("day < ?", day).Delete(&models.VisitorSalt{}).Error; err != nil {
+		return fmt.Errorf("failed to delete visitor salts before %s: %w", day, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"gorm.io/gorm"
+)
+
+// WebhookDeliveryRepository définit l'accès au journal des livraisons de webhooks.
+type WebhookDeliveryRepository interface {
+	// CreateDeliveries enregistre de nouvelles livraisons.
+	CreateDeliveries(deliveries []*models.WebhookDelivery) error
+	// GetDelivery récupère une livraison à partir de son identifiant.
+	GetDelivery(id uint) (*models.WebhookDelivery, error)
+	// ListDeliveries retourne les livraisons correspondant au filtre, de la plus récente à la plus ancienne.
+	ListDeliveries(filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	// ListDueDeliveries retourne au plus 'limit' livraisons en attente dont la prochaine tentative est échue.
+	ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error)
+	// StartAttempt réserve la livraison pour une nouvelle tentative jusqu'à 'leaseUntil'.
+	// Retourne false si une autre instance l'a réservée entre-temps.
+	StartAttempt(delivery *models.WebhookDelivery, now, leaseUntil time.Time) (bool, error)
+	// FinishAttempt enregistre le résultat de la tentative commencée par StartAttempt.
+	FinishAttempt(delivery *models.WebhookDelivery) error
+	// DeleteDeliveriesBefore supprime les livraisons terminées créées avant 'before' et retourne leur nombre.
+	DeleteDeliveriesBefore(before time.Time) (int64, error)
+}
+
+// WebhookDeliveryFilter regroupe les critères de ListDeliveries.
+// Les champs laissés à leur valeur zéro ne filtrent pas les résultats.
+type WebhookDeliveryFilter struct {
+	Status   string // models.DeliveryPending, DeliverySucceeded ou DeliveryFailed
+	Endpoint string // Nom de l'endpoint
+	Event    string // Type d'événement
+	Limit    int    // Nombre maximal de livraisons retournées (0 = pas de limite)
+}
+
+// GormWebhookDeliveryRepository est l'implémentation de WebhookDeliveryRepository utilisant GORM.
+type GormWebhookDeliveryRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookDeliveryRepository crée une nouvelle instance de GormWebhookDeliveryRepository.
+func NewWebhookDeliveryRepository(db *gorm.DB) WebhookDeliveryRepository {
+	if db == nil {
+		panic("nil *gorm.DB passed to NewWebhookDeliveryRepository")
+	}
+	return &GormWebhookDeliveryRepository{db: db}
+}
+
+// CreateDeliveries insère les livraisons en une seule requête ; leurs identifiants sont renseignés.
+func (r *GormWebhookDeliveryRepository) CreateDeliveries(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	if err := r.db.Create(deliveries).Error; err != nil {
+		return fmt.Errorf("failed to create webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+// GetDelivery récupère une livraison ; retourne gorm.ErrRecordNotFound si elle n'existe pas.
+func (r *GormWebhookDeliveryRepository) GetDelivery(id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := r.db.First(&delivery, id).Error; err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries retourne les livraisons correspondant au filtre.
+func (r *GormWebhookDeliveryRepository) ListDeliveries(filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	query := r.db.Model(&models.WebhookDelivery{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Endpoint != "" {
+		query = query.Where("endpoint = ?", filter.Endpoint)
+	}
+	if filter.Event != "" {
+		query = query.Where("event = ?", filter.Event)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var deliveries []models.WebhookDelivery
+	if err := query.Order("id DESC").Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ListDueDeliveries retourne les livraisons à tenter, de la plus anciennement échue à la plus récente.
+func (r *GormWebhookDeliveryRepository) ListDueDeliveries(now time.Time, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	err := r.db.Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+		Order("next_attempt_at ASC").Order("id ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list due webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// StartAttempt incrémente le nombre de tentatives et repousse la prochaine tentative à 'leaseUntil',
+// à condition que la livraison soit toujours en attente avec le même nombre de tentatives :
+// deux instances ne peuvent pas commencer la même tentative. Si l'instance s'arrête pendant
+// la tentative, la livraison redevient échue à 'leaseUntil'.
+// Les champs de 'delivery' sont mis à jour en cas de succès.
+func (r *GormWebhookDeliveryRepository) StartAttempt(delivery *models.WebhookDelivery, now, leaseUntil time.Time) (bool, error) {
+	result := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND status = ? AND attempts = ?", delivery.ID, models.DeliveryPending, delivery.Attempts).
+		Updates(map[string]any{
+			"attempts":        delivery.Attempts + 1,
+			"last_attempt_at": now,
+			"next_attempt_at": leaseUntil,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to start attempt of webhook delivery %d: %w", delivery.ID, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.NextAttemptAt = &leaseUntil
+	return true, nil
+}
+
+// FinishAttempt enregistre l'état, la prochaine tentative et le résultat de la dernière tentative.
+// Sans effet si une autre instance a repris la livraison après expiration de la réservation.
+func (r *GormWebhookDeliveryRepository) FinishAttempt(delivery *models.WebhookDelivery) error {
+	err := r.db.Model(&models.WebhookDelivery{}).
+		Where("id = ? AND attempts = ?", delivery.ID, delivery.Attempts).
+		Select("url", "status", "next_attempt_at", "response_code", "last_error", "delivered_at").
+		Updates(delivery).Error
+	if err != nil {
+		return fmt.Errorf("failed to record attempt of webhook delivery %d: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// DeleteDeliveriesBefore supprime les livraisons réussies ou abandonnées plus anciennes que 'before'.
+// Les livraisons en attente sont conservées quel que soit leur âge.
+func (r *GormWebhookDeliveryRepository) DeleteDeliveriesBefore(before time.Time) (int64, error) {
+	result := r.db.Where("status <> ? AND created_at < ?", models.DeliveryPending, before).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete webhook deliveries before %s: %w", before.Format(time.RFC3339), result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+)
+
+// apiKeyPrefix préfixe toutes les clés générées, pour les reconnaître facilement (ex. dans un scanner de secrets).
+const apiKeyPrefix = "usk_"
+
+// apiKeyTouchInterval limite la fréquence de mise à jour de LastUsedAt pour éviter une écriture par requête.
+const apiKeyTouchInterval = time.Minute
+
+var (
+	// ErrInvalidAPIKey est retournée lorsqu'une clé d'API est inconnue ou révoquée.
+	ErrInvalidAPIKey = errors.New("invalid or revoked api key")
+	// ErrAPIKeyAlreadyRevoked est retournée lorsqu'on tente de révoquer une clé déjà révoquée.
+	ErrAPIKeyAlreadyRevoked = errors.New("api key already revoked")
+)
+
+// APIKeyService fournit la logique métier de création, de révocation et de vérification des clés d'API.
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	logger     *slog.Logger
+}
+
+// NewAPIKeyService crée et retourne une nouvelle instance de APIKeyService.
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		logger:     logger,
+	}
+}
+
+// hashAPIKey calcule l'empreinte stockée en base pour une clé en clair.
+// Les clés étant aléatoires et longues, un SHA-256 simple suffit (pas besoin d'un hash lent comme bcrypt).
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateAPIKey génère une nouvelle clé d'API et enregistre son empreinte.
+// La clé en clair est retournée une seule fois et n'est jamais stockée.
+func (s *APIKeyService) CreateAPIKey(name string) (string, *models.APIKey, error) {
+	if name == "" {
+		return "", nil, errors.New("api key name is required")
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	rawKey := apiKeyPrefix + hex.EncodeToString(secret)
+
+	key := &models.APIKey{
+		Name:      name,
+		Prefix:    rawKey[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(rawKey),
+		CreatedAt: time.Now(),
+	}
+	if err := s.apiKeyRepo.CreateAPIKey(key); err != nil {
+		return "", nil, err
+	}
+	return rawKey, key, nil
+}
+
+// Authenticate vérifie une clé d'API en clair et retourne la clé correspondante si elle est active.
+func (s *APIKeyService) Authenticate(rawKey string) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByHash(hashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to look up api key: %w", err)
+	}
+	if key.RevokedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Mise à jour best-effort de la dernière utilisation : un échec ne doit pas bloquer la requête.
+	now := time.Now()
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyTouchInterval {
+		if err := s.apiKeyRepo.TouchAPIKey(key.ID, now); err != nil {
+			s.logger.Warn("failed to record api key usage", "api_key_id", key.ID, "error", err)
+		} else {
+			key.LastUsedAt = &now
+		}
+	}
+	return key, nil
+}
+
+// GetActiveAPIKey récupère une clé d'API via son identifiant.
+// Retourne ErrInvalidAPIKey si elle est révoquée, gorm.ErrRecordNotFound si elle n'existe pas.
+func (s *APIKeyService) GetActiveAPIKey(id uint) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, ErrInvalidAPIKey
+	}
+	return key, nil
+}
+
+// ListAPIKeys retourne toutes les clés d'API enregistrées.
+func (s *APIKeyService) ListAPIKeys() ([]models.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys()
+}
+
+// RevokeAPIKey révoque une clé d'API : elle ne permet plus d'accéder à l'API,
+// mais les liens qu'elle possède sont conservés.
+func (s *APIKeyService) RevokeAPIKey(id uint) (*models.APIKey, error) {
+	key, err := s.apiKeyRepo.GetAPIKeyByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.RevokedAt != nil {
+		return key, ErrAPIKeyAlreadyRevoked
+	}
+
+	now := time.Now()
+	if err := s.apiKeyRepo.RevokeAPIKey(id, now); err != nil {
+		return nil, err
+	}
+	key.RevokedAt = &now
+	return key, nil
+}
@@ -1,10 +1,107 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
 	"github.com/axellelanca/urlshortener/internal/models"
 	"github.com/axellelanca/urlshortener/internal/repository" // Importe le package repository
 )
 
+// Granularity représente la taille des intervalles d'une série temporelle de clics.
+type Granularity string
+
+// Granularités supportées par GetClickTimeSeries.
+const (
+	GranularityHour Granularity = "hour"
+	GranularityDay  Granularity = "day"
+	GranularityWeek Granularity = "week"
+)
+
+// MaxTimeSeriesBuckets borne le nombre d'intervalles d'une série temporelle,
+// pour éviter qu'une plage trop large ne produise une réponse démesurée.
+const MaxTimeSeriesBuckets = 2000
+
+var (
+	// ErrInvalidGranularity est retournée lorsque la granularité demandée n'est pas supportée.
+	ErrInvalidGranularity = errors.New("invalid granularity")
+	// ErrInvalidTimeRange est retournée lorsque la plage de temps demandée est vide ou trop large.
+	ErrInvalidTimeRange = errors.New("invalid time range")
+)
+
+// ParseGranularity convertit une chaîne (hour, day, week) en Granularity.
+func ParseGranularity(value string) (Granularity, error) {
+	switch g := Granularity(value); g {
+	case GranularityHour, GranularityDay, GranularityWeek:
+		return g, nil
+	}
+	return "", fmt.Errorf("%w: '%s' (expected hour, day or week)", ErrInvalidGranularity, value)
+}
+
+// bucketing retourne la taille des intervalles en secondes et le décalage de leur origine.
+// Les intervalles sont alignés sur UTC ; les semaines commencent le lundi
+// (le 1er janvier 1970 étant un jeudi, on décale l'origine de 3 jours).
+func (g Granularity) bucketing() (size, offset int64) {
+	switch g {
+	case GranularityHour:
+		return 3600, 0
+	case GranularityWeek:
+		return 7 * 86400, 3 * 86400
+	default:
+		return 86400, 0
+	}
+}
+
+// DefaultRange retourne la plage par défaut d'une série temporelle se terminant à 'to' :
+// 48 heures, 30 jours ou 12 semaines selon la granularité.
+func (g Granularity) DefaultRange(to time.Time) time.Time {
+	switch g {
+	case GranularityHour:
+		return to.Add(-48 * time.Hour)
+	case GranularityWeek:
+		return to.AddDate(0, 0, -7*12)
+	default:
+		return to.AddDate(0, 0, -30)
+	}
+}
+
+// Bornes du nombre d'entrées retournées par les classements (référents, navigateurs...).
+const (
+	DefaultTopLimit = 10
+	MaxTopLimit     = 100
+)
+
+// DirectReferrer désigne les clics sans en-tête Referer dans le classement des référents.
+const DirectReferrer = "(direct)"
+
+// UnknownValue désigne, dans les répartitions, les clics enregistrés avant que la dimension ne soit collectée.
+const UnknownValue = "(unknown)"
+
+// ReferrerCount représente le nombre de clics provenant d'un hôte référent.
+type ReferrerCount struct {
+	Host   string `json:"host"`
+	Clicks int    `json:"clicks"`
+}
+
+// BreakdownEntry représente la part des clics d'un lien associée à une valeur
+// d'une dimension (un navigateur, un système ou un type d'appareil).
+type BreakdownEntry struct {
+	Name       string  `json:"name"`
+	Clicks     int     `json:"clicks"`
+	Percentage float64 `json:"percentage"`
+}
+
+// TimeSeriesPoint représente le nombre de clics et de visiteurs uniques d'un intervalle d'une série temporelle.
+// Les empreintes de visiteurs changeant chaque jour, les visiteurs uniques d'un intervalle
+// de plus d'un jour (une semaine) correspondent à la somme des visiteurs uniques quotidiens.
+type TimeSeriesPoint struct {
+	Start          time.Time `json:"start"`
+	Clicks         int       `json:"clicks"`
+	UniqueVisitors int       `json:"unique_visitors"`
+}
+
 // ClickService est une structure qui fournit des méthodes pour la logique métier des clics.
 // Elle est juste composée de clickRepo qui est de type ClickRepository
 type ClickService struct {
@@ -27,6 +124,120 @@ func (s *ClickService) RecordClick(click *models.Click) error {
 
 // GetClicksCountByLinkID récupère le nombre total de clics pour un LinkID donné.
 // Cette méthode pourrait être utilisée par le LinkService pour les statistiques, ou directement par l'API stats.
-func (s *ClickService) GetClicksCountByLinkID(linkID uint) (int, error) {
-	return s.clickRepo.CountClicksByLinkID(linkID)
+func (s *ClickService) GetClicksCountByLinkID(linkID uint, includeBots bool) (int, error) {
+	return s.clickRepo.CountClicksByLinkID(linkID, includeBots)
+}
+
+// GetUniqueVisitorsCount récupère le nombre de visiteurs uniques (robots exclus) pour un LinkID donné.
+// Un visiteur revenant plusieurs jours est compté une fois par jour (cf. workers.VisitorHasher).
+func (s *ClickService) GetUniqueVisitorsCount(linkID uint) (int, error) {
+	return s.clickRepo.CountUniqueVisitorsByLinkID(linkID, false)
+}
+
+// GetDailyUniqueVisitors retourne le nombre de clics et de visiteurs uniques (robots exclus)
+// pour chacun des 'days' derniers jours UTC, jour courant compris.
+func (s *ClickService) GetDailyUniqueVisitors(linkID uint, days int, now time.Time) ([]TimeSeriesPoint, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	return s.GetClickTimeSeries(linkID, today.AddDate(0, 0, 1-days), today.AddDate(0, 0, 1), GranularityDay, false)
+}
+
+// GetBotClicksCount récupère le nombre de clics attribués à des robots pour un LinkID donné.
+func (s *ClickService) GetBotClicksCount(linkID uint) (int, error) {
+	return s.clickRepo.CountBotClicksByLinkID(linkID)
+}
+
+// GetClickTimeSeries retourne le nombre de clics d'un lien par intervalle entre 'from' et 'to'.
+// Les bornes sont alignées sur les intervalles de la granularité et chaque intervalle
+// de la plage est présent dans le résultat, y compris ceux sans aucun clic.
+// Les clics de robots ne sont comptés que si includeBots vaut true.
+func (s *ClickService) GetClickTimeSeries(linkID uint, from, to time.Time, granularity Granularity, includeBots bool) ([]TimeSeriesPoint, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidTimeRange)
+	}
+
+	size, offset := granularity.bucketing()
+	firstBucket := floorDiv(from.Unix()+offset, size)
+	lastBucket := floorDiv(to.Unix()-1+offset, size)
+	if lastBucket-firstBucket+1 > MaxTimeSeriesBuckets {
+		return nil, fmt.Errorf("%w: more than %d %s buckets requested", ErrInvalidTimeRange, MaxTimeSeriesBuckets, granularity)
+	}
+
+	// La requête couvre les intervalles entiers, même si 'from' et 'to' tombent en leur milieu.
+	rangeStart := time.Unix(firstBucket*size-offset, 0).UTC()
+	rangeEnd := time.Unix((lastBucket+1)*size-offset, 0).UTC()
+	buckets, err := s.clickRepo.CountClicksByInterval(linkID, rangeStart, rangeEnd, size, offset, includeBots)
+	if err != nil {
+		return nil, err
+	}
+
+	byBucket := make(map[int64]repository.ClickBucket, len(buckets))
+	for _, b := range buckets {
+		byBucket[b.Bucket] = b
+	}
+
+	points := make([]TimeSeriesPoint, 0, lastBucket-firstBucket+1)
+	for bucket := firstBucket; bucket <= lastBucket; bucket++ {
+		points = append(points, TimeSeriesPoint{
+			Start:          time.Unix(bucket*size-offset, 0).UTC(),
+			Clicks:         byBucket[bucket].Count,
+			UniqueVisitors: byBucket[bucket].Uniques,
+		})
+	}
+	return points, nil
+}
+
+// floorDiv effectue une division entière arrondie vers le bas, y compris pour les valeurs négatives.
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
+
+// GetTopReferrers retourne les hôtes référents ayant généré le plus de clics pour un lien.
+// Les clics sans Referer sont regroupés sous DirectReferrer.
+func (s *ClickService) GetTopReferrers(linkID uint, limit int, includeBots bool) ([]ReferrerCount, error) {
+	counts, err := s.clickRepo.CountClicksByDimension(linkID, repository.DimensionReferrerHost, limit, includeBots)
+	if err != nil {
+		return nil, err
+	}
+
+	referrers := make([]ReferrerCount, 0, len(counts))
+	for _, c := range counts {
+		host := c.Value
+		if host == "" {
+			host = DirectReferrer
+		}
+		referrers = append(referrers, ReferrerCount{Host: host, Clicks: c.Count})
+	}
+	return referrers, nil
+}
+
+// GetClickBreakdown retourne la répartition des clics d'un lien selon une dimension
+// (navigateur, système ou type d'appareil), avec la part de chaque valeur en pourcentage.
+func (s *ClickService) GetClickBreakdown(linkID uint, dimension repository.ClickDimension, limit int, includeBots bool) ([]BreakdownEntry, int, error) {
+	total, err := s.clickRepo.CountClicksByLinkID(linkID, includeBots)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	counts, err := s.clickRepo.CountClicksByDimension(linkID, dimension, limit, includeBots)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	entries := make([]BreakdownEntry, 0, len(counts))
+	for _, c := range counts {
+		name := c.Value
+		if name == "" {
+			name = UnknownValue
+		}
+		entry := BreakdownEntry{Name: name, Clicks: c.Count}
+		if total > 0 {
+			entry.Percentage = math.Round(float64(c.Count)*10000/float64(total)) / 100
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, nil
 }
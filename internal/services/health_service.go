@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+)
+
+// Nombre de vérifications retournées par GetLinkHealth.
+const (
+	DefaultHealthChecks = 20
+	MaxHealthChecks     = 100
+)
+
+// DefaultHealthWindow est la période sur laquelle le taux de disponibilité est calculé par défaut.
+const DefaultHealthWindow = 24 * time.Hour
+
+// LinkHealth décrit l'état de santé d'un lien et son historique récent.
+type LinkHealth struct {
+	Status        string     // models.HealthUnknown, HealthUp ou HealthDown
+	LastCheckedAt *time.Time // Date de la dernière vérification
+	StatusSince   *time.Time // Date depuis laquelle le lien est dans cet état
+	From, To      time.Time  // Période du calcul de disponibilité
+	Checks        int64      // Nombre de vérifications sur la période
+	HealthyChecks int64      // Nombre de vérifications réussies sur la période
+	// UptimePercent est la part de vérifications réussies sur la période, nil si aucune vérification.
+	UptimePercent *float64
+	// RecentChecks sont les dernières vérifications, de la plus récente à la plus ancienne.
+	RecentChecks []models.LinkCheck
+}
+
+// BrokenLink est un lien actuellement inaccessible, avec sa dernière vérification.
+type BrokenLink struct {
+	Link      models.Link
+	LastCheck *models.LinkCheck // nil si l'historique a été purgé
+}
+
+// HealthService fournit l'état de santé des liens à partir des vérifications du moniteur.
+type HealthService struct {
+	checkRepo repository.LinkCheckRepository
+}
+
+// NewHealthService crée et retourne une nouvelle instance de HealthService.
+func NewHealthService(checkRepo repository.LinkCheckRepository) *HealthService {
+	return &HealthService{checkRepo: checkRepo}
+}
+
+// GetLinkHealth retourne l'état de santé d'un lien, son taux de disponibilité sur [from, to)
+// et ses 'limit' dernières vérifications.
+func (s *HealthService) GetLinkHealth(link *models.Link, from, to time.Time, limit int) (*LinkHealth, error) {
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: 'from' must be before 'to'", ErrInvalidTimeRange)
+	}
+
+	// L'état est relu en base : 'link' peut provenir du cache des liens.
+	state, err := s.checkRepo.GetHealthState(link.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load health state of link %d: %w", link.ID, err)
+	}
+	total, healthy, err := s.checkRepo.CountChecks(link.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := s.checkRepo.ListRecentChecks(link.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	health := &LinkHealth{
+		Status:        state.HealthStatus,
+		LastCheckedAt: state.LastCheckedAt,
+		StatusSince:   state.HealthChangedAt,
+		From:          from,
+		To:            to,
+		Checks:        total,
+		HealthyChecks: healthy,
+		RecentChecks:  checks,
+	}
+	if total > 0 {
+		uptime := float64(healthy) * 100 / float64(total)
+		health.UptimePercent = &uptime
+	}
+	return health, nil
+}
+
+// ListBrokenLinks retourne les liens dont la dernière vérification a échoué,
+// du plus anciennement inaccessible au plus récent. Les dernières vérifications sont lues
+// en une seule requête, quel que soit le nombre de liens.
+func (s *HealthService) ListBrokenLinks() ([]BrokenLink, error) {
+	links, err := s.checkRepo.ListLinksByHealth(models.HealthDown)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := s.checkRepo.ListLatestChecksByHealth(models.HealthDown)
+	if err != nil {
+		return nil, err
+	}
+
+	broken := make([]BrokenLink, 0, len(links))
+	for _, link := range links {
+		entry := BrokenLink{Link: link}
+		// Absent de 'latest' : historique purgé, ou lien revenu en ligne entre les deux requêtes.
+		if check, ok := latest[link.ID]; ok {
+			entry.LastCheck = &check
+		}
+		broken = append(broken, entry)
+	}
+	return broken, nil
+}
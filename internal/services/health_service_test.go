@@ -0,0 +1,91 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+// noPerLinkChecksRepository échoue le test si les vérifications sont lues lien par lien.
+type noPerLinkChecksRepository struct {
+	repository.LinkCheckRepository
+	t *testing.T
+}
+
+func (r noPerLinkChecksRepository) ListRecentChecks(linkID uint, limit int) ([]models.LinkCheck, error) {
+	r.t.Errorf("ListRecentChecks(%d, %d) called: checks must be loaded in a single query", linkID, limit)
+	return r.LinkCheckRepository.ListRecentChecks(linkID, limit)
+}
+
+func TestListBrokenLinks(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		linkRepo := repository.NewLinkRepository(db)
+		checkRepo := repository.NewLinkCheckRepository(db)
+		service := NewHealthService(noPerLinkChecksRepository{checkRepo, t})
+
+		base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+		record := func(link *models.Link, at time.Time, healthy bool, statusCode int) {
+			t.Helper()
+			check := &models.LinkCheck{LinkID: link.ID, CheckedAt: at, Healthy: healthy, StatusCode: statusCode}
+			if err := checkRepo.RecordCheck(link, check); err != nil {
+				t.Fatalf("RecordCheck(%s): %v", link.ShortCode, err)
+			}
+		}
+		newLink := func(code string) *models.Link {
+			t.Helper()
+			link := &models.Link{ShortCode: code, LongURL: "https://example.com/" + code, CreatedAt: base}
+			if err := linkRepo.CreateLink(link); err != nil {
+				t.Fatalf("CreateLink(%s): %v", code, err)
+			}
+			return link
+		}
+
+		// Inaccessible depuis base-1h, dernière vérification en 503.
+		recent := newLink("recent")
+		record(recent, base.Add(-3*time.Hour), true, 200)
+		record(recent, base.Add(-time.Hour), false, 500)
+		record(recent, base, false, 503)
+		// Inaccessible depuis base-2h : listé en premier.
+		older := newLink("older")
+		record(older, base.Add(-2*time.Hour), false, 404)
+		// De nouveau accessible : absent de la liste.
+		recovered := newLink("recovered")
+		record(recovered, base.Add(-time.Hour), false, 500)
+		record(recovered, base, true, 200)
+		// Inaccessible, historique purgé.
+		purged := newLink("purged")
+		record(purged, base.Add(-48*time.Hour), false, 502)
+		if _, err := checkRepo.DeleteChecksBefore(base.Add(-24 * time.Hour)); err != nil {
+			t.Fatalf("DeleteChecksBefore: %v", err)
+		}
+
+		broken, err := service.ListBrokenLinks()
+		if err != nil {
+			t.Fatalf("ListBrokenLinks: %v", err)
+		}
+		want := []struct {
+			code       string
+			statusCode int // 0 : pas de dernière vérification
+		}{{"purged", 0}, {"older", 404}, {"recent", 503}}
+		if len(broken) != len(want) {
+			t.Fatalf("%d broken links, want %d", len(broken), len(want))
+		}
+		for i, w := range want {
+			got := broken[i]
+			if got.Link.ShortCode != w.code {
+				t.Errorf("broken[%d] = %s, want %s", i, got.Link.ShortCode, w.code)
+				continue
+			}
+			switch {
+			case w.statusCode == 0 && got.LastCheck != nil:
+				t.Errorf("%s: LastCheck = %+v, want nil", w.code, got.LastCheck)
+			case w.statusCode != 0 && (got.LastCheck == nil || got.LastCheck.StatusCode != w.statusCode || got.LastCheck.LinkID != got.Link.ID):
+				t.Errorf("%s: LastCheck = %+v, want status %d", w.code, got.LastCheck, w.statusCode)
+			}
+		}
+	})
+}
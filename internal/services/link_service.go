@@ -1,36 +1,165 @@
 package services
 
 import (
+	"context"
 	"crypto/rand"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"math/big"
+	"regexp"
+	"strings"
 	"time"
 
 	"gorm.io/gorm" // Nécessaire pour la gestion spécifique de gorm.ErrRecordNotFound
 
 	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/netguard"
 	"github.com/axellelanca/urlshortener/internal/repository" // Importe le package repository
+	"github.com/axellelanca/urlshortener/internal/webhooks"
 )
 
 // Définition du jeu de caractères pour la génération des codes courts.
 const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 
+// Bornes de longueur d'un alias personnalisé.
+// MaxAliasLength doit rester cohérent avec la contrainte `size` de models.Link.ShortCode.
+const (
+	MinAliasLength = 3
+	MaxAliasLength = 32
+)
+
+// aliasPattern décrit les caractères autorisés dans un alias : lettres, chiffres, '-' et '_',
+// sans tiret ni underscore en début ou en fin.
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9](?:[a-zA-Z0-9_-]*[a-zA-Z0-9])?$`)
+
+// destinationCheckTimeout borne la résolution DNS de la vérification d'une URL de destination.
+const destinationCheckTimeout = 3 * time.Second
+
+// reservedAliases liste les alias qui entreraient en conflit avec les routes du serveur.
+var reservedAliases = map[string]struct{}{
+	"api":     {},
+	"health":  {},
+	"metrics": {},
+}
+
+var (
+	// ErrInvalidAlias est retournée lorsqu'un alias personnalisé ne respecte pas le format attendu.
+	ErrInvalidAlias = errors.New("invalid custom alias")
+	// ErrAliasTaken est retournée lorsqu'un alias personnalisé est déjà utilisé par un autre lien.
+	ErrAliasTaken = errors.New("custom alias already in use")
+	// ErrInvalidExpiration est retournée lorsque la date d'expiration ou le budget de clics est incohérent.
+	ErrInvalidExpiration = errors.New("invalid expiration")
+	// ErrForbiddenDestination est retournée lorsque l'URL de destination désigne une adresse interne refusée.
+	ErrForbiddenDestination = errors.New("forbidden destination")
+	// ErrLinkExpired est retournée lorsqu'un lien a dépassé sa date d'expiration ou consommé son budget de clics.
+	ErrLinkExpired = errors.New("link has expired")
+)
+
+// CreateLinkOptions regroupe les paramètres optionnels de la création d'un lien.
+type CreateLinkOptions struct {
+	// CustomAlias remplace le code court généré aléatoirement lorsqu'il est renseigné.
+	CustomAlias string
+	// ExpiresAt fixe une date au-delà de laquelle le lien n'est plus redirigé.
+	ExpiresAt *time.Time
+	// MaxClicks limite le nombre de clics acceptés par le lien (0 = illimité).
+	MaxClicks int
+	// OwnerID rattache le lien à la clé d'API qui l'a créé.
+	OwnerID *uint
+}
+
+// Bornes de la pagination de ListLinks.
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// LinkPage représente une page de résultats retournée par ListLinks.
+type LinkPage struct {
+	Links    []models.Link
+	Total    int64
+	Page     int
+	PageSize int
+}
+
+// LinkLifetime décrit la durée de vie restante d'un lien.
+// Les champs pointeurs sont nil lorsque la limite correspondante n'est pas définie.
+type LinkLifetime struct {
+	ExpiresAt        *time.Time
+	ExpiresInSeconds *int64
+	MaxClicks        int
+	RemainingClicks  *int
+	Expired          bool
+}
+
+// ComputeLifetime calcule la durée de vie restante d'un lien à l'instant 'now',
+// à partir de sa date d'expiration et des clics déjà décomptés de son budget (HumanClicks).
+func ComputeLifetime(link *models.Link, now time.Time) LinkLifetime {
+	lifetime := LinkLifetime{
+		ExpiresAt: link.ExpiresAt,
+		MaxClicks: link.MaxClicks,
+	}
+
+	if link.ExpiresAt != nil {
+		remaining := int64(link.ExpiresAt.Sub(now).Seconds())
+		if remaining <= 0 {
+			remaining = 0
+			lifetime.Expired = true
+		}
+		lifetime.ExpiresInSeconds = &remaining
+	}
+
+	if link.MaxClicks > 0 {
+		remaining := link.MaxClicks - link.HumanClicks
+		if remaining <= 0 {
+			remaining = 0
+			lifetime.Expired = true
+		}
+		lifetime.RemainingClicks = &remaining
+	}
+
+	return lifetime
+}
+
 // LinkService est une structure qui fournit des méthodes pour la logique métier des liens.
 // Elle détient linkRepo qui est une référence vers une interface LinkRepository.
 // IMPORTANT : Le champ doit être du type de l'interface (non-pointeur).
 type LinkService struct {
 	linkRepo repository.LinkRepository
+	logger   *slog.Logger
+	guard    *netguard.Guard      // Vérifie les URLs de destination ; nil si aucune vérification
+	webhooks *webhooks.Dispatcher // Publie les événements des liens ; nil si les webhooks sont désactivés
 }
 
 // NewLinkService crée et retourne une nouvelle instance de LinkService.
-func NewLinkService(linkRepo repository.LinkRepository) *LinkService {
+func NewLinkService(linkRepo repository.LinkRepository, logger *slog.Logger) *LinkService {
 	return &LinkService{
 		linkRepo: linkRepo,
+		logger:   logger,
 	}
 }
 
+// SetNetworkGuard active la vérification des URLs de destination à la création et à la modification
+// d'un lien : une URL désignant une adresse interne refusée par 'guard' retourne ErrForbiddenDestination.
+func (s *LinkService) SetNetworkGuard(guard *netguard.Guard) {
+	s.guard = guard
+}
+
+// SetWebhooks fait publier par 'dispatcher' la création, la suppression et l'expiration des liens.
+func (s *LinkService) SetWebhooks(dispatcher *webhooks.Dispatcher) {
+	s.webhooks = dispatcher
+}
+
+// checkDestination vérifie l'URL de destination auprès du Guard éventuel.
+func (s *LinkService) checkDestination(longURL string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), destinationCheckTimeout)
+	defer cancel()
+	if err := s.guard.CheckURL(ctx, longURL); err != nil {
+		return fmt.Errorf("%w: %v", ErrForbiddenDestination, err)
+	}
+	return nil
+}
+
 // GenerateShortCode génère un code court aléatoire d'une longueur spécifiée.
 // Elle utilise le package 'crypto/rand' pour éviter la prévisibilité.
 func (s *LinkService) GenerateShortCode(length int) (string, error) {
@@ -49,9 +178,95 @@ func (s *LinkService) GenerateShortCode(length int) (string, error) {
 	return string(b), nil
 }
 
+// ValidateAlias vérifie qu'un alias personnalisé respecte le jeu de caractères,
+// les bornes de longueur et n'entre pas en conflit avec une route réservée.
+func ValidateAlias(alias string) error {
+	if len(alias) < MinAliasLength || len(alias) > MaxAliasLength {
+		return fmt.Errorf("%w: length must be between %d and %d characters", ErrInvalidAlias, MinAliasLength, MaxAliasLength)
+	}
+	if !aliasPattern.MatchString(alias) {
+		return fmt.Errorf("%w: only letters, digits, '-' and '_' are allowed, and it must start and end with a letter or digit", ErrInvalidAlias)
+	}
+	if _, reserved := reservedAliases[strings.ToLower(alias)]; reserved {
+		return fmt.Errorf("%w: '%s' is reserved", ErrInvalidAlias, alias)
+	}
+	return nil
+}
+
 // CreateLink crée un nouveau lien raccourci.
-// Il génère un code court unique, puis persiste le lien dans la base de données.
-func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
+// Si un alias personnalisé est fourni, il est validé puis utilisé tel quel ;
+// sinon un code court unique est généré. Le lien est ensuite persisté dans la base de données.
+func (s *LinkService) CreateLink(longURL string, opts CreateLinkOptions) (*models.Link, error) {
+	if opts.ExpiresAt != nil && !opts.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: expires_at must be in the future", ErrInvalidExpiration)
+	}
+	if opts.MaxClicks < 0 {
+		return nil, fmt.Errorf("%w: max_clicks must be positive", ErrInvalidExpiration)
+	}
+	if err := s.checkDestination(longURL); err != nil {
+		return nil, err
+	}
+
+	var shortCode string
+	var err error
+
+	if opts.CustomAlias != "" {
+		shortCode, err = s.reserveAlias(opts.CustomAlias)
+	} else {
+		shortCode, err = s.generateUniqueShortCode()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Crée une nouvelle instance du modèle Link.
+	link := &models.Link{
+		LongURL:   longURL,
+		ShortCode: shortCode,
+		CreatedAt: time.Now(),
+		ExpiresAt: opts.ExpiresAt,
+		MaxClicks: opts.MaxClicks,
+		OwnerID:   opts.OwnerID,
+	}
+
+	// Persiste le nouveau lien dans la base de données via le repository.
+	// La vérification préalable du code court ne suffit pas : deux créations simultanées peuvent
+	// la passer toutes les deux. L'index unique de short_code tranche, et le perdant reçoit
+	// gorm.ErrDuplicatedKey.
+	if err := s.linkRepo.CreateLink(link); err != nil {
+		if errors.Is(err, gorm.ErrDuplicatedKey) {
+			if opts.CustomAlias != "" {
+				return nil, fmt.Errorf("%w: '%s'", ErrAliasTaken, opts.CustomAlias)
+			}
+			return nil, errors.New("unable to generate unique shortcode: concurrent collision")
+		}
+		return nil, fmt.Errorf("failed to create link: %w", err)
+	}
+	s.webhooks.Publish(models.EventLinkCreated, link, nil)
+
+	// Retourne le lien créé
+	return link, nil
+}
+
+// reserveAlias valide un alias personnalisé et vérifie qu'il n'est pas déjà utilisé.
+// Un alias réservé entre-temps par une création concurrente est détecté à l'insertion.
+func (s *LinkService) reserveAlias(alias string) (string, error) {
+	if err := ValidateAlias(alias); err != nil {
+		return "", err
+	}
+
+	_, err := s.linkRepo.GetLinkByShortCode(alias)
+	if err == nil {
+		return "", fmt.Errorf("%w: '%s'", ErrAliasTaken, alias)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("database error checking alias availability: %w", err)
+	}
+	return alias, nil
+}
+
+// generateUniqueShortCode génère un code court aléatoire qui n'existe pas encore en base.
+func (s *LinkService) generateUniqueShortCode() (string, error) {
 	var shortCode string
 	maxRetries := 5
 
@@ -59,7 +274,7 @@ func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
 		// Génère un code de 6 caractères
 		code, err := s.GenerateShortCode(6)
 		if err != nil {
-			return nil, fmt.Errorf("failed to generate short code: %w", err)
+			return "", fmt.Errorf("failed to generate short code: %w", err)
 		}
 
 		// Vérifie si le code généré existe déjà en base de données
@@ -72,42 +287,120 @@ func (s *LinkService) CreateLink(longURL string) (*models.Link, error) {
 				break            // Sort de la boucle de retry
 			}
 			// Si c'est une autre erreur de base de données, retourne l'erreur.
-			return nil, fmt.Errorf("database error checking short code uniqueness: %w", err)
+			return "", fmt.Errorf("database error checking short code uniqueness: %w", err)
 		}
 
 		// Si aucune erreur (le code a été trouvé), cela signifie une collision.
-		log.Printf("Short code '%s' already exists, retrying generation (%d/%d)...", code, i+1, maxRetries)
+		s.logger.Debug("short code collision, retrying generation", "short_code", code, "attempt", i+1, "max_attempts", maxRetries)
 		// La boucle continuera pour générer un nouveau code.
 	}
 
 	// Si après toutes les tentatives, aucun code unique n'a été trouvé
 	if shortCode == "" {
-		return nil, errors.New("unable to generate unique shortcode after maximum retries")
+		return "", errors.New("unable to generate unique shortcode after maximum retries")
 	}
 
-	// Crée une nouvelle instance du modèle Link.
-	link := &models.Link{
-		LongURL:   longURL,
-		ShortCode: shortCode,
-		CreatedAt: time.Now(),
+	return shortCode, nil
+}
+
+// GetLinkByShortCode récupère un lien via son code court.
+// Il délègue l'opération de recherche au repository.
+func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error) {
+	return s.linkRepo.GetLinkByShortCode(shortCode)
+}
+
+// ResolveLink récupère un lien à rediriger via son code court.
+// Elle retourne ErrLinkExpired si le lien a dépassé sa date d'expiration ou son budget de clics ;
+// la première fois, le lien est marqué comme expiré (voir markExpired). L'expiration est définitive :
+// un lien déjà marqué est refusé sans autre accès à la base.
+// Pour un lien disposant d'un budget, une redirection humaine ('human') en décompte un clic
+// atomiquement (LinkRepository.ConsumeClick) et est refusée si le budget est déjà consommé ;
+// les redirections de robots ne consomment pas le budget.
+func (s *LinkService) ResolveLink(shortCode string, human bool) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.ExpiredAt != nil {
+		return link, ErrLinkExpired
 	}
 
-	// Persiste le nouveau lien dans la base de données via le repository
-	if err := s.linkRepo.CreateLink(link); err != nil {
-		return nil, fmt.Errorf("failed to create link: %w", err)
+	now := time.Now()
+	expired := ComputeLifetime(link, now).Expired
+	if !expired && human && link.MaxClicks > 0 {
+		consumed, err := s.linkRepo.ConsumeClick(link)
+		if err != nil {
+			return nil, err
+		}
+		expired = !consumed
 	}
 
-	// Retourne le lien créé
+	if expired {
+		s.markExpired(link, now)
+		return link, ErrLinkExpired
+	}
 	return link, nil
 }
 
-// GetLinkByShortCode récupère un lien via son code court.
-// Il délègue l'opération de recherche au repository.
-func (s *LinkService) GetLinkByShortCode(shortCode string) (*models.Link, error) {
-	return s.linkRepo.GetLinkByShortCode(shortCode)
+// NotifyExpiredLinks marque les liens expirés depuis le dernier appel (date dépassée ou budget
+// de clics consommé) et publie leur expiration. Retourne le nombre de liens marqués.
+func (s *LinkService) NotifyExpiredLinks() (int, error) {
+	now := time.Now()
+	links, err := s.linkRepo.ListNewlyExpiredLinks(now)
+	if err != nil {
+		return 0, err
+	}
+	marked := 0
+	for i := range links {
+		if s.markExpired(&links[i], now) {
+			marked++
+		}
+	}
+	return marked, nil
 }
 
-// GetLinkStats récupère les statistiques pour un lien donné (nombre total de clics).
+// markExpired enregistre que le lien a été constaté expiré et publie link.expired, une seule fois
+// par lien même si plusieurs redirections ou instances le constatent en même temps.
+// Retourne true si le lien vient d'être marqué.
+func (s *LinkService) markExpired(link *models.Link, now time.Time) bool {
+	if link.ExpiredAt != nil {
+		return false
+	}
+	marked, err := s.linkRepo.MarkLinkExpired(link, now)
+	if err != nil {
+		s.logger.Warn("failed to mark link as expired", "short_code", link.ShortCode, "error", err)
+		return false
+	}
+	if marked {
+		s.webhooks.Publish(models.EventLinkExpired, link, nil)
+	}
+	return marked
+}
+
+// GetOwnedLink récupère un lien via son code court en vérifiant qu'il appartient à la clé d'API donnée.
+// Un lien appartenant à une autre clé est traité comme inexistant (gorm.ErrRecordNotFound),
+// afin de ne pas révéler l'existence des codes courts des autres propriétaires.
+func (s *LinkService) GetOwnedLink(shortCode string, ownerID uint) (*models.Link, error) {
+	link, err := s.linkRepo.GetLinkByShortCode(shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if link.OwnerID == nil || *link.OwnerID != ownerID {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return link, nil
+}
+
+// CountClicks retourne le nombre total de clics humains d'un lien (robots exclus).
+func (s *LinkService) CountClicks(link *models.Link) (int, error) {
+	totalClicks, err := s.linkRepo.CountClicksByLinkID(link.ID, false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count clicks: %w", err)
+	}
+	return totalClicks, nil
+}
+
+// GetLinkStats récupère les statistiques pour un lien donné (nombre total de clics humains).
 // Il interagit avec le LinkRepository pour obtenir le lien, puis compte les clics.
 func (s *LinkService) GetLinkStats(shortCode string) (*models.Link, int, error) {
 	// Récupérer le lien par son shortCode
@@ -117,11 +410,72 @@ func (s *LinkService) GetLinkStats(shortCode string) (*models.Link, int, error)
 	}
 
 	// Compter le nombre de clics pour ce LinkID
-	totalClicks, err := s.linkRepo.CountClicksByLinkID(link.ID)
+	totalClicks, err := s.CountClicks(link)
 	if err != nil {
-		return nil, 0, fmt.Errorf("failed to count clicks: %w", err)
+		return nil, 0, err
 	}
 
 	// Retourner les 3 valeurs
 	return link, totalClicks, nil
 }
+
+// ListLinks retourne la page demandée des liens correspondant au filtre.
+// La page commence à 1 et sa taille est bornée par MaxPageSize ; les champs
+// Offset et Limit du filtre sont calculés à partir de ces valeurs.
+func (s *LinkService) ListLinks(filter repository.LinkFilter, page, pageSize int) (*LinkPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	filter.Offset = (page - 1) * pageSize
+	filter.Limit = pageSize
+
+	links, total, err := s.linkRepo.ListLinks(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LinkPage{
+		Links:    links,
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	}, nil
+}
+
+// UpdateLinkTarget modifie l'URL longue vers laquelle redirige un lien existant.
+// Retourne ErrForbiddenDestination si la nouvelle URL désigne une adresse interne refusée,
+// gorm.ErrRecordNotFound si le lien a été supprimé entre-temps.
+func (s *LinkService) UpdateLinkTarget(link *models.Link, longURL string) error {
+	if err := s.checkDestination(longURL); err != nil {
+		return err
+	}
+	return s.linkRepo.UpdateLongURL(link, longURL)
+}
+
+// AssignOwner rattache un lien à la clé d'API 'ownerID', qu'il ait déjà un propriétaire ou non.
+// Retourne gorm.ErrRecordNotFound si le lien a été supprimé entre-temps.
+func (s *LinkService) AssignOwner(link *models.Link, ownerID uint) error {
+	return s.linkRepo.SetOwner(link, ownerID)
+}
+
+// AssignUnownedLinks rattache à la clé d'API 'ownerID' tous les liens sans propriétaire,
+// créés hors API. Retourne le nombre de liens rattachés.
+func (s *LinkService) AssignUnownedLinks(ownerID uint) (int64, error) {
+	return s.linkRepo.AssignUnownedLinks(ownerID)
+}
+
+// DeleteLink supprime un lien, l'historique de ses clics et ses vérifications, puis publie link.deleted.
+func (s *LinkService) DeleteLink(link *models.Link) error {
+	if err := s.linkRepo.DeleteLink(link); err != nil {
+		return err
+	}
+	s.webhooks.Publish(models.EventLinkDeleted, link, nil)
+	return nil
+}
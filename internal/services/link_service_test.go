@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/database"
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+// staleLookupRepository simule une création concurrente : la vérification préalable de l'alias
+// ne voit pas le lien inséré entre-temps.
+type staleLookupRepository struct {
+	repository.LinkRepository
+}
+
+func (staleLookupRepository) GetLinkByShortCode(string) (*models.Link, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func newTestLinkService(repo repository.LinkRepository) *LinkService {
+	return NewLinkService(repo, slog.New(slog.DiscardHandler))
+}
+
+func TestCreateLinkAliasTakenAfterCheck(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		service := newTestLinkService(staleLookupRepository{repository.NewLinkRepository(db)})
+
+		if _, err := service.CreateLink("https://example.com/a", CreateLinkOptions{CustomAlias: "q3-report"}); err != nil {
+			t.Fatalf("first CreateLink: %v", err)
+		}
+		_, err := service.CreateLink("https://example.com/b", CreateLinkOptions{CustomAlias: "q3-report"})
+		if !errors.Is(err, ErrAliasTaken) {
+			t.Fatalf("second CreateLink error = %v, want ErrAliasTaken", err)
+		}
+	})
+}
+
+func TestCreateLinkConcurrentAlias(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		service := newTestLinkService(repository.NewLinkRepository(db))
+
+		const creators = 8
+		errs := make([]error, creators)
+		var wg sync.WaitGroup
+		for i := range creators {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = service.CreateLink("https://example.com", CreateLinkOptions{CustomAlias: "launch"})
+			}()
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, ErrAliasTaken):
+				t.Errorf("CreateLink error = %v, want nil or ErrAliasTaken", err)
+			}
+		}
+		if created != 1 {
+			t.Errorf("%d links created with the same alias, want 1", created)
+		}
+	})
+}
+
+func TestResolveLinkConcurrentBudget(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		linkRepo := repository.NewLinkRepository(db)
+		cached := repository.NewCachedLinkRepository(linkRepo, repository.LinkCacheConfig{Size: 10, TTL: time.Minute})
+		service := newTestLinkService(cached)
+
+		const budget, visitors = 5, 20
+		if _, err := service.CreateLink("https://example.com", CreateLinkOptions{CustomAlias: "limited", MaxClicks: budget}); err != nil {
+			t.Fatalf("CreateLink: %v", err)
+		}
+		// Les robots ne consomment pas le budget.
+		if _, err := service.ResolveLink("limited", false); err != nil {
+			t.Fatalf("ResolveLink (bot): %v", err)
+		}
+
+		errs := make([]error, visitors)
+		var wg sync.WaitGroup
+		for i := range visitors {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, errs[i] = service.ResolveLink("limited", true)
+			}()
+		}
+		wg.Wait()
+
+		redirected := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				redirected++
+			case !errors.Is(err, ErrLinkExpired):
+				t.Errorf("ResolveLink error = %v, want nil or ErrLinkExpired", err)
+			}
+		}
+		if redirected != budget {
+			t.Errorf("%d redirects accepted, want %d", redirected, budget)
+		}
+
+		link, err := linkRepo.GetLinkByShortCode("limited")
+		if err != nil {
+			t.Fatalf("GetLinkByShortCode: %v", err)
+		}
+		if link.HumanClicks != budget {
+			t.Errorf("HumanClicks = %d, want %d", link.HumanClicks, budget)
+		}
+		if link.ExpiredAt == nil {
+			t.Error("exhausted link not marked as expired")
+		}
+		if _, err := service.ResolveLink("limited", false); !errors.Is(err, ErrLinkExpired) {
+			t.Errorf("ResolveLink (bot) after exhaustion error = %v, want ErrLinkExpired", err)
+		}
+	})
+}
+
+// expiredLinkRepository ne répond qu'à la recherche par code court : tout autre accès à la base
+// (décompte du budget, marquage de l'expiration) paniquerait sur l'interface nil embarquée.
+type expiredLinkRepository struct {
+	repository.LinkRepository
+	link *models.Link
+}
+
+func (r expiredLinkRepository) GetLinkByShortCode(string) (*models.Link, error) {
+	return r.link, nil
+}
+
+func TestResolveLinkAlreadyExpired(t *testing.T) {
+	expiredAt := time.Now().Add(-time.Hour)
+	link := &models.Link{ID: 1, ShortCode: "gone", MaxClicks: 5, ExpiredAt: &expiredAt}
+	service := newTestLinkService(expiredLinkRepository{link: link})
+
+	for _, human := range []bool{true, false} {
+		if _, err := service.ResolveLink("gone", human); !errors.Is(err, ErrLinkExpired) {
+			t.Errorf("ResolveLink(human=%v) error = %v, want ErrLinkExpired", human, err)
+		}
+	}
+}
+
+// BenchmarkResolveLink mesure le coût d'une redirection résolue depuis le cache : sans budget,
+// elle n'accède pas à la base ; avec un budget, chaque redirection humaine exécute un UPDATE.
+func BenchmarkResolveLink(b *testing.B) {
+	db := dbtest.Open(b, database.DriverSQLite)
+	cached := repository.NewCachedLinkRepository(repository.NewLinkRepository(db), repository.LinkCacheConfig{Size: 10, TTL: time.Hour})
+	service := newTestLinkService(cached)
+
+	for _, bc := range []struct {
+		name      string
+		maxClicks int
+		human     bool
+	}{
+		{"unlimited", 0, true},
+		{"budget/bot", 1 << 30, false},
+		{"budget/human", 1 << 30, true},
+	} {
+		alias := strings.ReplaceAll(bc.name, "/", "-")
+		if _, err := service.CreateLink("https://example.com", CreateLinkOptions{CustomAlias: alias, MaxClicks: bc.maxClicks}); err != nil {
+			b.Fatalf("CreateLink: %v", err)
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := service.ResolveLink(alias, bc.human); err != nil {
+					b.Fatalf("ResolveLink: %v", err)
+				}
+			}
+		})
+	}
+}
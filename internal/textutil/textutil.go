@@ -0,0 +1,16 @@
+// Package textutil regroupe des fonctions utilitaires sur les chaînes partagées par plusieurs packages.
+package textutil
+
+import "unicode/utf8"
+
+// Truncate limite une chaîne à 'limit' octets pour respecter la taille des colonnes,
+// sans couper un caractère UTF-8 : PostgreSQL refuse les séquences UTF-8 invalides.
+func Truncate(s string, limit int) string {
+	if len(s) <= limit {
+		return s
+	}
+	for limit > 0 && !utf8.RuneStart(s[limit]) {
+		limit--
+	}
+	return s[:limit]
+}
@@ -0,0 +1,98 @@
+package useragent
+
+import "strings"
+
+// Types d'appareils reconnus par Parse.
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot" // Attribué par le pipeline de clics à partir de botfilter, jamais par Parse
+	DeviceOther   = "other"
+)
+
+// Other désigne un navigateur ou un système d'exploitation non reconnu.
+const Other = "Other"
+
+// Info regroupe les informations extraites d'un en-tête User-Agent.
+type Info struct {
+	Browser string // Famille de navigateur (Chrome, Firefox, Safari...)
+	OS      string // Famille de système d'exploitation (Windows, macOS, Android...)
+	Device  string // Type d'appareil (desktop, mobile, tablet, bot, other)
+}
+
+// rule associe une famille à la liste des marqueurs qui la signalent dans un User-Agent.
+type rule struct {
+	family  string
+	markers []string
+}
+
+// browserRules est évaluée dans l'ordre : de nombreux navigateurs reprennent les marqueurs
+// de Chrome ou Safari, les plus spécifiques doivent donc être testés en premier.
+var browserRules = []rule{
+	{"Edge", []string{"edg/", "edge/", "edga/", "edgios/"}},
+	{"Opera", []string{"opr/", "opera", "opios/"}},
+	{"Samsung Internet", []string{"samsungbrowser/"}},
+	{"Firefox", []string{"firefox/", "fxios/"}},
+	{"Chrome", []string{"chrome/", "crios/", "chromium/"}},
+	{"Safari", []string{"safari/"}},
+	{"Internet Explorer", []string{"msie ", "trident/"}},
+}
+
+// osRules est évaluée dans l'ordre : iOS et Android avant macOS et Linux dont ils reprennent les marqueurs.
+// Le marqueur de ChromeOS inclut le séparateur qui le suit : "cros" seul apparaît dans "microsoft".
+var osRules = []rule{
+	{"Windows", []string{"windows"}},
+	{"iOS", []string{"iphone", "ipad", "ipod"}},
+	{"Android", []string{"android"}},
+	{"ChromeOS", []string{"cros ", "cros;"}},
+	{"macOS", []string{"mac os x", "macintosh"}},
+	{"Linux", []string{"linux", "x11"}},
+}
+
+// Parse analyse un en-tête User-Agent et en déduit le navigateur, le système et le type d'appareil.
+// L'analyse repose sur des marqueurs connus et ne vise pas l'exhaustivité : les valeurs
+// non reconnues sont classées dans Other / DeviceOther. La détection des robots relève
+// du package botfilter.
+func Parse(ua string) Info {
+	lower := strings.ToLower(ua)
+	info := Info{
+		Browser: match(lower, browserRules),
+		OS:      match(lower, osRules),
+	}
+
+	switch {
+	case lower == "":
+		info.Device = DeviceOther
+	case containsAny(lower, []string{"ipad", "tablet"}) ||
+		(strings.Contains(lower, "android") && !strings.Contains(lower, "mobile")):
+		info.Device = DeviceTablet
+	case containsAny(lower, []string{"mobi", "iphone", "ipod", "windows phone"}):
+		info.Device = DeviceMobile
+	case info.OS != Other:
+		info.Device = DeviceDesktop
+	default:
+		info.Device = DeviceOther
+	}
+	return info
+}
+
+// match retourne la première famille dont un marqueur apparaît dans le User-Agent (en minuscules).
+func match(lower string, rules []rule) string {
+	for _, r := range rules {
+		if containsAny(lower, r.markers) {
+			return r.family
+		}
+	}
+	return Other
+}
+
+// containsAny indique si l'un des marqueurs apparaît dans la chaîne.
+func containsAny(s string, markers []string) bool {
+	for _, m := range markers {
+		if strings.Contains(s, m) {
+			return true
+		}
+	}
+	return false
+}
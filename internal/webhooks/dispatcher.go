@@ -0,0 +1,428 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/netguard"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"github.com/axellelanca/urlshortener/internal/textutil"
+)
+
+// Réglages internes de la livraison.
+const (
+	pollInterval        = 5 * time.Second  // Intervalle de recherche des livraisons échues
+	dueBatchSize        = 100              // Livraisons échues chargées à la fois
+	deliveryConcurrency = 4                // Tentatives simultanées
+	leaseMargin         = 30 * time.Second // Marge de la réservation d'une tentative, au-delà du timeout
+	pruneInterval       = time.Hour        // Intervalle de purge des livraisons terminées
+	maxErrorLength      = 512              // Cf. la taille de models.WebhookDelivery.LastError
+	maxResponseBody     = 64 << 10         // Octets de réponse lus, pour réutiliser la connexion
+)
+
+var (
+	// ErrUnknownEndpoint est retournée lorsqu'aucun endpoint configuré ne porte le nom demandé.
+	ErrUnknownEndpoint = errors.New("unknown webhook endpoint")
+	// ErrDeliveryPending est retournée lorsqu'on rejoue une livraison qui n'est pas terminée.
+	ErrDeliveryPending = errors.New("webhook delivery is still pending")
+	// ErrDeliveryBusy est retournée lorsqu'une autre instance a commencé la même tentative.
+	ErrDeliveryBusy = errors.New("webhook delivery attempt already started elsewhere")
+)
+
+// Endpoint est un destinataire des webhooks.
+type Endpoint struct {
+	Name   string
+	URL    string
+	Secret string
+	Events []string // Événements souscrits (vide = tous)
+}
+
+// subscribes indique si l'endpoint reçoit les événements de type 'eventType'.
+func (e Endpoint) subscribes(eventType string) bool {
+	return len(e.Events) == 0 || slices.Contains(e.Events, eventType)
+}
+
+// Config regroupe les réglages du Dispatcher.
+type Config struct {
+	Endpoints      []Endpoint
+	MaxAttempts    int           // Tentatives maximum par livraison
+	InitialBackoff time.Duration // Délai avant la deuxième tentative, doublé à chaque échec
+	MaxBackoff     time.Duration // Délai maximal entre deux tentatives
+	Timeout        time.Duration // Durée maximale d'une tentative
+	Retention      time.Duration // Conservation des livraisons terminées (0 = indéfiniment)
+	BaseURL        string        // URL de base des liens courts, pour full_short_url
+	// Guard refuse les endpoints qui se résolvent vers une adresse interne, vérifiée à chaque
+	// connexion (voir netguard.Guard.NewHTTPClient). nil = aucune restriction.
+	Guard *netguard.Guard
+}
+
+// Dispatcher publie les événements des liens vers les endpoints de webhooks.
+// Chaque événement donne lieu à une livraison par endpoint abonné, enregistrée en base :
+// Publish peut donc être appelée par la CLI, la livraison étant effectuée par le serveur.
+// Start livre les livraisons échues et réessaie les échecs avec un délai exponentiel ;
+// les livraisons en attente survivent aux redémarrages. Plusieurs serveurs peuvent partager
+// la base : chaque tentative est réservée par une seule instance (voir StartAttempt).
+// Un Dispatcher nil ne publie rien.
+type Dispatcher struct {
+	repo      repository.WebhookDeliveryRepository
+	cfg       Config
+	endpoints map[string]Endpoint
+	client    *http.Client
+	logger    *slog.Logger
+
+	wake     chan struct{}      // Signale une nouvelle livraison à la boucle de Start
+	ctx      context.Context    // Annulé par Stop pour interrompre une tentative en cours
+	cancel   context.CancelFunc // Fonction d'annulation associée à ctx
+	done     chan struct{}      // Fermé à la fin de Start
+	stopOnce sync.Once
+}
+
+// NewDispatcher crée un Dispatcher livrant les événements aux endpoints de 'cfg'.
+func NewDispatcher(repo repository.WebhookDeliveryRepository, cfg Config, logger *slog.Logger) *Dispatcher {
+	endpoints := make(map[string]Endpoint, len(cfg.Endpoints))
+	for _, endpoint := range cfg.Endpoints {
+		endpoints[endpoint.Name] = endpoint
+	}
+	client := cfg.Guard.NewHTTPClient(cfg.Timeout)
+	// Une redirection ferait renvoyer l'événement en GET, sans corps : elle est traitée comme un échec.
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Dispatcher{
+		repo:      repo,
+		cfg:       cfg,
+		endpoints: endpoints,
+		client:    client,
+		logger:    logger.With("component", "webhooks"),
+		wake:      make(chan struct{}, 1),
+		ctx:       ctx,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+}
+
+// Publish enregistre une livraison de l'événement pour chaque endpoint abonné à 'eventType'.
+// 'link' et 'check' décrivent l'objet de l'événement ; 'check' peut être nil.
+// Un échec d'enregistrement est journalisé : il ne doit pas faire échouer l'opération sur le lien.
+func (d *Dispatcher) Publish(eventType string, link *models.Link, check *models.LinkCheck) {
+	if d == nil {
+		return
+	}
+	var subscribers []Endpoint
+	for _, endpoint := range d.cfg.Endpoints {
+		if endpoint.subscribes(eventType) {
+			subscribers = append(subscribers, endpoint)
+		}
+	}
+	if len(subscribers) == 0 {
+		return
+	}
+
+	event := newEvent(eventType, link, check, d.cfg.BaseURL)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("failed to encode webhook event", "event", eventType, "error", err)
+		return
+	}
+	now := time.Now()
+	deliveries := make([]*models.WebhookDelivery, 0, len(subscribers))
+	for _, endpoint := range subscribers {
+		deliveries = append(deliveries, newDelivery(endpoint, event, payload, now))
+	}
+	if err := d.repo.CreateDeliveries(deliveries); err != nil {
+		d.logger.Error("failed to queue webhook event", "event", eventType, "event_id", event.ID, "error", err)
+		return
+	}
+	metrics.WebhookEventsTotal.WithLabelValues(eventType).Inc()
+	d.logger.Debug("webhook event queued", "event", eventType, "event_id", event.ID, "endpoints", len(deliveries))
+
+	select {
+	case d.wake <- struct{}{}:
+	default: // Un réveil est déjà en attente.
+	}
+}
+
+// newDelivery crée une livraison en attente de l'événement vers 'endpoint', à tenter à partir de 'nextAttempt'.
+func newDelivery(endpoint Endpoint, event Event, payload []byte, nextAttempt time.Time) *models.WebhookDelivery {
+	return &models.WebhookDelivery{
+		Endpoint:      endpoint.Name,
+		URL:           endpoint.URL,
+		Event:         event.Type,
+		EventID:       event.ID,
+		Payload:       string(payload),
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &nextAttempt,
+		CreatedAt:     event.CreatedAt,
+	}
+}
+
+// Start livre les livraisons échues dès le démarrage, puis à chaque nouvel événement et toutes
+// les pollInterval, jusqu'à l'appel de Stop. Les livraisons terminées plus anciennes que
+// cfg.Retention sont purgées toutes les heures.
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (d *Dispatcher) Start() {
+	defer close(d.done)
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		d.deliverDue()
+		if d.cfg.Retention > 0 && time.Since(lastPrune) >= pruneInterval {
+			d.prune()
+			lastPrune = time.Now()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-d.wake:
+		case <-d.ctx.Done():
+			d.logger.Info("webhook dispatcher stopped")
+			return
+		}
+	}
+}
+
+// Stop arrête la recherche de livraisons, interrompt les tentatives en cours et attend la fin de Start,
+// ou l'expiration de ctx. Une tentative interrompue reste en attente et est reprise au démarrage suivant ;
+// si le processus s'arrête avant de l'avoir enregistrée, elle l'est après expiration de sa réservation.
+func (d *Dispatcher) Stop(ctx context.Context) error {
+	d.stopOnce.Do(d.cancel)
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// stopping indique si Stop a été appelée.
+func (d *Dispatcher) stopping() bool {
+	return d.ctx.Err() != nil
+}
+
+// deliverDue tente les livraisons échues, deliveryConcurrency à la fois, par lots de dueBatchSize.
+func (d *Dispatcher) deliverDue() {
+	for !d.stopping() {
+		due, err := d.repo.ListDueDeliveries(time.Now(), dueBatchSize)
+		if err != nil {
+			d.logger.Error("failed to load due webhook deliveries", "error", err)
+			return
+		}
+
+		slots := make(chan struct{}, deliveryConcurrency)
+		var wg sync.WaitGroup
+		for i := range due {
+			if d.stopping() {
+				break
+			}
+			slots <- struct{}{}
+			wg.Add(1)
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				if err := d.attempt(delivery, true); err != nil && !errors.Is(err, ErrDeliveryBusy) {
+					d.logger.Error("failed to attempt webhook delivery", "delivery_id", delivery.ID, "error", err)
+				}
+			}(&due[i])
+		}
+		wg.Wait()
+
+		if len(due) < dueBatchSize {
+			return
+		}
+	}
+}
+
+// attempt effectue une tentative de livraison et enregistre son résultat dans 'delivery'.
+// Sans 'retry', un échec est définitif. L'échec de l'envoi est enregistré dans la livraison ;
+// l'erreur retournée est ErrDeliveryBusy si une autre instance a commencé la tentative,
+// ou une erreur d'accès à la base.
+func (d *Dispatcher) attempt(delivery *models.WebhookDelivery, retry bool) error {
+	now := time.Now()
+	started, err := d.repo.StartAttempt(delivery, now, now.Add(d.cfg.Timeout+leaseMargin))
+	if err != nil {
+		return err
+	}
+	if !started {
+		return ErrDeliveryBusy
+	}
+
+	var sendErr error
+	endpoint, configured := d.endpoints[delivery.Endpoint]
+	if configured {
+		delivery.URL = endpoint.URL
+		delivery.ResponseCode, sendErr = d.send(endpoint, delivery)
+	} else {
+		// Endpoint retiré de la configuration depuis la création de la livraison.
+		delivery.ResponseCode = 0
+		sendErr = fmt.Errorf("endpoint '%s' is not configured", delivery.Endpoint)
+		retry = false
+	}
+
+	logger := d.logger.With("delivery_id", delivery.ID, "endpoint", delivery.Endpoint,
+		"event", delivery.Event, "attempt", delivery.Attempts)
+	finished := time.Now()
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		delivery.DeliveredAt = &finished
+		metrics.WebhookAttemptsTotal.WithLabelValues(metrics.WebhookSucceeded).Inc()
+		logger.Debug("webhook delivered", "status_code", delivery.ResponseCode)
+	case retry && d.stopping() && errors.Is(sendErr, context.Canceled):
+		// Tentative interrompue par Stop : elle ne compte pas comme un échec de l'endpoint
+		// et sera reprise dès le démarrage suivant, même s'il s'agissait de la dernière.
+		delivery.NextAttemptAt = &finished
+		delivery.LastError = textutil.Truncate(sendErr.Error(), maxErrorLength)
+		logger.Info("webhook delivery attempt interrupted by shutdown, will resume", "error", sendErr)
+	case retry && delivery.Attempts < d.cfg.MaxAttempts:
+		next := finished.Add(d.backoff(delivery.Attempts))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = textutil.Truncate(sendErr.Error(), maxErrorLength)
+		metrics.WebhookAttemptsTotal.WithLabelValues(metrics.WebhookFailed).Inc()
+		logger.Warn("webhook delivery attempt failed, will retry",
+			"status_code", delivery.ResponseCode, "next_attempt_at", next, "error", sendErr)
+	default:
+		delivery.Status = models.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = textutil.Truncate(sendErr.Error(), maxErrorLength)
+		metrics.WebhookAttemptsTotal.WithLabelValues(metrics.WebhookFailed).Inc()
+		metrics.WebhookDeliveriesFailedTotal.Inc()
+		logger.Error("webhook delivery failed", "status_code", delivery.ResponseCode, "error", sendErr)
+	}
+	return d.repo.FinishAttempt(delivery)
+}
+
+// send envoie la livraison en POST à l'endpoint, signée avec son secret.
+// Retourne le code HTTP reçu (0 sans réponse) et une erreur si ce code n'est pas 2xx.
+func (d *Dispatcher) send(endpoint Endpoint, delivery *models.WebhookDelivery) (int, error) {
+	start := time.Now()
+	defer func() { metrics.WebhookAttemptDuration.Observe(time.Since(start).Seconds()) }()
+
+	body := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderDelivery, strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, "sha256="+Sign(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff retourne le délai avant la tentative suivant la tentative numéro 'attempts' :
+// cfg.InitialBackoff doublé à chaque échec, borné par cfg.MaxBackoff.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.cfg.InitialBackoff
+	for i := 1; i < attempts && delay < d.cfg.MaxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, d.cfg.MaxBackoff)
+}
+
+// prune supprime les livraisons terminées plus anciennes que cfg.Retention.
+func (d *Dispatcher) prune() {
+	deleted, err := d.repo.DeleteDeliveriesBefore(time.Now().Add(-d.cfg.Retention))
+	if err != nil {
+		d.logger.Error("failed to prune webhook deliveries", "error", err)
+		return
+	}
+	if deleted > 0 {
+		d.logger.Debug("old webhook deliveries pruned", "deleted", deleted, "retention", d.cfg.Retention)
+	}
+}
+
+// Test envoie immédiatement un événement webhook.test à l'endpoint 'name', quels que soient
+// ses abonnements. La livraison est enregistrée dans le journal mais n'est pas réessayée.
+func (d *Dispatcher) Test(name string) (*models.WebhookDelivery, error) {
+	endpoint, ok := d.endpoints[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownEndpoint, name)
+	}
+	event := newEvent(models.EventWebhookTest, nil, nil, d.cfg.BaseURL)
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode webhook event: %w", err)
+	}
+	return d.deliverNow(newDelivery(endpoint, event, payload, d.reservedUntil()), false)
+}
+
+// Replay crée une nouvelle livraison du même événement, vers le même endpoint et avec le même
+// corps que la livraison 'id', puis effectue sa première tentative immédiatement. En cas d'échec,
+// elle est réessayée par le serveur comme toute livraison. Une livraison en attente ne peut pas
+// être rejouée (ErrDeliveryPending), ni une livraison vers un endpoint retiré de la configuration
+// (ErrUnknownEndpoint) ; une livraison inconnue retourne gorm.ErrRecordNotFound.
+func (d *Dispatcher) Replay(id uint) (*models.WebhookDelivery, error) {
+	original, err := d.repo.GetDelivery(id)
+	if err != nil {
+		return nil, err
+	}
+	if original.Status == models.DeliveryPending {
+		return nil, fmt.Errorf("%w: %d", ErrDeliveryPending, id)
+	}
+	if _, ok := d.endpoints[original.Endpoint]; !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnknownEndpoint, original.Endpoint)
+	}
+
+	nextAttempt := d.reservedUntil()
+	delivery := &models.WebhookDelivery{
+		Endpoint:      original.Endpoint,
+		URL:           original.URL,
+		Event:         original.Event,
+		EventID:       original.EventID,
+		Payload:       original.Payload,
+		Status:        models.DeliveryPending,
+		NextAttemptAt: &nextAttempt,
+		ReplayOf:      &original.ID,
+		CreatedAt:     time.Now().UTC(),
+	}
+	return d.deliverNow(delivery, true)
+}
+
+// reservedUntil retourne la date de première tentative d'une livraison tentée immédiatement par
+// l'appelant : le serveur ne la tente pas entre-temps, mais la reprend si l'appelant s'arrête avant.
+func (d *Dispatcher) reservedUntil() time.Time {
+	return time.Now().Add(d.cfg.Timeout + leaseMargin)
+}
+
+// deliverNow enregistre la livraison puis effectue sa première tentative.
+func (d *Dispatcher) deliverNow(delivery *models.WebhookDelivery, retry bool) (*models.WebhookDelivery, error) {
+	if err := d.repo.CreateDeliveries([]*models.WebhookDelivery{delivery}); err != nil {
+		return nil, err
+	}
+	if err := d.attempt(delivery, retry); err != nil {
+		return delivery, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,391 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/netguard"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+const testSecret = "0123456789abcdef-secret"
+
+// receivedRequest est un envoi reçu par un receiver.
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver est un endpoint de test qui enregistre les envois reçus et répond avec 'status'.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	status   int
+	requests []receivedRequest
+}
+
+func newReceiver(t *testing.T, status int) *receiver {
+	t.Helper()
+	r := &receiver{status: status}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		status := r.status
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func (r *receiver) setStatus(status int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.status = status
+}
+
+// newTestDispatcher crée un Dispatcher sur 'db' avec des réglages de test ; 'cfg' complète les endpoints.
+func newTestDispatcher(db *gorm.DB, cfg Config) *Dispatcher {
+	if cfg.MaxAttempts == 0 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.InitialBackoff == 0 {
+		cfg.InitialBackoff = time.Minute
+		cfg.MaxBackoff = 5 * time.Minute
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	cfg.BaseURL = "http://short.test"
+	return NewDispatcher(repository.NewWebhookDeliveryRepository(db), cfg, slog.New(slog.DiscardHandler))
+}
+
+// listDeliveries retourne toutes les livraisons, de la plus récente à la plus ancienne.
+func listDeliveries(t *testing.T, d *Dispatcher) []models.WebhookDelivery {
+	t.Helper()
+	deliveries, err := d.repo.ListDeliveries(repository.WebhookDeliveryFilter{})
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	return deliveries
+}
+
+var testLink = &models.Link{ShortCode: "docs", LongURL: "https://example.com/docs", CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)}
+
+func TestDispatcherSignsDeliveries(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		subscribed := newReceiver(t, http.StatusNoContent)
+		other := newReceiver(t, http.StatusNoContent)
+		d := newTestDispatcher(db, Config{Endpoints: []Endpoint{
+			{Name: "alerts", URL: subscribed.URL, Secret: testSecret, Events: []string{models.EventLinkCreated}},
+			{Name: "other", URL: other.URL, Secret: testSecret, Events: []string{models.EventLinkDown}},
+		}})
+
+		d.Publish(models.EventLinkCreated, testLink, nil)
+		d.deliverDue()
+
+		if got := len(other.received()); got != 0 {
+			t.Errorf("unsubscribed endpoint received %d requests, want 0", got)
+		}
+		requests := subscribed.received()
+		if len(requests) != 1 {
+			t.Fatalf("subscribed endpoint received %d requests, want 1", len(requests))
+		}
+		req := requests[0]
+
+		timestamp, err := strconv.ParseInt(req.header.Get(HeaderTimestamp), 10, 64)
+		if err != nil {
+			t.Fatalf("invalid %s header %q", HeaderTimestamp, req.header.Get(HeaderTimestamp))
+		}
+		if got, want := req.header.Get(HeaderSignature), "sha256="+Sign(testSecret, timestamp, req.body); got != want {
+			t.Errorf("%s = %q, want %q", HeaderSignature, got, want)
+		}
+		if got := req.header.Get(HeaderSignature); got == "sha256="+Sign("another-secret-value", timestamp, req.body) {
+			t.Error("signature does not depend on the endpoint secret")
+		}
+
+		var event Event
+		if err := json.Unmarshal(req.body, &event); err != nil {
+			t.Fatalf("invalid body: %v", err)
+		}
+		if event.Type != models.EventLinkCreated || event.Data.Link == nil || event.Data.Link.FullShortURL != "http://short.test/docs" {
+			t.Errorf("event = %+v, want link.created for http://short.test/docs", event)
+		}
+
+		deliveries := listDeliveries(t, d)
+		if len(deliveries) != 1 {
+			t.Fatalf("%d deliveries, want 1", len(deliveries))
+		}
+		delivery := deliveries[0]
+		for header, want := range map[string]string{
+			HeaderEvent:    models.EventLinkCreated,
+			HeaderEventID:  event.ID,
+			HeaderDelivery: strconv.FormatUint(uint64(delivery.ID), 10),
+			"Content-Type": "application/json",
+			"User-Agent":   userAgent,
+		} {
+			if got := req.header.Get(header); got != want {
+				t.Errorf("%s = %q, want %q", header, got, want)
+			}
+		}
+		if delivery.Status != models.DeliverySucceeded || delivery.Attempts != 1 || delivery.ResponseCode != http.StatusNoContent {
+			t.Errorf("delivery = status %s, %d attempts, code %d; want succeeded, 1, 204",
+				delivery.Status, delivery.Attempts, delivery.ResponseCode)
+		}
+		if delivery.EventID != event.ID || delivery.Payload != string(req.body) {
+			t.Error("recorded delivery does not match the sent event")
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{cfg: Config{InitialBackoff: 10 * time.Second, MaxBackoff: time.Minute}}
+	for attempts, want := range map[int]time.Duration{
+		1: 10 * time.Second,
+		2: 20 * time.Second,
+		3: 40 * time.Second,
+		4: time.Minute,
+		9: time.Minute,
+	} {
+		if got := d.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
+
+func TestDispatcherRetriesWithBackoff(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		endpoint := newReceiver(t, http.StatusInternalServerError)
+		d := newTestDispatcher(db, Config{
+			Endpoints:      []Endpoint{{Name: "alerts", URL: endpoint.URL, Secret: testSecret}},
+			MaxAttempts:    3,
+			InitialBackoff: time.Minute,
+			MaxBackoff:     90 * time.Second,
+		})
+		d.Publish(models.EventLinkDown, testLink, nil)
+
+		for attempt, wantDelay := range []time.Duration{time.Minute, 90 * time.Second} {
+			delivery := listDeliveries(t, d)[0]
+			before := time.Now()
+			if err := d.attempt(&delivery, true); err != nil {
+				t.Fatalf("attempt %d: %v", attempt+1, err)
+			}
+			stored, err := d.repo.GetDelivery(delivery.ID)
+			if err != nil {
+				t.Fatalf("GetDelivery: %v", err)
+			}
+			if stored.Status != models.DeliveryPending || stored.Attempts != attempt+1 || stored.ResponseCode != http.StatusInternalServerError {
+				t.Fatalf("after attempt %d: status %s, %d attempts, code %d; want pending, %d, 500",
+					attempt+1, stored.Status, stored.Attempts, stored.ResponseCode, attempt+1)
+			}
+			if !strings.Contains(stored.LastError, "500") {
+				t.Errorf("after attempt %d: LastError = %q, want the response status", attempt+1, stored.LastError)
+			}
+			if stored.NextAttemptAt == nil || stored.NextAttemptAt.Before(before.Add(wantDelay-time.Second)) ||
+				stored.NextAttemptAt.After(time.Now().Add(wantDelay+time.Second)) {
+				t.Errorf("after attempt %d: NextAttemptAt = %v, want about now + %v", attempt+1, stored.NextAttemptAt, wantDelay)
+			}
+			// La livraison n'est pas échue avant la fin du délai.
+			if due, err := d.repo.ListDueDeliveries(time.Now(), 10); err != nil || len(due) != 0 {
+				t.Errorf("after attempt %d: %d due deliveries (err %v), want 0", attempt+1, len(due), err)
+			}
+		}
+
+		delivery := listDeliveries(t, d)[0]
+		if err := d.attempt(&delivery, true); err != nil {
+			t.Fatalf("last attempt: %v", err)
+		}
+		stored, err := d.repo.GetDelivery(delivery.ID)
+		if err != nil {
+			t.Fatalf("GetDelivery: %v", err)
+		}
+		if stored.Status != models.DeliveryFailed || stored.Attempts != 3 || stored.NextAttemptAt != nil {
+			t.Errorf("after the last attempt: status %s, %d attempts, next %v; want failed, 3, nil",
+				stored.Status, stored.Attempts, stored.NextAttemptAt)
+		}
+		if got := len(endpoint.received()); got != 3 {
+			t.Errorf("endpoint received %d requests, want 3", got)
+		}
+	})
+}
+
+func TestDispatcherAttemptLease(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		var d *Dispatcher
+		var dueDuringAttempt []models.WebhookDelivery
+		var hits int
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits++
+			// Pendant la tentative, la livraison est réservée : aucune instance ne la voit échue.
+			dueDuringAttempt, _ = d.repo.ListDueDeliveries(time.Now(), 10)
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(server.Close)
+		d = newTestDispatcher(db, Config{Endpoints: []Endpoint{{Name: "alerts", URL: server.URL, Secret: testSecret}}})
+		d.Publish(models.EventLinkCreated, testLink, nil)
+
+		// Deux instances ont chargé la même livraison échue.
+		first := listDeliveries(t, d)[0]
+		second := first
+		if err := d.attempt(&first, true); err != nil {
+			t.Fatalf("first attempt: %v", err)
+		}
+		if err := d.attempt(&second, true); !errors.Is(err, ErrDeliveryBusy) {
+			t.Errorf("second attempt error = %v, want ErrDeliveryBusy", err)
+		}
+		if hits != 1 {
+			t.Errorf("endpoint received %d requests, want 1", hits)
+		}
+		if len(dueDuringAttempt) != 0 {
+			t.Errorf("%d due deliveries during the attempt, want 0", len(dueDuringAttempt))
+		}
+		if stored := listDeliveries(t, d)[0]; stored.Status != models.DeliverySucceeded || stored.Attempts != 1 {
+			t.Errorf("delivery = status %s, %d attempts; want succeeded, 1", stored.Status, stored.Attempts)
+		}
+	})
+}
+
+func TestDispatcherReplay(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		endpoint := newReceiver(t, http.StatusOK)
+		d := newTestDispatcher(db, Config{Endpoints: []Endpoint{{Name: "alerts", URL: endpoint.URL, Secret: testSecret}}})
+		d.Publish(models.EventLinkDeleted, testLink, nil)
+		pending := listDeliveries(t, d)[0]
+
+		if _, err := d.Replay(pending.ID); !errors.Is(err, ErrDeliveryPending) {
+			t.Errorf("Replay of a pending delivery error = %v, want ErrDeliveryPending", err)
+		}
+		if _, err := d.Replay(pending.ID + 100); !errors.Is(err, gorm.ErrRecordNotFound) {
+			t.Errorf("Replay of an unknown delivery error = %v, want gorm.ErrRecordNotFound", err)
+		}
+
+		d.deliverDue()
+		endpoint.setStatus(http.StatusServiceUnavailable)
+		replay, err := d.Replay(pending.ID)
+		if err != nil {
+			t.Fatalf("Replay: %v", err)
+		}
+		if replay.ID == pending.ID || replay.ReplayOf == nil || *replay.ReplayOf != pending.ID {
+			t.Errorf("replay = ID %d, ReplayOf %v; want a new delivery replaying %d", replay.ID, replay.ReplayOf, pending.ID)
+		}
+		// Échec de la première tentative : le renvoi reste en attente et sera réessayé par le serveur.
+		if replay.Status != models.DeliveryPending || replay.Attempts != 1 || replay.NextAttemptAt == nil {
+			t.Errorf("replay = status %s, %d attempts, next %v; want pending, 1, set", replay.Status, replay.Attempts, replay.NextAttemptAt)
+		}
+
+		requests := endpoint.received()
+		if len(requests) != 2 {
+			t.Fatalf("endpoint received %d requests, want 2", len(requests))
+		}
+		if string(requests[0].body) != string(requests[1].body) ||
+			requests[0].header.Get(HeaderEventID) != requests[1].header.Get(HeaderEventID) {
+			t.Error("replay does not resend the same event")
+		}
+		if requests[0].header.Get(HeaderDelivery) == requests[1].header.Get(HeaderDelivery) {
+			t.Error("replay reuses the original delivery ID")
+		}
+
+		removed := newTestDispatcher(db, Config{Endpoints: []Endpoint{{Name: "other", URL: endpoint.URL, Secret: testSecret}}})
+		if _, err := removed.Replay(pending.ID); !errors.Is(err, ErrUnknownEndpoint) {
+			t.Errorf("Replay to a removed endpoint error = %v, want ErrUnknownEndpoint", err)
+		}
+	})
+}
+
+func TestDispatcherStopCancelsAttempt(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		started, release := make(chan struct{}), make(chan struct{})
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Le corps est lu pour que la déconnexion du client annule r.Context().
+			_, _ = io.Copy(io.Discard, r.Body)
+			close(started)
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		}))
+		t.Cleanup(server.Close)
+		t.Cleanup(func() { close(release) })
+		d := newTestDispatcher(db, Config{
+			Endpoints:   []Endpoint{{Name: "alerts", URL: server.URL, Secret: testSecret}},
+			MaxAttempts: 1,
+			Timeout:     time.Minute,
+		})
+		d.Publish(models.EventLinkCreated, testLink, nil)
+		go d.Start()
+
+		select {
+		case <-started:
+		case <-time.After(10 * time.Second):
+			t.Fatal("delivery attempt not started")
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := d.Stop(ctx); err != nil {
+			t.Fatalf("Stop did not interrupt the attempt: %v", err)
+		}
+
+		// Même à la dernière tentative, une livraison interrompue reste en attente et est échue aussitôt.
+		delivery := listDeliveries(t, d)[0]
+		if delivery.Status != models.DeliveryPending || delivery.Attempts != 1 {
+			t.Errorf("delivery = status %s, %d attempts; want pending, 1", delivery.Status, delivery.Attempts)
+		}
+		if delivery.NextAttemptAt == nil || delivery.NextAttemptAt.After(time.Now()) {
+			t.Errorf("NextAttemptAt = %v, want due now", delivery.NextAttemptAt)
+		}
+	})
+}
+
+func TestDispatcherNetworkGuard(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		endpoint := newReceiver(t, http.StatusOK)
+		endpoints := []Endpoint{{Name: "internal", URL: endpoint.URL, Secret: testSecret}}
+
+		guard, err := netguard.New(nil)
+		if err != nil {
+			t.Fatalf("netguard.New: %v", err)
+		}
+		blocked, err := newTestDispatcher(db, Config{Endpoints: endpoints, Guard: guard}).Test("internal")
+		if err != nil {
+			t.Fatalf("Test with guard: %v", err)
+		}
+		if blocked.Status != models.DeliveryFailed || !strings.Contains(blocked.LastError, netguard.ErrBlockedAddress.Error()) {
+			t.Errorf("delivery to a loopback endpoint = status %s, error %q; want failed and blocked", blocked.Status, blocked.LastError)
+		}
+		if got := len(endpoint.received()); got != 0 {
+			t.Fatalf("blocked endpoint received %d requests, want 0", got)
+		}
+
+		allowing, err := netguard.New([]string{"127.0.0.1"})
+		if err != nil {
+			t.Fatalf("netguard.New: %v", err)
+		}
+		allowed, err := newTestDispatcher(db, Config{Endpoints: endpoints, Guard: allowing}).Test("internal")
+		if err != nil {
+			t.Fatalf("Test with allowed network: %v", err)
+		}
+		if allowed.Status != models.DeliverySucceeded {
+			t.Errorf("delivery to an allowed endpoint = status %s, error %q; want succeeded", allowed.Status, allowed.LastError)
+		}
+	})
+}
@@ -0,0 +1,100 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+// En-têtes HTTP ajoutés à chaque envoi.
+const (
+	HeaderEvent     = "X-Webhook-Event"     // Type d'événement
+	HeaderEventID   = "X-Webhook-Id"        // Identifiant de l'événement, identique pour toutes ses livraisons
+	HeaderDelivery  = "X-Webhook-Delivery"  // Identifiant de la livraison dans le journal
+	HeaderTimestamp = "X-Webhook-Timestamp" // Date de la tentative, en secondes Unix
+	HeaderSignature = "X-Webhook-Signature" // "sha256=" suivi de Sign(secret, timestamp, corps)
+)
+
+// userAgent identifie les envois auprès des destinataires.
+const userAgent = "urlshortener-webhooks/1.0"
+
+// Event est le corps JSON envoyé aux endpoints.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      EventData `json:"data"`
+}
+
+// EventData détaille l'objet de l'événement.
+type EventData struct {
+	Link  *LinkData  `json:"link,omitempty"`  // Absent pour webhook.test
+	Check *CheckData `json:"check,omitempty"` // Vérification à l'origine de link.up et link.down
+}
+
+// LinkData décrit le lien concerné par un événement, au format de l'API.
+type LinkData struct {
+	ShortCode    string     `json:"short_code"`
+	FullShortURL string     `json:"full_short_url"`
+	LongURL      string     `json:"long_url"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxClicks    int        `json:"max_clicks"`
+}
+
+// CheckData décrit une vérification du moniteur, au format de l'API de santé des liens.
+type CheckData struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	Healthy    bool      `json:"healthy"`
+	StatusCode int       `json:"status_code"`
+	LatencyMs  int64     `json:"latency_ms"`
+	ErrorClass string    `json:"error_class"`
+}
+
+// newEvent construit un événement ; 'link' et 'check' peuvent être nil.
+func newEvent(eventType string, link *models.Link, check *models.LinkCheck, baseURL string) Event {
+	event := Event{ID: newEventID(), Type: eventType, CreatedAt: time.Now().UTC()}
+	if link != nil {
+		event.Data.Link = &LinkData{
+			ShortCode:    link.ShortCode,
+			FullShortURL: baseURL + "/" + link.ShortCode,
+			LongURL:      link.LongURL,
+			CreatedAt:    link.CreatedAt,
+			ExpiresAt:    link.ExpiresAt,
+			MaxClicks:    link.MaxClicks,
+		}
+	}
+	if check != nil {
+		event.Data.Check = &CheckData{
+			CheckedAt:  check.CheckedAt,
+			Healthy:    check.Healthy,
+			StatusCode: check.StatusCode,
+			LatencyMs:  check.LatencyMs,
+			ErrorClass: check.ErrorClass,
+		}
+	}
+	return event
+}
+
+// newEventID génère un identifiant d'événement aléatoire de 32 caractères hexadécimaux.
+func newEventID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b) // crypto/rand.Read ne retourne jamais d'erreur
+	return hex.EncodeToString(b)
+}
+
+// Sign calcule la signature d'un envoi : HMAC-SHA256, avec le secret de l'endpoint, de la date
+// de la tentative en secondes Unix, d'un point et du corps, encodé en hexadécimal.
+// Le destinataire recalcule la signature et rejette les envois dont la date est trop ancienne.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -1,45 +1,276 @@
 package workers
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/metrics"
 	"github.com/axellelanca/urlshortener/internal/models"
 	"github.com/axellelanca/urlshortener/internal/repository" // Nécessaire pour interagir avec le ClickRepository
+	"github.com/axellelanca/urlshortener/internal/textutil"
+	"github.com/axellelanca/urlshortener/internal/useragent"
 )
 
+// Tailles des colonnes texte de models.Click.
+const (
+	maxUserAgentLength    = 255
+	maxReferrerLength     = 512
+	maxReferrerHostLength = 255
+)
+
+// ClickWorkerConfig regroupe les paramètres du pool de workers de clics.
+type ClickWorkerConfig struct {
+	WorkerCount   int           // Nombre de goroutines workers
+	BatchSize     int           // Nombre maximal de clics par insertion groupée
+	FlushInterval time.Duration // Délai maximal entre la réception d'un clic et son enregistrement
+}
+
+// ClickWorkerPool regroupe les goroutines "workers" qui persistent les événements de clic.
+// Chaque worker accumule les clics reçus et les enregistre par lots, dès que le lot atteint
+// BatchSize clics ou que le plus ancien clic du lot attend depuis FlushInterval.
+// Les workers s'arrêtent lorsque le channel d'événements est fermé et entièrement vidé :
+// Wait permet d'attendre cette fin lors de l'arrêt du serveur.
+type ClickWorkerPool struct {
+	events        <-chan models.ClickEvent
+	clickRepo     repository.ClickRepository
+	classifier    *botfilter.Classifier
+	hasher        *VisitorHasher
+	batchSize     int
+	flushInterval time.Duration
+	logger        *slog.Logger
+
+	mu     sync.Mutex      // Protège quits et nextID lors des redimensionnements
+	quits  []chan struct{} // Un channel d'arrêt par worker actif, fermé pour arrêter ce worker
+	nextID int             // Identifiant du prochain worker lancé
+
+	wg        sync.WaitGroup
+	persisted atomic.Uint64 // Nombre de clics enregistrés avec succès
+	failed    atomic.Uint64 // Nombre de clics dont l'enregistrement a échoué
+}
+
 // StartClickWorkers lance un pool de goroutines "workers" pour traiter les événements de clic.
 // Chaque worker lira depuis le même 'clickEventsChan' et utilisera le 'clickRepo' pour la persistance.
-func StartClickWorkers(workerCount int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
-	log.Printf("Starting %d click worker(s)...", workerCount)
-	for i := 0; i < workerCount; i++ {
-		// Lance chaque worker dans sa propre goroutine.
-		// Le channel est passé en lecture seule (<-chan) pour renforcer l'immutabilité du channel à l'intérieur du worker.
-		go clickWorker(i, clickEventsChan, clickRepo)
+// Le 'classifier' détermine quels clics proviennent de robots et le 'hasher' calcule l'empreinte
+// anonyme des visiteurs utilisée pour compter les visiteurs uniques.
+func StartClickWorkers(cfg ClickWorkerConfig, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository, classifier *botfilter.Classifier, hasher *VisitorHasher, logger *slog.Logger) *ClickWorkerPool {
+	pool := &ClickWorkerPool{
+		events:        clickEventsChan,
+		clickRepo:     clickRepo,
+		classifier:    classifier,
+		hasher:        hasher,
+		batchSize:     max(cfg.BatchSize, 1),
+		flushInterval: cfg.FlushInterval,
+		logger:        logger.With("component", "click_workers"),
+	}
+
+	pool.logger.Info("starting click workers",
+		"workers", cfg.WorkerCount, "batch_size", pool.batchSize, "flush_interval", pool.flushInterval)
+	pool.mu.Lock()
+	pool.startWorkers(cfg.WorkerCount)
+	pool.mu.Unlock()
+	return pool
+}
+
+// Resize ajuste le nombre de workers à 'count' et retourne le nombre précédent.
+// Les workers retirés enregistrent leur lot en cours avant de s'arrêter.
+// Resize ne doit pas être appelée après la fermeture du channel d'événements.
+func (p *ClickWorkerPool) Resize(count int) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	previous := len(p.quits)
+	if count > previous {
+		p.startWorkers(count - previous)
 	}
+	for len(p.quits) > count {
+		last := len(p.quits) - 1
+		close(p.quits[last])
+		p.quits = p.quits[:last]
+	}
+	if count != previous {
+		p.logger.Info("click worker pool resized", "previous_workers", previous, "workers", count)
+	}
+	return previous
+}
+
+// Size retourne le nombre de workers actifs.
+func (p *ClickWorkerPool) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.quits)
+}
+
+// startWorkers lance 'count' workers supplémentaires. p.mu doit être verrouillé.
+func (p *ClickWorkerPool) startWorkers(count int) {
+	for i := 0; i < count; i++ {
+		// Lance chaque worker dans sa propre goroutine, avec son propre channel d'arrêt.
+		quit := make(chan struct{})
+		p.quits = append(p.quits, quit)
+		p.wg.Add(1)
+		go p.clickWorker(p.nextID, quit)
+		p.nextID++
+	}
+}
+
+// Wait bloque jusqu'à l'arrêt de tous les workers, c'est-à-dire jusqu'à ce que le channel
+// d'événements ait été fermé et vidé, ou jusqu'à l'expiration du contexte.
+func (p *ClickWorkerPool) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Persisted retourne le nombre de clics enregistrés avec succès depuis le démarrage du pool.
+func (p *ClickWorkerPool) Persisted() uint64 {
+	return p.persisted.Load()
+}
+
+// Failed retourne le nombre de clics dont l'enregistrement a échoué depuis le démarrage du pool.
+func (p *ClickWorkerPool) Failed() uint64 {
+	return p.failed.Load()
 }
 
 // clickWorker est la fonction exécutée par chaque goroutine worker.
-// Elle tourne indéfiniment, lisant les événements de clic dès qu'ils sont disponibles dans le channel.
-func clickWorker(id int, clickEventsChan <-chan models.ClickEvent, clickRepo repository.ClickRepository) {
-	for event := range clickEventsChan { // Boucle qui lit les événements du channel
-		// Convertir le 'ClickEvent' (reçu du channel) en un modèle 'models.Click'.
-		click := &models.Click{
-			LinkID:    event.LinkID,
-			Timestamp: event.Timestamp,
-			UserAgent: event.UserAgent,
-			IPAddress: event.IP,
+// Elle lit les événements de clic dès qu'ils sont disponibles dans le channel et les accumule
+// dans un lot, enregistré lorsqu'il est plein ou que son délai maximal est écoulé.
+// À la fermeture du channel, ou lorsque 'quit' est fermé par Resize, le lot en cours est
+// enregistré avant l'arrêt du worker.
+func (p *ClickWorkerPool) clickWorker(id int, quit <-chan struct{}) {
+	defer p.wg.Done()
+	logger := p.logger.With("worker", id)
+
+	batch := make([]models.Click, 0, p.batchSize)
+	timer := time.NewTimer(p.flushInterval)
+	timer.Stop()
+	var flushC <-chan time.Time // nil tant que le lot est vide : le cas du select est alors désactivé
+
+	flush := func() {
+		timer.Stop()
+		flushC = nil
+		p.persistBatch(logger, batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event, ok := <-p.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, p.toClick(logger, event))
+			if len(batch) == 1 {
+				// Premier clic du lot : démarrer le délai maximal d'attente.
+				timer.Reset(p.flushInterval)
+				flushC = timer.C
+			}
+			if len(batch) >= p.batchSize {
+				flush()
+			}
+		case <-flushC:
+			flush()
+		case <-quit:
+			flush()
+			logger.Debug("click worker stopped by resize")
+			return
 		}
+	}
+}
+
+// toClick convertit un 'ClickEvent' (reçu du channel) en un modèle 'models.Click' prêt à être enregistré.
+func (p *ClickWorkerPool) toClick(logger *slog.Logger, event models.ClickEvent) models.Click {
+	click := newClick(event, p.classifier)
+
+	// Calculer l'empreinte anonyme du visiteur. En cas d'échec, le clic est tout de même
+	// enregistré mais ne compte pas dans les visiteurs uniques.
+	visitorHash, err := p.hasher.Hash(event.IP, event.UserAgent, event.Timestamp)
+	if err != nil {
+		logger.Warn("failed to compute visitor hash", "link_id", event.LinkID, "error", err)
+	}
+	click.VisitorHash = visitorHash
+	return *click
+}
 
-		// Persister le clic en base de données via le 'clickRepo' (CreateClick).
-		err := clickRepo.CreateClick(click)
-		if err != nil {
-			// Si une erreur se produit lors de l'enregistrement, logguez-la.
-			// L'événement est "perdu" pour ce TP, mais dans un vrai système,
-			// vous pourriez le remettre dans une file de retry ou une file d'erreurs.
-			log.Printf("[worker %d] ERROR: impossible d'enregistrer le clic: %v", id, err)
-		} else {
-			// Log optionnel pour confirmer l'enregistrement (utile pour le débogage)
-			log.Printf("[worker %d] Click enregistré pour LinkID=%d", id, event.LinkID)
+// persistBatch enregistre un lot de clics via le 'clickRepo' (CreateClicks).
+// Si l'insertion groupée échoue, les clics sont réessayés un par un pour ne perdre
+// que ceux qui sont réellement en erreur.
+func (p *ClickWorkerPool) persistBatch(logger *slog.Logger, batch []models.Click) {
+	if len(batch) == 0 {
+		return
+	}
+
+	err := p.clickRepo.CreateClicks(batch)
+	if err == nil {
+		p.persisted.Add(uint64(len(batch)))
+		metrics.ClicksPersistedTotal.Add(float64(len(batch)))
+		logger.Debug("click batch persisted", "clicks", len(batch))
+		return
+	}
+	metrics.ClickInsertErrorsTotal.WithLabelValues("batch").Inc()
+	logger.Warn("batch insert failed, retrying clicks one by one", "clicks", len(batch), "error", err)
+
+	for i := range batch {
+		// L'insertion groupée annulée a pu renseigner les ID : ils ne correspondent à aucune ligne
+		// et provoqueraient des conflits de clé primaire.
+		batch[i].ID = 0
+		if err := p.clickRepo.CreateClick(&batch[i]); err != nil {
+			// L'événement est perdu : dans un vrai système, il pourrait être placé dans une file d'erreurs.
+			p.failed.Add(1)
+			metrics.ClickInsertErrorsTotal.WithLabelValues("single").Inc()
+			logger.Error("failed to persist click", "link_id", batch[i].LinkID, "error", err)
+			continue
 		}
+		p.persisted.Add(1)
+		metrics.ClicksPersistedTotal.Inc()
+	}
+}
+
+// newClick convertit un ClickEvent en models.Click enrichi : hôte référent,
+// navigateur, système et type d'appareil déduits du User-Agent, et classification robot/humain.
+func newClick(event models.ClickEvent, classifier *botfilter.Classifier) *models.Click {
+	ua := useragent.Parse(event.UserAgent)
+	isBot := classifier.IsBot(event)
+	if isBot {
+		ua.Device = useragent.DeviceBot
+	}
+	return &models.Click{
+		LinkID:       event.LinkID,
+		Timestamp:    event.Timestamp,
+		UserAgent:    textutil.Truncate(event.UserAgent, maxUserAgentLength),
+		IPAddress:    event.IP,
+		Referrer:     textutil.Truncate(event.Referrer, maxReferrerLength),
+		ReferrerHost: textutil.Truncate(referrerHost(event.Referrer), maxReferrerHostLength),
+		Browser:      ua.Browser,
+		OS:           ua.OS,
+		DeviceType:   ua.Device,
+		IsBot:        isBot,
+	}
+}
+
+// referrerHost extrait l'hôte (en minuscules, sans port) d'un en-tête Referer.
+// Retourne une chaîne vide pour un accès direct ou un Referer illisible.
+func referrerHost(referrer string) string {
+	if referrer == "" {
+		return ""
+	}
+	u, err := url.Parse(referrer)
+	if err != nil {
+		return ""
 	}
+	return strings.ToLower(u.Hostname())
 }
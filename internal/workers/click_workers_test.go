@@ -0,0 +1,81 @@
+package workers
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+)
+
+func TestNewClickTruncatesColumns(t *testing.T) {
+	host := strings.Repeat("é", 200) + ".example"
+	event := models.ClickEvent{
+		LinkID:    1,
+		UserAgent: "Mozilla/5.0 " + strings.Repeat("ü", 200),
+		Referrer:  "https://" + host + "/" + strings.Repeat("ß", 300),
+		Method:    "GET",
+	}
+	click := newClick(event, botfilter.NewClassifier(nil))
+
+	for column, value := range map[string]string{
+		"user_agent":    click.UserAgent,
+		"referrer":      click.Referrer,
+		"referrer_host": click.ReferrerHost,
+	} {
+		if !utf8.ValidString(value) {
+			t.Errorf("%s is not valid UTF-8 after truncation", column)
+		}
+	}
+	if len(click.UserAgent) > maxUserAgentLength {
+		t.Errorf("user_agent length = %d, want <= %d", len(click.UserAgent), maxUserAgentLength)
+	}
+	if len(click.Referrer) > maxReferrerLength {
+		t.Errorf("referrer length = %d, want <= %d", len(click.Referrer), maxReferrerLength)
+	}
+	if len(click.ReferrerHost) == 0 || len(click.ReferrerHost) > maxReferrerHostLength {
+		t.Errorf("referrer_host length = %d, want 1..%d", len(click.ReferrerHost), maxReferrerHostLength)
+	}
+}
+
+// failingBatchRepository simule une insertion groupée annulée après avoir renseigné les ID des clics,
+// et enregistre les ID reçus par les insertions unitaires.
+type failingBatchRepository struct {
+	repository.ClickRepository
+	retriedIDs []uint
+}
+
+func (r *failingBatchRepository) CreateClicks(clicks []models.Click) error {
+	for i := range clicks {
+		clicks[i].ID = uint(i + 1)
+	}
+	return errors.New("transaction rolled back")
+}
+
+func (r *failingBatchRepository) CreateClick(click *models.Click) error {
+	r.retriedIDs = append(r.retriedIDs, click.ID)
+	return nil
+}
+
+func TestPersistBatchRetriesWithoutIDs(t *testing.T) {
+	repo := &failingBatchRepository{}
+	pool := &ClickWorkerPool{clickRepo: repo}
+
+	pool.persistBatch(slog.New(slog.DiscardHandler), []models.Click{{LinkID: 1}, {LinkID: 1}, {LinkID: 2}})
+
+	if len(repo.retriedIDs) != 3 {
+		t.Fatalf("%d clicks retried, want 3", len(repo.retriedIDs))
+	}
+	for i, id := range repo.retriedIDs {
+		if id != 0 {
+			t.Errorf("click %d retried with ID %d, want 0", i, id)
+		}
+	}
+	if got := pool.persisted.Load(); got != 3 {
+		t.Errorf("persisted = %d, want 3", got)
+	}
+}
@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/services"
+)
+
+// ExpirySweeper recherche périodiquement les liens expirés qui n'ont pas encore été marqués
+// comme tels, pour publier leur expiration même s'ils ne sont plus visités.
+// Les liens visités après leur expiration sont marqués dès la redirection refusée.
+type ExpirySweeper struct {
+	linkService *services.LinkService
+	interval    time.Duration
+	logger      *slog.Logger
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewExpirySweeper crée un ExpirySweeper qui parcourt les liens toutes les 'interval'.
+func NewExpirySweeper(linkService *services.LinkService, interval time.Duration, logger *slog.Logger) *ExpirySweeper {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &ExpirySweeper{
+		linkService: linkService,
+		interval:    interval,
+		logger:      logger.With("component", "expiry_sweeper"),
+		ctx:         ctx,
+		cancel:      cancel,
+		done:        make(chan struct{}),
+	}
+}
+
+// Start effectue un premier passage immédiatement, puis à chaque intervalle jusqu'à l'appel de Stop.
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (s *ExpirySweeper) Start() {
+	defer close(s.done)
+
+	s.Sweep()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.Sweep()
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop arrête les passages périodiques et attend la fin du passage en cours, ou l'expiration de ctx.
+func (s *ExpirySweeper) Stop(ctx context.Context) error {
+	s.stopOnce.Do(s.cancel)
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Sweep marque les liens expirés depuis le passage précédent et retourne leur nombre.
+func (s *ExpirySweeper) Sweep() int {
+	marked, err := s.linkService.NotifyExpiredLinks()
+	if err != nil {
+		s.logger.Error("failed to sweep expired links", "error", err)
+	}
+	if marked > 0 {
+		s.logger.Info("expired links marked", "links", marked)
+	}
+	return marked
+}
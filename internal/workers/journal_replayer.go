@@ -0,0 +1,133 @@
+package workers
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/journal"
+	"github.com/axellelanca/urlshortener/internal/metrics"
+	"github.com/axellelanca/urlshortener/internal/models"
+)
+
+// JournalReplayer relit périodiquement les segments scellés du journal de débordement
+// et enregistre leurs clics en base, avec la même conversion que les workers.
+// Chaque segment est enregistré en une seule transaction puis supprimé : en cas d'échec,
+// il est conservé et réessayé au passage suivant.
+type JournalReplayer struct {
+	journal  *journal.Journal
+	pool     *ClickWorkerPool
+	interval time.Duration
+	logger   *slog.Logger
+
+	ctx      context.Context
+	cancel   context.CancelFunc
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewJournalReplayer crée un JournalReplayer qui enregistre les clics du journal via le pool de workers.
+func NewJournalReplayer(j *journal.Journal, pool *ClickWorkerPool, interval time.Duration) *JournalReplayer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &JournalReplayer{
+		journal:  j,
+		pool:     pool,
+		interval: interval,
+		logger:   pool.logger.With("component", "journal_replayer", "dir", j.Dir()),
+		ctx:      ctx,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+}
+
+// Start relit immédiatement le journal (événements laissés par une exécution précédente),
+// puis à chaque intervalle jusqu'à l'appel de Stop.
+// Cette fonction est conçue pour être lancée dans une goroutine séparée.
+func (r *JournalReplayer) Start() {
+	defer close(r.done)
+
+	r.Replay()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.Replay()
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// Stop arrête la relecture périodique et attend la fin du passage en cours, ou l'expiration de ctx.
+func (r *JournalReplayer) Stop(ctx context.Context) error {
+	r.stopOnce.Do(r.cancel)
+
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Replay scelle le segment actif puis enregistre les clics de tous les segments scellés.
+// Retourne le nombre de clics enregistrés.
+func (r *JournalReplayer) Replay() int {
+	if err := r.journal.Seal(); err != nil {
+		r.logger.Error("failed to seal active journal segment", "error", err)
+	}
+	segments, err := r.journal.SealedSegments()
+	if err != nil {
+		r.logger.Error("failed to list journal segments", "error", err)
+		return 0
+	}
+
+	replayed := 0
+	for _, path := range segments {
+		if r.ctx.Err() != nil {
+			break
+		}
+		n, err := r.replaySegment(path)
+		if err != nil {
+			// Le segment est conservé ; les suivants seront relus au prochain passage, dans l'ordre.
+			r.logger.Error("failed to replay journal segment", "segment", path, "error", err)
+			break
+		}
+		replayed += n
+	}
+	if replayed > 0 {
+		r.logger.Info("clicks replayed from journal", "clicks", replayed)
+	}
+	return replayed
+}
+
+// replaySegment enregistre les clics d'un segment puis le supprime.
+func (r *JournalReplayer) replaySegment(path string) (int, error) {
+	events, corrupted, err := journal.ReadSegment(path)
+	if err != nil {
+		return 0, err
+	}
+	if corrupted {
+		r.logger.Warn("invalid record in journal segment, skipping the rest of the segment", "segment", path, "events_read", len(events))
+	}
+
+	clicks := make([]models.Click, 0, len(events))
+	for _, event := range events {
+		clicks = append(clicks, r.pool.toClick(r.logger, event))
+	}
+	if err := r.pool.clickRepo.CreateClicks(clicks); err != nil {
+		metrics.ClickInsertErrorsTotal.WithLabelValues("replay").Inc()
+		return 0, err
+	}
+	r.pool.persisted.Add(uint64(len(clicks)))
+	metrics.ClicksPersistedTotal.Add(float64(len(clicks)))
+
+	if err := r.journal.Remove(path); err != nil {
+		// Le segment sera relu au prochain passage : ses clics seraient alors comptés deux fois.
+		r.logger.Error("failed to remove replayed journal segment", "segment", path, "error", err)
+	}
+	return len(clicks), nil
+}
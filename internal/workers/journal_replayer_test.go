@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/botfilter"
+	"github.com/axellelanca/urlshortener/internal/dbtest"
+	"github.com/axellelanca/urlshortener/internal/journal"
+	"github.com/axellelanca/urlshortener/internal/models"
+	"github.com/axellelanca/urlshortener/internal/repository"
+	"gorm.io/gorm"
+)
+
+func TestJournalReplayRoundTrip(t *testing.T) {
+	dbtest.Run(t, func(t *testing.T, db *gorm.DB) {
+		link := &models.Link{ShortCode: "spilled", LongURL: "https://example.com", CreatedAt: time.Now()}
+		if err := repository.NewLinkRepository(db).CreateLink(link); err != nil {
+			t.Fatalf("CreateLink: %v", err)
+		}
+
+		j, err := journal.Open(t.TempDir(), 1<<20)
+		if err != nil {
+			t.Fatalf("journal.Open: %v", err)
+		}
+		defer j.Close()
+		events := []models.ClickEvent{
+			{LinkID: link.ID, Timestamp: time.Now(), UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0", IP: "203.0.113.7", Method: "GET"},
+			{LinkID: link.ID, Timestamp: time.Now(), UserAgent: "Mozilla/5.0 (X11; Linux x86_64) Firefox/125.0", IP: "203.0.113.8", Method: "GET"},
+			{LinkID: link.ID, Timestamp: time.Now(), UserAgent: "Slackbot-LinkExpanding 1.0", IP: "203.0.113.9", Method: "GET"},
+		}
+		for _, event := range events {
+			if err := j.Append(event); err != nil {
+				t.Fatalf("Append: %v", err)
+			}
+		}
+
+		logger := slog.New(slog.DiscardHandler)
+		clickRepo := repository.NewClickRepository(db)
+		pool := &ClickWorkerPool{
+			clickRepo:  clickRepo,
+			classifier: botfilter.NewClassifier(nil),
+			hasher:     NewVisitorHasher(repository.NewVisitorSaltRepository(db), logger),
+			logger:     logger,
+		}
+		replayer := NewJournalReplayer(j, pool, time.Hour)
+
+		if replayed := replayer.Replay(); replayed != len(events) {
+			t.Fatalf("Replay() = %d, want %d", replayed, len(events))
+		}
+		if segments, err := j.SealedSegments(); err != nil || len(segments) != 0 {
+			t.Errorf("segments left after replay = %v (error %v), want none", segments, err)
+		}
+
+		humans, err := clickRepo.CountClicksByLinkID(link.ID, false)
+		if err != nil {
+			t.Fatalf("CountClicksByLinkID: %v", err)
+		}
+		bots, err := clickRepo.CountBotClicksByLinkID(link.ID)
+		if err != nil {
+			t.Fatalf("CountBotClicksByLinkID: %v", err)
+		}
+		if humans != 2 || bots != 1 {
+			t.Errorf("replayed clicks = %d humans, %d bots, want 2 and 1", humans, bots)
+		}
+		visitors, err := clickRepo.CountUniqueVisitorsByLinkID(link.ID, false)
+		if err != nil {
+			t.Fatalf("CountUniqueVisitorsByLinkID: %v", err)
+		}
+		if visitors != 2 {
+			t.Errorf("unique visitors = %d, want 2", visitors)
+		}
+
+		// Un second passage n'a plus rien à relire.
+		if replayed := replayer.Replay(); replayed != 0 {
+			t.Errorf("second Replay() = %d, want 0", replayed)
+		}
+	})
+}
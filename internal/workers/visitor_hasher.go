@@ -0,0 +1,103 @@
+package workers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/axellelanca/urlshortener/internal/repository"
+)
+
+// saltRetentionDays est le nombre de jours précédents dont le sel est conservé,
+// pour que les clics arrivant en retard (autour de minuit UTC) gardent une empreinte cohérente.
+const saltRetentionDays = 1
+
+// VisitorHasher calcule l'empreinte anonyme d'un visiteur à partir de son adresse IP
+// et de son User-Agent, salée par un sel aléatoire qui change chaque jour (UTC).
+// Un même visiteur a donc la même empreinte au cours d'une journée, mais aucune
+// corrélation n'est possible d'un jour à l'autre une fois l'ancien sel supprimé.
+// VisitorHasher peut être partagé entre plusieurs workers.
+type VisitorHasher struct {
+	saltRepo repository.VisitorSaltRepository
+	logger   *slog.Logger
+	mu       sync.Mutex
+	salts    map[string]string // Sels en cache, par jour (AAAA-MM-JJ)
+	today    string            // Dernier jour pour lequel la rotation a été effectuée
+}
+
+// NewVisitorHasher crée un VisitorHasher s'appuyant sur le repository de sels.
+func NewVisitorHasher(saltRepo repository.VisitorSaltRepository, logger *slog.Logger) *VisitorHasher {
+	return &VisitorHasher{
+		saltRepo: saltRepo,
+		logger:   logger,
+		salts:    make(map[string]string),
+	}
+}
+
+// Hash retourne l'empreinte (SHA-256 hexadécimal) du visiteur pour le jour de 't'.
+// Pour un clic antérieur à la fenêtre de rétention des sels (relu tardivement depuis le journal
+// de débordement par exemple), aucun sel n'est recréé et l'empreinte retournée est vide.
+func (h *VisitorHasher) Hash(ip, userAgent string, t time.Time) (string, error) {
+	day := t.UTC().Format(time.DateOnly)
+	oldest := time.Now().UTC().AddDate(0, 0, -saltRetentionDays).Format(time.DateOnly)
+	if day < oldest {
+		return "", nil
+	}
+
+	salt, err := h.saltFor(day)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(salt + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// saltFor retourne le sel du jour donné, en le créant au besoin, et effectue la rotation
+// des sels lorsque la journée courante change.
+func (h *VisitorHasher) saltFor(day string) (string, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if salt, ok := h.salts[day]; ok {
+		return salt, nil
+	}
+
+	candidate := make([]byte, 32)
+	if _, err := rand.Read(candidate); err != nil {
+		return "", fmt.Errorf("failed to generate visitor salt: %w", err)
+	}
+	salt, err := h.saltRepo.GetOrCreateSalt(day, hex.EncodeToString(candidate))
+	if err != nil {
+		return "", err
+	}
+	h.salts[day] = salt
+
+	if day > h.today {
+		h.today = day
+		h.rotate()
+	}
+	return salt, nil
+}
+
+// rotate oublie et supprime les sels antérieurs à la fenêtre de rétention.
+// Doit être appelée avec h.mu verrouillé.
+func (h *VisitorHasher) rotate() {
+	today, err := time.Parse(time.DateOnly, h.today)
+	if err != nil {
+		return
+	}
+	oldest := today.AddDate(0, 0, -saltRetentionDays).Format(time.DateOnly)
+
+	for day := range h.salts {
+		if day < oldest {
+			delete(h.salts, day)
+		}
+	}
+	if err := h.saltRepo.DeleteSaltsBefore(oldest); err != nil {
+		h.logger.Warn("failed to delete expired visitor salts", "before", oldest, "error", err)
+	}
+}